@@ -0,0 +1,63 @@
+package ar3
+
+import (
+	"github.com/koeng101/armos/devices/arm"
+	"github.com/koeng101/armos/utils/kinematics"
+	"math"
+)
+
+// degrees converts degrees into radians.
+func degrees(d float64) float64 {
+	return d * math.Pi / 180
+}
+
+// jointVelocity, jointAcceleration and jointJerk are the trajectory limits of
+// each joint of the AR3 and AR4, in radians per second (squared, cubed). They
+// are well below what the steppers can do at full speed.
+var (
+	jointVelocity     = degrees(60)
+	jointAcceleration = degrees(180)
+	jointJerk         = degrees(900)
+)
+
+// ar3Limits are the AR3 joint ranges published by Annin Robotics.
+var ar3Limits = kinematics.AR3JointLimits
+
+// ar3Axes are the axes of the AR3. The angle ranges are ar3Limits. A stepper
+// position of 0 is the negative end of the range, which is where Calibrate
+// leaves each joint. The range of the track is unknown. Annin Robotics does
+// not publish joint speeds, so the trajectory limits are conservative
+// estimates (see jointVelocity).
+var ar3Axes = []arm.Axis{
+	{Name: "J1", StepLimit: j1stepLim, Min: ar3Limits.Min.J1, Max: ar3Limits.Max.J1, MaxVelocity: jointVelocity, MaxAcceleration: jointAcceleration, MaxJerk: jointJerk},
+	{Name: "J2", StepLimit: j2stepLim, Min: ar3Limits.Min.J2, Max: ar3Limits.Max.J2, MaxVelocity: jointVelocity, MaxAcceleration: jointAcceleration, MaxJerk: jointJerk},
+	{Name: "J3", StepLimit: j3stepLim, Min: ar3Limits.Min.J3, Max: ar3Limits.Max.J3, MaxVelocity: jointVelocity, MaxAcceleration: jointAcceleration, MaxJerk: jointJerk},
+	{Name: "J4", StepLimit: j4stepLim, Min: ar3Limits.Min.J4, Max: ar3Limits.Max.J4, MaxVelocity: jointVelocity, MaxAcceleration: jointAcceleration, MaxJerk: jointJerk},
+	{Name: "J5", StepLimit: j5stepLim, Min: ar3Limits.Min.J5, Max: ar3Limits.Max.J5, MaxVelocity: jointVelocity, MaxAcceleration: jointAcceleration, MaxJerk: jointJerk},
+	{Name: "J6", StepLimit: j6stepLim, Min: ar3Limits.Min.J6, Max: ar3Limits.Max.J6, MaxVelocity: jointVelocity, MaxAcceleration: jointAcceleration, MaxJerk: jointJerk},
+	{Name: "Tr", Prismatic: true},
+}
+
+// ar4Axes are the axes of an arm running the AR4 firmware. The angle ranges
+// are the AR4 joint ranges published by Annin Robotics.
+var ar4Axes = []arm.Axis{
+	{Name: "J1", StepLimit: j1stepLim, Min: degrees(-170), Max: degrees(170), MaxVelocity: jointVelocity, MaxAcceleration: jointAcceleration, MaxJerk: jointJerk},
+	{Name: "J2", StepLimit: j2stepLim, Min: degrees(-42), Max: degrees(90), MaxVelocity: jointVelocity, MaxAcceleration: jointAcceleration, MaxJerk: jointJerk},
+	{Name: "J3", StepLimit: j3stepLim, Min: degrees(-89), Max: degrees(52), MaxVelocity: jointVelocity, MaxAcceleration: jointAcceleration, MaxJerk: jointJerk},
+	{Name: "J4", StepLimit: j4stepLim, Min: degrees(-165), Max: degrees(165), MaxVelocity: jointVelocity, MaxAcceleration: jointAcceleration, MaxJerk: jointJerk},
+	{Name: "J5", StepLimit: j5stepLim, Min: degrees(-105), Max: degrees(105), MaxVelocity: jointVelocity, MaxAcceleration: jointAcceleration, MaxJerk: jointJerk},
+	{Name: "J6", StepLimit: j6stepLim, Min: degrees(-155), Max: degrees(155), MaxVelocity: jointVelocity, MaxAcceleration: jointAcceleration, MaxJerk: jointJerk},
+	{Name: "Tr", Prismatic: true},
+}
+
+// capabilities returns the capabilities of an arm running the given firmware.
+// The AR4 shares the link lengths of the AR3, so both use AR3DhParameters and
+// AR3CollisionModel.
+func capabilities(f Firmware) arm.Capabilities {
+	dhParameters := kinematics.AR3DhParameters
+	collision := kinematics.AR3CollisionModel
+	if f == FirmwareAR4 {
+		return arm.Capabilities{Model: "AR4", Axes: ar4Axes, Kinematics: &dhParameters, Collision: &collision}
+	}
+	return arm.Capabilities{Model: "AR3", Axes: ar3Axes, Kinematics: &dhParameters, Collision: &collision}
+}
@@ -0,0 +1,386 @@
+package ar3
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"github.com/koeng101/armos/devices/arm"
+	"io"
+	"io/ioutil"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Firmware identifies the sketch running on the arm's microcontroller. Each
+// firmware speaks a different command dialect, which is chosen when
+// connecting to the arm.
+type Firmware int
+
+const (
+	// FirmwareUnknown is a firmware that did not respond to any known dialect.
+	FirmwareUnknown Firmware = iota
+	// FirmwareAR3 is the AR2/AR3 Arduino sketch used by ARCS.
+	FirmwareAR3
+	// FirmwareAR4 is the AR4 Teensy sketch, which moves in joint angles and
+	// reports position and error strings after each command.
+	FirmwareAR4
+)
+
+// String returns the name of the firmware.
+func (f Firmware) String() string {
+	switch f {
+	case FirmwareAR3:
+		return "AR3"
+	case FirmwareAR4:
+		return "AR4"
+	default:
+		return "unknown"
+	}
+}
+
+// ErrUnknownFirmware is returned by Connect when the controller does not
+// answer in any supported dialect.
+var ErrUnknownFirmware = errors.New("Unknown AR firmware. Only the AR3 Arduino and AR4 Teensy sketches are supported")
+
+// detectTimeout is how long we wait for a reply when probing the firmware.
+var detectTimeout = 2 * time.Second
+
+// drainTimeout is how long we wait for late replies to a probe before probing
+// the next dialect.
+var drainTimeout = 200 * time.Millisecond
+
+// responseTimeout is how long we wait for a reply after a command. AR4
+// firmware only replies once a move is complete, so this is generous.
+var responseTimeout = 60 * time.Second
+
+// protocol implements the command dialect of a single firmware. Target and
+// positions are absolute stepper positions, and delta is the relative move
+// being made.
+type protocol interface {
+	firmware() Firmware
+	echo(port *bufferedPort) error
+	move(port *bufferedPort, motion arm.MotionParameters, delta, target arm.Joints, directions []bool) error
+	calibrate(port *bufferedPort, speed int, home []bool, positions arm.Joints, directions []bool) error
+}
+
+// detectFirmware probes the controller and returns the protocol of the first
+// dialect it responds to. Each probe gets its own deadline, and anything left
+// over from a failed probe is drained before the next one.
+func detectFirmware(port *bufferedPort) (protocol, error) {
+	defer setReadDeadline(port, 0)
+	for _, p := range []protocol{ar3Protocol{}, ar4Protocol{}} {
+		setReadDeadline(port, detectTimeout)
+		if p.echo(port) == nil {
+			return p, nil
+		}
+		port.drain()
+	}
+	return nil, ErrUnknownFirmware
+}
+
+// readDeadliner is a port that supports read deadlines, such as a serial port
+// opened with os.OpenFile.
+type readDeadliner interface {
+	SetReadDeadline(time.Time) error
+}
+
+// setReadDeadline sets a read deadline on ports that support it. A timeout of
+// 0 clears the deadline.
+func setReadDeadline(port io.ReadWriter, timeout time.Duration) {
+	deadliner, ok := port.(readDeadliner)
+	if !ok {
+		return
+	}
+	if timeout == 0 {
+		_ = deadliner.SetReadDeadline(time.Time{})
+		return
+	}
+	_ = deadliner.SetReadDeadline(time.Now().Add(timeout))
+}
+
+// bufferedPort is a port to the controller that keeps bytes read past the end
+// of a line for the next read.
+type bufferedPort struct {
+	port   io.ReadWriter
+	reader *bufio.Reader
+}
+
+// newBufferedPort wraps a port to the controller.
+func newBufferedPort(port io.ReadWriter) *bufferedPort {
+	return &bufferedPort{port: port, reader: bufio.NewReader(port)}
+}
+
+func (p *bufferedPort) Read(b []byte) (int, error) {
+	return p.reader.Read(b)
+}
+
+func (p *bufferedPort) Write(b []byte) (int, error) {
+	return p.port.Write(b)
+}
+
+// SetReadDeadline sets a read deadline on the underlying port, if it supports
+// them.
+func (p *bufferedPort) SetReadDeadline(t time.Time) error {
+	deadliner, ok := p.port.(readDeadliner)
+	if !ok {
+		return nil
+	}
+	return deadliner.SetReadDeadline(t)
+}
+
+// readLine reads until a newline, returning the line without surrounding
+// whitespace.
+func (p *bufferedPort) readLine() (string, error) {
+	line, err := p.reader.ReadString('\n')
+	return strings.TrimSpace(line), err
+}
+
+// drain discards buffered input, and anything the controller sends within
+// drainTimeout, such as a late reply to a probe. Ports without read deadlines
+// might block forever, so only buffered input is discarded from them.
+func (p *bufferedPort) drain() {
+	p.reader.Reset(p.port)
+	if _, ok := p.port.(readDeadliner); !ok {
+		return
+	}
+	setReadDeadline(p.port, drainTimeout)
+	_, _ = io.Copy(ioutil.Discard, p.port)
+	setReadDeadline(p.port, 0)
+}
+
+// alphabetForCommands are the axis characters used by both firmwares. These
+// were derived from line 4493 in the ARCS source file under the variable "commandCalc".
+var alphabetForCommands = []string{"A", "B", "C", "D", "E", "F", "T"}
+
+/******************************************************************************
+
+                                AR3 protocol
+
+******************************************************************************/
+
+// ar3Protocol is the dialect of the AR2/AR3 Arduino sketch. It moves in
+// relative steps and does not report completion of commands.
+type ar3Protocol struct{}
+
+func (ar3Protocol) firmware() Firmware {
+	return FirmwareAR3
+}
+
+func (ar3Protocol) echo(port *bufferedPort) error {
+	// Send echo to the device
+	str := "Test"
+	stringToSend := fmt.Sprintf("TM%s\n", str)
+	_, err := port.Write([]byte(stringToSend))
+	if err != nil {
+		return err
+	}
+
+	// Read output of echo
+	buf := make([]byte, 128)
+	n, err := port.Read(buf)
+	if err != nil {
+		return err
+	}
+
+	// Double check to make sure the length is > 1
+	if n < 2 {
+		return fmt.Errorf("Return from echo is empty. Is the serial port responding properly?")
+	}
+
+	// See if we had the same bytes returned
+	// Note: the serial returns with your string with \n\r\n between two double-quotes ("), so we remove these characters
+	stringOutput := fmt.Sprintf("%q", buf[:n])
+	if len(stringOutput) < 8 {
+		return fmt.Errorf("Failed echo to AR3. Expected %s but got %s", str, stringOutput)
+	}
+	stringOutput = stringOutput[1 : len(stringOutput)-7]
+	if stringOutput != str {
+		return fmt.Errorf("Failed echo to AR3. Expected %s but got %s", str, stringOutput)
+	}
+
+	// If we got the same string back, success
+	return nil
+}
+
+func (ar3Protocol) move(port *bufferedPort, motion arm.MotionParameters, delta, target arm.Joints, directions []bool) error {
+	_, err := port.Write([]byte(ar3MoveCommand(motion, delta, directions)))
+	// Normally, we would check here for successful completion. However, there IS no way to check for
+	// successful completion implemented in the AR3 code. So we do not check for this.
+	return err
+}
+
+func (ar3Protocol) calibrate(port *bufferedPort, speed int, home []bool, positions arm.Joints, directions []bool) error {
+	_, err := port.Write([]byte(ar3CalibrateCommand(speed, home, positions, directions)))
+	// Normally, we would check here for successful completion. However, there IS no way to check for
+	// successful completion implemented in the AR3 code. So we do not check for this.
+	return err
+}
+
+// ar3MoveCommand builds an AR3 MJ command from relative step counts.
+func ar3MoveCommand(motion arm.MotionParameters, delta arm.Joints, directions []bool) string {
+	// command string for movement is MJ
+	command := "MJ"
+	// First, compute direction. If the stepper is negative, that means that direction is set to 1.
+	// We are going to compute these as a list, and then append them to a growing string
+	var jdirection int
+	for i, j := range delta {
+		jdirection = 0
+		if j < 0 {
+			jdirection = 1
+			j = -1 * j
+		}
+
+		// We also have to compensate for the direction coded when initializing the AR3 (as oftentimes, this can be off)
+		if directions[i] {
+			j = -1 * j
+		}
+
+		command = command + fmt.Sprintf("%s%d%d", alphabetForCommands[i], jdirection, j)
+	}
+
+	// We now have the axis commands, so we need to add the speed, accspd, accdur, dccdur, and dccspd.
+	// These are also derived from the above commandCalc.
+	return command + fmt.Sprintf("S%dG%dH%dI%dK%d", motion.Speed, motion.AccelerationSpeed, motion.AccelerationDuration, motion.DecelerationDuration, motion.DecelerationSpeed)
+}
+
+// ar3CalibrateCommand builds an AR3 LL command.
+func ar3CalibrateCommand(speed int, home []bool, positions arm.Joints, directions []bool) string {
+	// command string for home is LL
+	command := "LL"
+	for i, direction := range directions {
+		// First, we check if we need to home the motor. If we do not (false), do not home the motor.
+		if home[i] {
+			// Each direction is set by the boolean and appended into the calibrate string.
+			// The number of steps taken is equivalent to the step limits, which are hardcoded into the AR3 arm.
+			if direction {
+				command = command + fmt.Sprintf("%s%d%d", alphabetForCommands[i], 1, positions[i])
+			} else {
+				command = command + fmt.Sprintf("%s%d%d", alphabetForCommands[i], 0, positions[i])
+			}
+		} else {
+			command = command + fmt.Sprintf("%s%d%d", alphabetForCommands[i], 0, 0)
+		}
+	}
+	// Finally, we append the speed.
+	return command + fmt.Sprintf("S%d\n", speed)
+}
+
+/******************************************************************************
+
+                                AR4 protocol
+
+******************************************************************************/
+
+// ar4Protocol is the dialect of the AR4 Teensy sketch. It moves to absolute
+// joint angles and replies with the robot position, or an error string
+// starting with "E", after each command.
+type ar4Protocol struct{}
+
+func (ar4Protocol) firmware() Firmware {
+	return FirmwareAR4
+}
+
+func (ar4Protocol) echo(port *bufferedPort) error {
+	// The AR4 firmware has no echo, so we request the position instead and
+	// check that it parses.
+	_, err := port.Write([]byte("RP\n"))
+	if err != nil {
+		return err
+	}
+	response, err := port.readLine()
+	if err != nil {
+		return err
+	}
+	_, err = parseAR4Response(response)
+	return err
+}
+
+func (ar4Protocol) move(port *bufferedPort, motion arm.MotionParameters, delta, target arm.Joints, directions []bool) error {
+	setReadDeadline(port, responseTimeout)
+	defer setReadDeadline(port, 0)
+	_, err := port.Write([]byte(ar4MoveCommand(motion, target)))
+	if err != nil {
+		return err
+	}
+	response, err := port.readLine()
+	if err != nil {
+		return err
+	}
+	_, err = parseAR4Response(response)
+	return err
+}
+
+func (ar4Protocol) calibrate(port *bufferedPort, speed int, home []bool, positions arm.Joints, directions []bool) error {
+	setReadDeadline(port, responseTimeout)
+	defer setReadDeadline(port, 0)
+	_, err := port.Write([]byte(ar4CalibrateCommand(home)))
+	if err != nil {
+		return err
+	}
+	response, err := port.readLine()
+	if err != nil {
+		return err
+	}
+	_, err = parseAR4Response(response)
+	return err
+}
+
+// ar4MoveCommand builds an AR4 RJ (move to joint angles) command from absolute
+// step positions, which are mapped onto the AR4 joint ranges. The AR4 firmware
+// stores motor directions itself, so directions are not part of the command.
+// The track position is sent as J7.
+func ar4MoveCommand(motion arm.MotionParameters, target arm.Joints) string {
+	command := "RJ"
+	for i := 0; i < 6; i++ {
+		angle := ar4Axes[i].Angle(target[i]) * 180 / math.Pi
+		command = command + fmt.Sprintf("%s%.3f", alphabetForCommands[i], angle)
+	}
+	command = command + fmt.Sprintf("J7%dJ80J90", target[6])
+	return command + fmt.Sprintf("Sp%dAc%dDc%dRm%dWNLm000000\n", motion.Speed, motion.AccelerationSpeed, motion.DecelerationSpeed, motion.AccelerationDuration)
+}
+
+// ar4CalibrateCommand builds an AR4 LL command. Calibration offsets are left
+// at 0.
+func ar4CalibrateCommand(home []bool) string {
+	command := "LL"
+	flags := []string{"A", "B", "C", "D", "E", "F", "G"}
+	for i, h := range home {
+		if h {
+			command = command + fmt.Sprintf("%s%d", flags[i], 1)
+		} else {
+			command = command + fmt.Sprintf("%s%d", flags[i], 0)
+		}
+	}
+	return command + "H0I0J0K0L0M0N0O0P0Q0R0\n"
+}
+
+// parseAR4Response parses the position string returned by the AR4 firmware
+// into joint angles in degrees. Responses starting with "E" are errors.
+func parseAR4Response(response string) ([6]float64, error) {
+	var angles [6]float64
+	if strings.HasPrefix(response, "E") {
+		return angles, fmt.Errorf("AR4 returned error: %s", response)
+	}
+	// Position strings look like A<j1>B<j2>C<j3>D<j4>E<j5>F<j6>G<x>...
+	markers := []string{"A", "B", "C", "D", "E", "F", "G"}
+	rest := response
+	for i := 0; i < 6; i++ {
+		if !strings.HasPrefix(rest, markers[i]) {
+			return angles, fmt.Errorf("Failed to parse AR4 position %q", response)
+		}
+		rest = rest[1:]
+		end := strings.Index(rest, markers[i+1])
+		if end < 0 {
+			return angles, fmt.Errorf("Failed to parse AR4 position %q", response)
+		}
+		angle, err := strconv.ParseFloat(rest[:end], 64)
+		if err != nil {
+			return angles, fmt.Errorf("Failed to parse AR4 position %q", response)
+		}
+		angles[i] = angle
+		rest = rest[end:]
+	}
+	return angles, nil
+}
@@ -0,0 +1,117 @@
+package ar3
+
+import (
+	"github.com/koeng101/armos/devices/arm"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakePort simulates a controller firmware by replying to commands based on
+// their two letter prefix. Replies are returned in chunks of at most chunk
+// bytes, if set.
+type fakePort struct {
+	chunk   int
+	replies map[string]string
+	pending string
+	written []string
+}
+
+func (p *fakePort) Write(b []byte) (int, error) {
+	command := string(b)
+	p.written = append(p.written, command)
+	p.pending = p.pending + p.replies[command[:2]]
+	return len(b), nil
+}
+
+func (p *fakePort) Read(b []byte) (int, error) {
+	if p.pending == "" {
+		return 0, io.EOF
+	}
+	if p.chunk > 0 && len(b) > p.chunk {
+		b = b[:p.chunk]
+	}
+	n := copy(b, p.pending)
+	p.pending = p.pending[n:]
+	return n, nil
+}
+
+func (p *fakePort) SetReadDeadline(t time.Time) error {
+	return nil
+}
+
+func TestDetectFirmware(t *testing.T) {
+	ar3Port := &fakePort{replies: map[string]string{"TM": "Test\n\r\n"}}
+	p, err := detectFirmware(newBufferedPort(ar3Port))
+	if err != nil || p.firmware() != FirmwareAR3 {
+		t.Errorf("Expected AR3 firmware. Got %v with error %v", p, err)
+	}
+
+	ar4Port := &fakePort{replies: map[string]string{"RP": "A0.000B-10.000C20.000D0.000E30.000F0.000G300.000H0.000I400.000\n"}}
+	p, err = detectFirmware(newBufferedPort(ar4Port))
+	if err != nil || p.firmware() != FirmwareAR4 {
+		t.Errorf("Expected AR4 firmware. Got %v with error %v", p, err)
+	}
+
+	unknownPort := &fakePort{replies: map[string]string{"TM": "?\n", "RP": "?\n"}}
+	_, err = detectFirmware(newBufferedPort(unknownPort))
+	if err != ErrUnknownFirmware {
+		t.Errorf("Expected ErrUnknownFirmware. Got %v", err)
+	}
+
+	// A long reply to the AR3 probe is drained before probing the AR4
+	noisyPort := &fakePort{replies: map[string]string{"TM": strings.Repeat("?", 300) + "\n", "RP": ar4Port.replies["RP"]}}
+	p, err = detectFirmware(newBufferedPort(noisyPort))
+	if err != nil || p.firmware() != FirmwareAR4 {
+		t.Errorf("Expected AR4 firmware after a noisy probe. Got %v with error %v", p, err)
+	}
+}
+
+func TestBufferedPort_ReadLine(t *testing.T) {
+	// Lines arriving in pieces and together are both read whole
+	port := newBufferedPort(&fakePort{chunk: 3, replies: map[string]string{"RP": "A0.000B1.000\nA2.000\r\n"}})
+	_, _ = port.Write([]byte("RP\n"))
+	for _, expected := range []string{"A0.000B1.000", "A2.000"} {
+		line, err := port.readLine()
+		if err != nil || line != expected {
+			t.Errorf("Expected %s. Got %s with error %v", expected, line, err)
+		}
+	}
+	if _, err := port.readLine(); err != io.EOF {
+		t.Errorf("Expected io.EOF after the last line. Got %v", err)
+	}
+}
+
+func TestAR3MoveCommand(t *testing.T) {
+	command := ar3MoveCommand(arm.DefaultMotionParameters, arm.Joints{500, -500, 0, 0, 0, 0, 0}, make([]bool, 7))
+	expected := "MJA0500B1500C00D00E00F00T00S25G10H15I20K5"
+	if command != expected {
+		t.Errorf("Expected %s. Got %s", expected, command)
+	}
+}
+
+func TestAR4Move(t *testing.T) {
+	port := &fakePort{replies: map[string]string{"RJ": "EL100000\n"}}
+	err := ar4Protocol{}.move(newBufferedPort(port), arm.DefaultMotionParameters, make(arm.Joints, 7), make(arm.Joints, 7), make([]bool, 7))
+	if err == nil {
+		t.Errorf("AR4 error string should have failed the move")
+	}
+	if !strings.HasPrefix(port.written[0], "RJA-170.000B-42.000") {
+		t.Errorf("Unexpected AR4 move command %s", port.written[0])
+	}
+}
+
+func TestParseAR4Response(t *testing.T) {
+	angles, err := parseAR4Response("A1.5B-2.5C3.0D4.0E-5.0F6.0G0.0")
+	if err != nil {
+		t.Errorf("Failed to parse AR4 response: %s", err)
+	}
+	if angles != [6]float64{1.5, -2.5, 3, 4, -5, 6} {
+		t.Errorf("Unexpected angles %v", angles)
+	}
+	_, err = parseAR4Response("A1.5B")
+	if err == nil {
+		t.Errorf("Truncated response should have failed")
+	}
+}
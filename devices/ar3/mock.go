@@ -2,29 +2,23 @@ package ar3
 
 import (
 	"fmt"
+	"github.com/koeng101/armos/devices/arm"
+	"github.com/koeng101/armos/utils/trajectory"
+	"time"
 )
 
 // AR3simulate struct represents an AR3 robotic arm interface for testing purposes.
+// Moves happen instantly, but the time they would have taken on a real arm is
+// added up in Elapsed.
 type AR3simulate struct {
-	j1    int
-	j2    int
-	j3    int
-	j4    int
-	j5    int
-	j6    int
-	tr    int
-	j1dir bool
-	j2dir bool
-	j3dir bool
-	j4dir bool
-	j5dir bool
-	j6dir bool
-	trdir bool
+	position   arm.Joints
+	directions []bool
+	elapsed    time.Duration
 }
 
 // ConnectMock connects to a mock AR3simulate interface.
 func ConnectMock() *AR3simulate {
-	return &AR3simulate{}
+	return &AR3simulate{position: make(arm.Joints, len(ar3Axes)), directions: make([]bool, len(ar3Axes))}
 }
 
 // Echo simulates AR3exec.Echo().
@@ -32,76 +26,83 @@ func (ar3 *AR3simulate) Echo() error {
 	return nil
 }
 
+// Capabilities simulates AR3exec.Capabilities().
+func (ar3 *AR3simulate) Capabilities() arm.Capabilities {
+	return capabilities(FirmwareAR3)
+}
+
 // MoveSteppers simulates AR3exec.MoveSteppers().
-func (ar3 *AR3simulate) MoveSteppers(speed, accdur, accspd, dccdur, dccspd, j1, j2, j3, j4, j5, j6, tr int) error {
+func (ar3 *AR3simulate) MoveSteppers(motion arm.MotionParameters, delta arm.Joints) error {
 	// First, check if the move can be made
-	to := []int{j1, j2, j3, j4, j5, j6}
-	from := []int{ar3.j1, ar3.j2, ar3.j3, ar3.j4, ar3.j5, ar3.j6}
-	limits := []int{j1stepLim, j2stepLim, j3stepLim, j4stepLim, j5stepLim, j6stepLim}
-	motor := []string{"J1", "J2", "J3", "J4", "J5", "J6"}
-	var newPositions []int
-	for i := 0; i < 6; i++ {
-		newJ := to[i] + from[i]
-		if newJ < 0 || newJ > limits[i] {
-			return fmt.Errorf("%s out of range. Must be between 0 and %d. Got %d", motor[i], limits[i], newJ)
+	target, err := ar3.Capabilities().Add(ar3.position, delta)
+	if err != nil {
+		return err
+	}
+
+	// Time the move with an S-curve at the speed of the move, as a percentage
+	// of the limits of each axis. Axes without limits, like the track, are not
+	// timed.
+	limits := ar3.Capabilities().TrajectoryLimits()
+	speed := float64(motion.Speed) / 100
+	if speed <= 0 || speed > 1 {
+		speed = 1
+	}
+	from := make([]float64, len(delta))
+	to := make([]float64, len(delta))
+	for i := range delta {
+		limits.Velocity[i] = limits.Velocity[i] * speed
+		if limits.Velocity[i] > 0 {
+			from[i], to[i] = float64(ar3.position[i]), float64(target[i])
 		}
-		newPositions = append(newPositions, newJ)
 	}
-	// If all the limits check out, apply them.
-	ar3.j1 = newPositions[0]
-	ar3.j2 = newPositions[1]
-	ar3.j3 = newPositions[2]
-	ar3.j4 = newPositions[3]
-	ar3.j5 = newPositions[4]
-	ar3.j6 = newPositions[5]
+	move, err := trajectory.Plan([][]float64{from, to}, limits, trajectory.SCurve)
+	if err != nil {
+		return err
+	}
 
 	// Since we are simulating, simply update and assume that there is no error.
+	ar3.position = target
+	ar3.elapsed = ar3.elapsed + time.Duration(move.Duration()*float64(time.Second))
 	return nil
 }
 
+// Elapsed returns how long every move so far would have taken on a real arm.
+func (ar3 *AR3simulate) Elapsed() time.Duration {
+	return ar3.elapsed
+}
+
 // Calibrate simulates AR3exec.Calibrate()
-func (ar3 *AR3simulate) Calibrate(speed int, j1, j2, j3, j4, j5, j6, tr bool) error {
-	if j1 {
-		ar3.j1 = 0
+func (ar3 *AR3simulate) Calibrate(speed int, axes []bool) error {
+	if len(axes) != len(ar3.position) {
+		return fmt.Errorf("AR3 has %d axes. Got %d", len(ar3.position), len(axes))
 	}
-	if j2 {
-		ar3.j2 = 0
-	}
-	if j3 {
-		ar3.j3 = 0
-	}
-	if j4 {
-		ar3.j4 = 0
-	}
-	if j5 {
-		ar3.j5 = 0
-	}
-	if j6 {
-		ar3.j6 = 0
-	}
-	if tr {
-		ar3.tr = 0
+	for i, home := range axes {
+		if home {
+			ar3.position[i] = 0
+		}
 	}
 	return nil
 }
 
 // CurrentPosition simulates AR3exec.CurrentPosition().
-func (ar3 *AR3simulate) CurrentPosition() (int, int, int, int, int, int, int) {
-	return ar3.j1, ar3.j2, ar3.j3, ar3.j4, ar3.j5, ar3.j6, ar3.tr
+func (ar3 *AR3simulate) CurrentPosition() arm.Joints {
+	position := make(arm.Joints, len(ar3.position))
+	copy(position, ar3.position)
+	return position
 }
 
 // SetDirections simulates AR3exec.SetDirections().
-func (ar3 *AR3simulate) SetDirections(j1dir, j2dir, j3dir, j4dir, j5dir, j6dir, trdir bool) {
-	ar3.j1dir = j1dir
-	ar3.j2dir = j2dir
-	ar3.j3dir = j3dir
-	ar3.j4dir = j4dir
-	ar3.j5dir = j5dir
-	ar3.j6dir = j6dir
-	ar3.trdir = trdir
+func (ar3 *AR3simulate) SetDirections(directions []bool) error {
+	if len(directions) != len(ar3.directions) {
+		return fmt.Errorf("AR3 has %d axes. Got %d directions", len(ar3.directions), len(directions))
+	}
+	copy(ar3.directions, directions)
+	return nil
 }
 
-// GetDirections simulates AR3exec.GetDirections().
-func (ar3 *AR3simulate) GetDirections() (bool, bool, bool, bool, bool, bool, bool) {
-	return ar3.j1dir, ar3.j2dir, ar3.j3dir, ar3.j4dir, ar3.j5dir, ar3.j6dir, ar3.trdir
+// Directions simulates AR3exec.Directions().
+func (ar3 *AR3simulate) Directions() []bool {
+	directions := make([]bool, len(ar3.directions))
+	copy(directions, ar3.directions)
+	return directions
 }
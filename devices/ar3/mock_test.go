@@ -2,30 +2,36 @@ package ar3
 
 import (
 	"fmt"
+	"github.com/koeng101/armos/devices/arm"
+	"math"
 	"testing"
 )
 
 func TestAR3simulate_MoveSteppers(t *testing.T) {
-	// The following line establishes that mock DOES implement the AR3 interface.
-	var arm AR3 //nolint
-	arm = ConnectMock()
-	err := arm.MoveSteppers(25, 15, 10, 20, 5, 500, 500, 500, 500, 500, 500000000, 0)
+	// The following line establishes that mock DOES implement the Arm interface.
+	var a arm.Arm //nolint
+	a = ConnectMock()
+	err := a.MoveSteppers(arm.DefaultMotionParameters, arm.Joints{500, 500, 500, 500, 500, 500000000, 0})
 	if err == nil {
 		t.Errorf("Arm should have failed with large j6 value")
 	}
+	err = a.MoveSteppers(arm.DefaultMotionParameters, arm.Joints{500, 500})
+	if err == nil {
+		t.Errorf("Arm should have failed with too few axes")
+	}
 }
 
 func ExampleConnectMock() {
-	arm := ConnectMock()
-	if arm.Echo() == nil {
+	robot := ConnectMock()
+	if robot.Echo() == nil {
 		fmt.Println("Connected")
 	}
 	// Output: Connected
 }
 
 func ExampleAR3simulate_Echo() {
-	arm := ConnectMock()
-	err := arm.Echo()
+	robot := ConnectMock()
+	err := robot.Echo()
 	if err == nil {
 		fmt.Print("Connected")
 	}
@@ -33,9 +39,9 @@ func ExampleAR3simulate_Echo() {
 }
 
 func ExampleAR3simulate_MoveSteppers() {
-	arm := ConnectMock()
-	// Move the arm. First 5 numbers are rational defaults, and each motor gets moved 500 steps
-	err := arm.MoveSteppers(25, 15, 10, 20, 5, 500, 500, 500, 500, 500, 500, 0)
+	robot := ConnectMock()
+	// Move the arm with rational defaults. Each motor gets moved 500 steps
+	err := robot.MoveSteppers(arm.DefaultMotionParameters, arm.Joints{500, 500, 500, 500, 500, 500, 0})
 	if err == nil {
 		fmt.Println("Moved")
 	}
@@ -43,9 +49,9 @@ func ExampleAR3simulate_MoveSteppers() {
 }
 
 func ExampleAR3simulate_Calibrate() {
-	arm := ConnectMock()
+	robot := ConnectMock()
 	// Calibrate the arm. 50 is a good default speed.
-	err := arm.Calibrate(50, true, true, true, true, true, true, true)
+	err := robot.Calibrate(50, []bool{true, true, true, true, true, true, true})
 	if err == nil {
 		fmt.Println("Calibrated")
 	}
@@ -53,11 +59,35 @@ func ExampleAR3simulate_Calibrate() {
 }
 
 func ExampleAR3simulate_CurrentPosition() {
-	arm := ConnectMock()
+	robot := ConnectMock()
 	// Current position. By default, the arm is assumed to be homed at 0
-	j1, _, _, _, _, _, _ := arm.CurrentPosition()
-	if j1 == 0 {
+	position := robot.CurrentPosition()
+	if position[0] == 0 {
 		fmt.Println("At 0")
 	}
 	// Output: At 0
 }
+
+func TestAR3simulate_Elapsed(t *testing.T) {
+	robot := ConnectMock()
+	capabilities := robot.Capabilities()
+
+	// Move J1 by 90 degrees at full speed: 0.53s to reach 60 degrees per
+	// second, 0.97s of cruising, and 0.53s to stop.
+	steps := capabilities.Axes[0].Step(capabilities.Axes[0].Min + degrees(90))
+	motion := arm.DefaultMotionParameters
+	motion.Speed = 100
+	err := robot.MoveSteppers(motion, arm.Joints{steps, 0, 0, 0, 0, 0, 0})
+	if err != nil {
+		t.Fatalf("Failed to move with error: %s", err)
+	}
+	if math.Abs(robot.Elapsed().Seconds()-2.033) > 0.01 {
+		t.Errorf("Expected a 2.03s move. Got %s", robot.Elapsed())
+	}
+
+	// Moving the track is not timed
+	_ = robot.MoveSteppers(motion, arm.Joints{0, 0, 0, 0, 0, 0, 1000})
+	if math.Abs(robot.Elapsed().Seconds()-2.033) > 0.01 {
+		t.Errorf("Track moves should not be timed. Got %s", robot.Elapsed())
+	}
+}
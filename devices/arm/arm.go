@@ -0,0 +1,250 @@
+/*
+Package arm defines a device-agnostic interface for robotic arms.
+
+Basics
+
+Every arm driver in armos, starting with the AR3, implements the Arm interface.
+Arms are moved by stepper positions, which are passed around as a Joints
+vector with one entry per axis. The Capabilities of an arm describe each of its
+axes, how stepper positions map onto joint angles, and the kinematic model of
+the arm, so that higher level code such as the arm node does not need to know
+which arm it is driving.
+*/
+package arm
+
+import (
+	"errors"
+	"fmt"
+	"github.com/koeng101/armos/utils/kinematics"
+	"github.com/koeng101/armos/utils/trajectory"
+	"math"
+)
+
+// Arm is the generic interface for interacting with a robotic arm.
+type Arm interface {
+	Capabilities() Capabilities
+	Echo() error
+	CurrentPosition() Joints
+	Calibrate(speed int, axes []bool) error
+	MoveSteppers(motion MotionParameters, delta Joints) error
+	SetDirections(directions []bool) error
+	Directions() []bool
+}
+
+// Joints is a vector of stepper positions, one per axis of an arm. Depending
+// on context, it is either an absolute position or a relative move.
+type Joints []int
+
+// MotionParameters define the speed and acceleration of a move. ACCdur,
+// ACCspd, DCCdur, and DCCspd are named after the AR3 arduino controller.
+type MotionParameters struct {
+	Speed                int `json:"speed"`
+	AccelerationDuration int `json:"accdur"`
+	AccelerationSpeed    int `json:"accspd"`
+	DecelerationDuration int `json:"dccdur"`
+	DecelerationSpeed    int `json:"dccspd"`
+}
+
+// DefaultMotionParameters are good defaults for most moves. They are taken
+// from lines 7941 to 7945 of ARCS.
+var DefaultMotionParameters = MotionParameters{Speed: 25, AccelerationDuration: 15, AccelerationSpeed: 10, DecelerationDuration: 20, DecelerationSpeed: 5}
+
+// Axis describes a single stepper axis of an arm. Stepper positions run from 0
+// to StepLimit, which map linearly onto the range Min to Max. Min and Max are
+// radians for revolute axes and millimeters for prismatic axes. A StepLimit of
+// 0 means the range of the axis is unknown, and the axis is not range checked.
+// MaxVelocity is the fastest the axis may move in resolved-rate motion, per
+// second, where 0 means unlimited. MaxVelocity, MaxAcceleration and MaxJerk
+// (per second, per second squared and per second cubed) are also used to plan
+// trajectories, where 0 means the axis cannot be planned for.
+type Axis struct {
+	Name            string
+	StepLimit       int
+	Min             float64
+	Max             float64
+	Prismatic       bool
+	MaxVelocity     float64
+	MaxAcceleration float64
+	MaxJerk         float64
+}
+
+// Angle converts a stepper position into a joint angle (or distance for
+// prismatic axes).
+func (a Axis) Angle(step int) float64 {
+	if a.StepLimit == 0 {
+		return a.Min
+	}
+	return a.Min + (float64(step)/float64(a.StepLimit))*(a.Max-a.Min)
+}
+
+// Step converts a joint angle (or distance for prismatic axes) into the nearest
+// stepper position. It does not check that the position is within range.
+func (a Axis) Step(angle float64) int {
+	if a.Max == a.Min {
+		return 0
+	}
+	return int(math.Round((angle - a.Min) / (a.Max - a.Min) * float64(a.StepLimit)))
+}
+
+// Capabilities describe an arm. Kinematics is nil if the arm has no kinematic
+// model. Arms with a kinematic model have their six revolute joints as the
+// first six axes. Other axes, such as a track, are not part of the kinematic
+// model, which describes the arm as if they are at 0 (see CheckModeled).
+// Collision is nil if the arm has no collision geometry.
+type Capabilities struct {
+	Model      string
+	Axes       []Axis
+	Kinematics *kinematics.DhParameters
+	Collision  *kinematics.CollisionModel
+}
+
+// ErrNoKinematics is returned when an arm does not have a kinematic model.
+var ErrNoKinematics = errors.New("Arm does not have a kinematic model")
+
+// ErrUnmodeledAxis is returned when an axis that is not part of the kinematic
+// model has moved away from 0, so poses in the world cannot be found.
+var ErrUnmodeledAxis = errors.New("Axis outside of the kinematic model has moved")
+
+// Add applies a relative move to a position, checking that the resulting
+// position is within the range of each axis.
+func (c Capabilities) Add(position Joints, delta Joints) (Joints, error) {
+	if len(position) != len(c.Axes) || len(delta) != len(c.Axes) {
+		return nil, fmt.Errorf("%s has %d axes. Got position with %d and move with %d", c.Model, len(c.Axes), len(position), len(delta))
+	}
+	target := make(Joints, len(c.Axes))
+	for i, axis := range c.Axes {
+		target[i] = position[i] + delta[i]
+		if axis.StepLimit != 0 && (target[i] < 0 || target[i] > axis.StepLimit) {
+			return nil, fmt.Errorf("%s out of range. Must be between 0 and %d. Got %d", axis.Name, axis.StepLimit, target[i])
+		}
+	}
+	return target, nil
+}
+
+// Theta converts the first six axes of a position into joint angles for use
+// with the kinematics package.
+func (c Capabilities) Theta(position Joints) (kinematics.StepperTheta, error) {
+	if c.Kinematics == nil {
+		return kinematics.StepperTheta{}, ErrNoKinematics
+	}
+	if len(position) < 6 || len(c.Axes) < 6 {
+		return kinematics.StepperTheta{}, fmt.Errorf("Kinematics requires 6 axes. Got %d", len(position))
+	}
+	return kinematics.StepperTheta{
+		J1: c.Axes[0].Angle(position[0]),
+		J2: c.Axes[1].Angle(position[1]),
+		J3: c.Axes[2].Angle(position[2]),
+		J4: c.Axes[3].Angle(position[3]),
+		J5: c.Axes[4].Angle(position[4]),
+		J6: c.Axes[5].Angle(position[5]),
+	}, nil
+}
+
+// CheckModeled checks that every axis after the first six is at 0, so that the
+// kinematic model describes where the arm is in the world. Joint angles from
+// Theta are correct either way, but poses found from them are only in the
+// world if CheckModeled passes.
+func (c Capabilities) CheckModeled(position Joints) error {
+	for i := 6; i < len(position) && i < len(c.Axes); i++ {
+		if position[i] != 0 {
+			return fmt.Errorf("%w. %s is at %d", ErrUnmodeledAxis, c.Axes[i].Name, position[i])
+		}
+	}
+	return nil
+}
+
+// Steps converts joint angles into an absolute position. Axes after the first
+// six are taken from the current position, so that they do not move.
+func (c Capabilities) Steps(theta kinematics.StepperTheta, current Joints) (Joints, error) {
+	if c.Kinematics == nil {
+		return nil, ErrNoKinematics
+	}
+	if len(current) != len(c.Axes) || len(c.Axes) < 6 {
+		return nil, fmt.Errorf("Kinematics requires 6 axes. Got %d", len(current))
+	}
+	position := make(Joints, len(current))
+	copy(position, current)
+	for i, angle := range []float64{theta.J1, theta.J2, theta.J3, theta.J4, theta.J5, theta.J6} {
+		position[i] = c.Axes[i].Step(angle)
+	}
+	return position, nil
+}
+
+// JointLimits returns the range of the first six axes as joint limits for use
+// with the kinematics package.
+func (c Capabilities) JointLimits() (kinematics.JointLimits, error) {
+	if c.Kinematics == nil {
+		return kinematics.JointLimits{}, ErrNoKinematics
+	}
+	if len(c.Axes) < 6 {
+		return kinematics.JointLimits{}, fmt.Errorf("Kinematics requires 6 axes. Got %d", len(c.Axes))
+	}
+	return kinematics.JointLimits{
+		Min: kinematics.StepperTheta{J1: c.Axes[0].Min, J2: c.Axes[1].Min, J3: c.Axes[2].Min, J4: c.Axes[3].Min, J5: c.Axes[4].Min, J6: c.Axes[5].Min},
+		Max: kinematics.StepperTheta{J1: c.Axes[0].Max, J2: c.Axes[1].Max, J3: c.Axes[2].Max, J4: c.Axes[3].Max, J5: c.Axes[4].Max, J6: c.Axes[5].Max},
+	}, nil
+}
+
+// VelocityOptions returns kinematics.DefaultVelocityOptions with the maximum
+// velocity of the first six axes.
+func (c Capabilities) VelocityOptions() kinematics.VelocityOptions {
+	options := kinematics.DefaultVelocityOptions
+	for i := 0; i < 6 && i < len(c.Axes); i++ {
+		options.MaxVelocity[i] = c.Axes[i].MaxVelocity
+	}
+	return options
+}
+
+// IntegrateVelocities converts joint velocities of the first six axes, in
+// radians per second, into a relative stepper move over dt seconds. Steps are
+// whole numbers, so the fractional part of each move is carried over in
+// remainder, which must have one value per axis.
+func (c Capabilities) IntegrateVelocities(velocities [6]float64, dt float64, remainder []float64) Joints {
+	delta := make(Joints, len(c.Axes))
+	for i, axis := range c.Axes {
+		if i >= len(velocities) || axis.StepLimit == 0 || axis.Max == axis.Min {
+			continue
+		}
+		steps := velocities[i]*dt*float64(axis.StepLimit)/(axis.Max-axis.Min) + remainder[i]
+		delta[i] = int(steps)
+		remainder[i] = steps - float64(delta[i])
+	}
+	return delta
+}
+
+// TrajectoryLimits returns the velocity, acceleration and jerk limits of every
+// axis in steps, for use with the trajectory package. Axes without a known
+// range have limits of 0.
+func (c Capabilities) TrajectoryLimits() trajectory.Limits {
+	limits := trajectory.Limits{Velocity: make([]float64, len(c.Axes)), Acceleration: make([]float64, len(c.Axes)), Jerk: make([]float64, len(c.Axes))}
+	for i, axis := range c.Axes {
+		if axis.StepLimit == 0 || axis.Max == axis.Min {
+			continue
+		}
+		stepsPerUnit := math.Abs(float64(axis.StepLimit) / (axis.Max - axis.Min))
+		limits.Velocity[i] = axis.MaxVelocity * stepsPerUnit
+		limits.Acceleration[i] = axis.MaxAcceleration * stepsPerUnit
+		limits.Jerk[i] = axis.MaxJerk * stepsPerUnit
+	}
+	return limits
+}
+
+// Trajectory plans a trajectory through absolute stepper positions at the
+// limits of each axis. Sampled positions are in steps, and the duration of the
+// trajectory is an estimate of how long the moves take.
+func (c Capabilities) Trajectory(waypoints []Joints, profile trajectory.Profile) (trajectory.Trajectory, error) {
+	return trajectory.Plan(jointsToFloats(waypoints), c.TrajectoryLimits(), profile)
+}
+
+// jointsToFloats converts stepper positions into waypoints for the trajectory
+// package.
+func jointsToFloats(waypoints []Joints) [][]float64 {
+	floats := make([][]float64, len(waypoints))
+	for i, waypoint := range waypoints {
+		floats[i] = make([]float64, len(waypoint))
+		for j, step := range waypoint {
+			floats[i][j] = float64(step)
+		}
+	}
+	return floats
+}
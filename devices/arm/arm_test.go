@@ -0,0 +1,120 @@
+package arm
+
+import (
+	"errors"
+	"github.com/koeng101/armos/utils/kinematics"
+	"github.com/koeng101/armos/utils/trajectory"
+	"math"
+	"testing"
+)
+
+var testCapabilities = Capabilities{
+	Model: "test",
+	Axes: []Axis{
+		{Name: "J1", StepLimit: 1000, Min: -math.Pi, Max: math.Pi},
+		{Name: "J2", StepLimit: 1000, Min: -math.Pi, Max: math.Pi},
+		{Name: "J3", StepLimit: 1000, Min: -math.Pi, Max: math.Pi},
+		{Name: "J4", StepLimit: 1000, Min: -math.Pi, Max: math.Pi},
+		{Name: "J5", StepLimit: 1000, Min: -math.Pi, Max: math.Pi},
+		{Name: "J6", StepLimit: 1000, Min: -math.Pi, Max: math.Pi},
+		{Name: "Tr", Prismatic: true},
+	},
+	Kinematics: &kinematics.AR3DhParameters,
+}
+
+func TestCapabilities_Add(t *testing.T) {
+	target, err := testCapabilities.Add(Joints{0, 0, 0, 0, 0, 0, 0}, Joints{10, 20, 30, 40, 50, 60, -70})
+	if err != nil {
+		t.Errorf("Failed to add move: %s", err)
+	}
+	if target[1] != 20 || target[6] != -70 {
+		t.Errorf("Unexpected target %v", target)
+	}
+	_, err = testCapabilities.Add(Joints{0, 0, 0, 0, 0, 0, 0}, Joints{1001, 0, 0, 0, 0, 0, 0})
+	if err == nil {
+		t.Errorf("Move past step limit should have failed")
+	}
+}
+
+func TestCapabilities_Theta(t *testing.T) {
+	theta, err := testCapabilities.Theta(Joints{500, 0, 1000, 500, 500, 500, 0})
+	if err != nil {
+		t.Errorf("Failed to convert position: %s", err)
+	}
+	if theta.J1 != 0 || theta.J2 != -math.Pi || theta.J3 != math.Pi {
+		t.Errorf("Unexpected theta %v", theta)
+	}
+	position, err := testCapabilities.Steps(theta, Joints{0, 0, 0, 0, 0, 0, 42})
+	if err != nil {
+		t.Errorf("Failed to convert theta: %s", err)
+	}
+	if position[0] != 500 || position[2] != 1000 || position[6] != 42 {
+		t.Errorf("Unexpected position %v", position)
+	}
+}
+
+func TestCapabilities_CheckModeled(t *testing.T) {
+	if err := testCapabilities.CheckModeled(Joints{500, 0, 1000, 500, 500, 500, 0}); err != nil {
+		t.Errorf("Expected the track at 0 to be modeled. Got %s", err)
+	}
+	if err := testCapabilities.CheckModeled(Joints{500, 0, 1000, 500, 500, 500, -3}); !errors.Is(err, ErrUnmodeledAxis) {
+		t.Errorf("Expected ErrUnmodeledAxis. Got %v", err)
+	}
+}
+
+func TestCapabilities_JointLimits(t *testing.T) {
+	limits, err := testCapabilities.JointLimits()
+	if err != nil {
+		t.Errorf("Failed to get joint limits: %s", err)
+	}
+	if limits.Min.J1 != -math.Pi || limits.Max.J6 != math.Pi {
+		t.Errorf("Unexpected joint limits %v", limits)
+	}
+}
+
+func TestCapabilities_IntegrateVelocities(t *testing.T) {
+	// 1000 steps cover 2 pi radians, so 2 pi / 1000 radians per second is one
+	// step per second.
+	velocity := 2 * math.Pi / 1000
+	remainder := make([]float64, 7)
+	total := make(Joints, 7)
+	for i := 0; i < 10; i++ {
+		delta := testCapabilities.IntegrateVelocities([6]float64{velocity, -velocity, 0, 0, 0, 0}, 0.25, remainder)
+		for j := range total {
+			total[j] = total[j] + delta[j]
+		}
+	}
+	if total[0] < 2 || total[0] > 3 || total[1] > -2 || total[1] < -3 {
+		t.Errorf("Expected 2.5 steps carried over between moves. Got %v", total)
+	}
+}
+
+func TestCapabilities_Trajectory(t *testing.T) {
+	capabilities := testCapabilities
+	capabilities.Axes = append([]Axis{}, testCapabilities.Axes...)
+	for i := 0; i < 6; i++ {
+		capabilities.Axes[i].MaxVelocity = math.Pi
+		capabilities.Axes[i].MaxAcceleration = math.Pi
+	}
+
+	// 1000 steps cover 2 pi radians, so pi radians per second is 500 steps
+	// per second. 1000 steps take 1s to accelerate, 1s to cruise and 1s to
+	// stop.
+	limits := capabilities.TrajectoryLimits()
+	if math.Abs(limits.Velocity[0]-500) > 1e-9 || limits.Velocity[6] != 0 {
+		t.Errorf("Unexpected limits %v", limits)
+	}
+	plan, err := capabilities.Trajectory([]Joints{{0, 0, 0, 0, 0, 0, 0}, {1000, 500, 0, 0, 0, 0, 0}}, trajectory.Trapezoidal)
+	if err != nil {
+		t.Fatalf("Failed to plan trajectory: %s", err)
+	}
+	if math.Abs(plan.Duration()-3) > 1e-9 {
+		t.Errorf("Expected a 3s trajectory. Got %f", plan.Duration())
+	}
+
+	// The track has no limits, so it cannot be planned for
+	_, err = capabilities.Trajectory([]Joints{{0, 0, 0, 0, 0, 0, 0}, {0, 0, 0, 0, 0, 0, 10}}, trajectory.Trapezoidal)
+	if err == nil {
+		t.Errorf("Moving the track should fail")
+	}
+}
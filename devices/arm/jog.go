@@ -0,0 +1,264 @@
+package arm
+
+import (
+	"errors"
+	"github.com/koeng101/armos/utils/kinematics"
+	"math"
+	"sync"
+	"time"
+)
+
+// ErrHeartbeatTimeout is the error of a Jogger that stopped because it did not
+// receive a heartbeat in time.
+var ErrHeartbeatTimeout = errors.New("Jogging stopped because no heartbeat was received")
+
+// JogOptions define how a Jogger moves the arm. Every Period, the Jogger sends
+// a small move to the arm. If no heartbeat is received for Timeout, the Jogger
+// stops. Check is optional, and is called with the current position and the
+// move before each move is sent. If Check returns an error, the Jogger stops.
+// Lock is optional, and is held from reading the current position until the
+// move is sent, so that other code moving the same arm can hold it to keep
+// its moves from interleaving with jog moves.
+type JogOptions struct {
+	Period  time.Duration
+	Timeout time.Duration
+	Check   func(current Joints, delta Joints) error
+	Lock    sync.Locker
+}
+
+// DefaultJogOptions are good defaults for jogging with a gamepad or UI.
+var DefaultJogOptions = JogOptions{Period: 100 * time.Millisecond, Timeout: 500 * time.Millisecond}
+
+// Jogger continuously moves an arm at a velocity, either in joint space or in
+// cartesian space. Jogging has to be kept alive with heartbeats, so that the
+// arm stops if the controlling client goes away.
+type Jogger struct {
+	arm     Arm
+	motion  MotionParameters
+	options JogOptions
+
+	mu            sync.Mutex
+	jogging       bool
+	cartesian     bool
+	jointVelocity []float64
+	linear        [3]float64
+	angular       [3]float64
+	frames        kinematics.Frames
+	lastHeartbeat time.Time
+	stop          chan struct{}
+	done          chan struct{}
+	err           error
+}
+
+// NewJogger creates a Jogger for an arm. Moves made while jogging use the
+// given motion parameters.
+func NewJogger(a Arm, motion MotionParameters, options JogOptions) *Jogger {
+	return &Jogger{arm: a, motion: motion, options: options}
+}
+
+// JogJoints starts jogging in joint space. Velocity is in steps per second,
+// one per axis of the arm. Each velocity is clamped to the MaxVelocity of its
+// axis. If the Jogger is already jogging, the velocity is updated.
+func (j *Jogger) JogJoints(velocity []float64) error {
+	capabilities := j.arm.Capabilities()
+	if len(velocity) != len(capabilities.Axes) {
+		return errors.New("Jog velocity must have one value per axis")
+	}
+	clamped := append([]float64{}, velocity...)
+	for i, limit := range capabilities.TrajectoryLimits().Velocity {
+		if capabilities.Axes[i].MaxVelocity > 0 {
+			clamped[i] = math.Max(-limit, math.Min(limit, clamped[i]))
+		}
+	}
+	j.mu.Lock()
+	j.cartesian = false
+	j.jointVelocity = clamped
+	j.mu.Unlock()
+	j.start()
+	return nil
+}
+
+// JogCartesian starts jogging in cartesian space. Linear velocity is in
+// millimeters per second and angular velocity is in radians per second, both
+// of the tool center point in the world (see SetFrames). Joint moves are
+// found with resolved-rate motion, and respect the MaxVelocity of each axis.
+// If the Jogger is already jogging, the velocity is updated.
+func (j *Jogger) JogCartesian(linear [3]float64, angular [3]float64) error {
+	if j.arm.Capabilities().Kinematics == nil {
+		return ErrNoKinematics
+	}
+	j.mu.Lock()
+	j.cartesian = true
+	j.linear = linear
+	j.angular = angular
+	j.mu.Unlock()
+	j.start()
+	return nil
+}
+
+// SetFrames sets the base and tool offsets used for cartesian jogging, so
+// that velocities are of the tool center point in the world.
+func (j *Jogger) SetFrames(frames kinematics.Frames) {
+	j.mu.Lock()
+	j.frames = frames
+	j.mu.Unlock()
+}
+
+// Heartbeat keeps the Jogger alive.
+func (j *Jogger) Heartbeat() {
+	j.mu.Lock()
+	j.lastHeartbeat = time.Now()
+	j.mu.Unlock()
+}
+
+// Stop stops jogging and waits for the last move to be sent.
+func (j *Jogger) Stop() {
+	j.mu.Lock()
+	stop, done := j.stop, j.done
+	j.stop = nil
+	j.jogging = false
+	j.mu.Unlock()
+	if stop == nil {
+		return
+	}
+	close(stop)
+	<-done
+}
+
+// Jogging returns whether the Jogger is currently moving the arm.
+func (j *Jogger) Jogging() bool {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.jogging
+}
+
+// Err returns the reason the Jogger last stopped on its own, such as a missed
+// heartbeat or a failed move. It is reset when jogging starts again.
+func (j *Jogger) Err() error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.err
+}
+
+// start starts the jogging loop if it is not already running.
+func (j *Jogger) start() {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.lastHeartbeat = time.Now()
+	if j.jogging {
+		return
+	}
+	j.jogging = true
+	j.err = nil
+	j.stop = make(chan struct{})
+	j.done = make(chan struct{})
+	go j.loop(j.stop, j.done)
+}
+
+// loop sends a move to the arm every period until stopped.
+func (j *Jogger) loop(stop chan struct{}, done chan struct{}) {
+	ticker := time.NewTicker(j.options.Period)
+	defer ticker.Stop()
+	defer close(done)
+	remainder := make([]float64, len(j.arm.Capabilities().Axes))
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			j.mu.Lock()
+			expired := time.Since(j.lastHeartbeat) > j.options.Timeout
+			j.mu.Unlock()
+			if expired {
+				j.finish(stop, ErrHeartbeatTimeout)
+				return
+			}
+			err := j.step(j.options.Period.Seconds(), remainder)
+			if err != nil {
+				j.finish(stop, err)
+				return
+			}
+		}
+	}
+}
+
+// finish marks the Jogger as stopped on its own, unless the loop was already
+// stopped with Stop, in which case jogging may have started again.
+func (j *Jogger) finish(stop chan struct{}, err error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if j.stop != stop {
+		return
+	}
+	j.jogging = false
+	j.stop = nil
+	j.err = err
+}
+
+// step moves the arm by the current velocity for dt seconds. Fractional steps
+// are carried over in remainder.
+func (j *Jogger) step(dt float64, remainder []float64) error {
+	j.mu.Lock()
+	cartesian := j.cartesian
+	jointVelocity := j.jointVelocity
+	linear, angular := j.linear, j.angular
+	frames := j.frames
+	j.mu.Unlock()
+
+	if j.options.Lock != nil {
+		j.options.Lock.Lock()
+		defer j.options.Lock.Unlock()
+	}
+	current := j.arm.CurrentPosition()
+	var delta Joints
+	if cartesian {
+		var err error
+		delta, err = cartesianJogDelta(j.arm.Capabilities(), frames, current, linear, angular, dt, remainder)
+		if err != nil {
+			return err
+		}
+	} else {
+		// Steps are whole numbers, so carry the fractional part of each move
+		// over into the next one.
+		delta = make(Joints, len(current))
+		for i := range delta {
+			steps := jointVelocity[i]*dt + remainder[i]
+			delta[i] = int(steps)
+			remainder[i] = steps - float64(delta[i])
+		}
+	}
+	if j.options.Check != nil {
+		err := j.options.Check(current, delta)
+		if err != nil {
+			return err
+		}
+	}
+	return j.arm.MoveSteppers(j.motion, delta)
+}
+
+// cartesianJogDelta returns the joint move that moves the tool center point by
+// a linear and angular velocity for dt seconds, using resolved-rate motion.
+// Fractional steps are carried over in remainder.
+func cartesianJogDelta(capabilities Capabilities, frames kinematics.Frames, current Joints, linear [3]float64, angular [3]float64, dt float64, remainder []float64) (Joints, error) {
+	theta, err := capabilities.Theta(current)
+	if err != nil {
+		return nil, err
+	}
+	flange := kinematics.ForwardKinematics(theta, *capabilities.Kinematics)
+	twist := frames.FlangeTwist(kinematics.Twist{Linear: linear, Angular: angular}, flange)
+	velocities := kinematics.JointVelocities(twist, theta, *capabilities.Kinematics, capabilities.VelocityOptions())
+	delta := capabilities.IntegrateVelocities(velocities, dt, remainder)
+
+	target, err := capabilities.Add(current, delta)
+	if err != nil {
+		return nil, err
+	}
+	targetTheta, err := capabilities.Theta(target)
+	if err != nil {
+		return nil, err
+	}
+	if approachesSingularity(*capabilities.Kinematics, theta, targetTheta) {
+		return nil, errors.New("Jogging stopped near a singularity")
+	}
+	return delta, nil
+}
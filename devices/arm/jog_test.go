@@ -0,0 +1,118 @@
+package arm
+
+import (
+	"github.com/koeng101/armos/utils/kinematics"
+	"math"
+	"testing"
+	"time"
+)
+
+var testJogOptions = JogOptions{Period: 5 * time.Millisecond, Timeout: 50 * time.Millisecond}
+
+func TestJogger_JogJoints(t *testing.T) {
+	a := &testArm{position: Joints{600, 550, 650, 520, 600, 580, 0}}
+	jogger := NewJogger(a, DefaultMotionParameters, testJogOptions)
+	err := jogger.JogJoints([]float64{1000, 0, 0, 0, 0, 0, 0})
+	if err != nil {
+		t.Errorf("Failed to start jogging: %s", err)
+	}
+	time.Sleep(30 * time.Millisecond)
+	jogger.Stop()
+	if jogger.Jogging() {
+		t.Errorf("Jogger should have stopped")
+	}
+	if a.position[0] <= 600 || a.position[1] != 550 {
+		t.Errorf("Only J1 should have moved. Got %v", a.position)
+	}
+}
+
+func TestJogger_HeartbeatTimeout(t *testing.T) {
+	a := &testArm{position: Joints{600, 550, 650, 520, 600, 580, 0}}
+	jogger := NewJogger(a, DefaultMotionParameters, testJogOptions)
+	_ = jogger.JogJoints([]float64{0, 100, 0, 0, 0, 0, 0})
+
+	// Without heartbeats, the Jogger should stop by itself
+	time.Sleep(200 * time.Millisecond)
+	if jogger.Jogging() {
+		t.Errorf("Jogger should have stopped without heartbeats")
+	}
+	if jogger.Err() != ErrHeartbeatTimeout {
+		t.Errorf("Expected ErrHeartbeatTimeout. Got %v", jogger.Err())
+	}
+	jogger.Stop()
+}
+
+func TestJogger_JogCartesian(t *testing.T) {
+	a := &testArm{position: Joints{600, 550, 650, 520, 600, 580, 0}}
+	theta, _ := testCapabilities.Theta(a.position)
+	start := kinematics.ForwardKinematics(theta, kinematics.AR3DhParameters)
+	jogger := NewJogger(a, DefaultMotionParameters, testJogOptions)
+	err := jogger.JogCartesian([3]float64{0, 0, 100}, [3]float64{})
+	if err != nil {
+		t.Errorf("Failed to start jogging: %s", err)
+	}
+	time.Sleep(30 * time.Millisecond)
+	jogger.Heartbeat()
+	time.Sleep(30 * time.Millisecond)
+	jogger.Stop()
+	if jogger.Err() != nil {
+		t.Errorf("Cartesian jogging failed: %s", jogger.Err())
+	}
+
+	// The end effector should have moved up without drifting much sideways.
+	theta, _ = testCapabilities.Theta(a.position)
+	end := kinematics.ForwardKinematics(theta, kinematics.AR3DhParameters)
+	if end.Z <= start.Z || math.Abs(end.X-start.X) > 5 || math.Abs(end.Y-start.Y) > 5 {
+		t.Errorf("Expected the end effector to move up from %v. Got %v", start, end)
+	}
+}
+
+// limitedArm is a testArm with a MaxVelocity on J1.
+type limitedArm struct {
+	*testArm
+}
+
+func (a limitedArm) Capabilities() Capabilities {
+	capabilities := testCapabilities
+	capabilities.Axes = append([]Axis{}, testCapabilities.Axes...)
+	capabilities.Axes[0].MaxVelocity = math.Pi
+	return capabilities
+}
+
+func TestJogger_JogJointsClamped(t *testing.T) {
+	a := limitedArm{&testArm{position: Joints{600, 550, 650, 520, 600, 580, 0}}}
+	jogger := NewJogger(a, DefaultMotionParameters, testJogOptions)
+	defer jogger.Stop()
+
+	// J1 can move half a turn, 500 steps, per second. Axes without a
+	// MaxVelocity are not clamped.
+	_ = jogger.JogJoints([]float64{-2000, 2000, 0, 0, 0, 0, 0})
+	jogger.mu.Lock()
+	velocity := jogger.jointVelocity
+	jogger.mu.Unlock()
+	if velocity[0] != -500 || velocity[1] != 2000 {
+		t.Errorf("Expected J1 to be clamped to -500 steps per second. Got %v", velocity)
+	}
+}
+
+func TestJogger_Restart(t *testing.T) {
+	a := &testArm{position: Joints{600, 550, 650, 520, 600, 580, 0}}
+	jogger := NewJogger(a, DefaultMotionParameters, testJogOptions)
+	for i := 0; i < 3; i++ {
+		_ = jogger.JogJoints([]float64{1000, 0, 0, 0, 0, 0, 0})
+		if !jogger.Jogging() {
+			t.Errorf("Jogger should be jogging after a restart")
+		}
+		jogger.Stop()
+		if jogger.Jogging() {
+			t.Errorf("Jogger should have stopped")
+		}
+	}
+	start := a.position[0]
+	_ = jogger.JogJoints([]float64{1000, 0, 0, 0, 0, 0, 0})
+	time.Sleep(30 * time.Millisecond)
+	jogger.Stop()
+	if a.position[0] <= start || jogger.Err() != nil {
+		t.Errorf("J1 should have moved after restarting. Got %v, %v", a.position, jogger.Err())
+	}
+}
@@ -0,0 +1,118 @@
+package arm
+
+import (
+	"fmt"
+	"github.com/koeng101/armos/utils/kinematics"
+	"math"
+)
+
+// MoveLOptions define how a straight line move is split into joint moves.
+// SegmentLength is the maximum length of a segment in millimeters, and
+// SegmentRotation is the maximum rotation of a segment in radians.
+// MaxJointStep is the largest change of any joint, in radians, allowed within
+// a single segment. Larger changes mean the path passes near a singularity or
+// through a change of configuration, so the move is rejected. Frames are the
+// base and tool offsets, so that the tool center point moves in a straight
+// line in the world.
+type MoveLOptions struct {
+	SegmentLength   float64
+	SegmentRotation float64
+	MaxJointStep    float64
+	Frames          kinematics.Frames
+}
+
+// DefaultMoveLOptions are good defaults for straight line moves.
+var DefaultMoveLOptions = MoveLOptions{SegmentLength: 5, SegmentRotation: 5 * math.Pi / 180, MaxJointStep: 10 * math.Pi / 180}
+
+// PlanMoveL plans a straight line move of the tool from the current position
+// to the target. The path is split into small segments, and each
+// segment uses the inverse kinematics solution nearest to the previous one.
+// It returns the absolute position at the end of each segment. Paths that
+// leave the range of an axis or move towards a singularity return an error, as
+// do arms with an axis outside of the kinematic model away from 0.
+func PlanMoveL(capabilities Capabilities, current Joints, target kinematics.XyzWxyz, options MoveLOptions) ([]Joints, error) {
+	theta, err := capabilities.Theta(current)
+	if err != nil {
+		return nil, err
+	}
+	err = capabilities.CheckModeled(current)
+	if err != nil {
+		return nil, err
+	}
+	limits, err := capabilities.JointLimits()
+	if err != nil {
+		return nil, err
+	}
+
+	// Solve each sample, staying as close as possible to the previous
+	// solution without changing configuration
+	pathOptions := kinematics.PathOptions{
+		SegmentLength:   options.SegmentLength,
+		SegmentRotation: options.SegmentRotation,
+		MaxJointStep:    options.MaxJointStep,
+		Frames:          options.Frames,
+		Nearest:         kinematics.NearestOptions{Limits: &limits, KeepConfiguration: true},
+	}
+	cartesianPath, err := kinematics.PlanCartesianPath([]kinematics.XyzWxyz{target}, *capabilities.Kinematics, theta, pathOptions)
+	if err != nil {
+		return nil, err
+	}
+
+	// Convert each sample into stepper positions
+	samples := cartesianPath.Segments[0].Samples
+	var path []Joints
+	previousTheta := theta
+	previous := current
+	for i, sample := range samples {
+		if approachesSingularity(*capabilities.Kinematics, previousTheta, sample.Theta) {
+			return nil, fmt.Errorf("Segment %d of %d approaches a singularity", i+1, len(samples))
+		}
+		position, err := capabilities.Steps(sample.Theta, previous)
+		if err != nil {
+			return nil, err
+		}
+		delta := make(Joints, len(position))
+		for j := range position {
+			delta[j] = position[j] - previous[j]
+		}
+		_, err = capabilities.Add(previous, delta)
+		if err != nil {
+			return nil, fmt.Errorf("Segment %d of %d is out of range: %s", i+1, len(samples), err)
+		}
+		path = append(path, position)
+		previous = position
+		previousTheta = sample.Theta
+	}
+	return path, nil
+}
+
+// MoveL moves the end effector of an arm in a straight line to the target.
+// The whole path is planned before the arm is moved, so a path that cannot be
+// completed does not move the arm at all.
+func MoveL(a Arm, motion MotionParameters, target kinematics.XyzWxyz, options MoveLOptions) error {
+	current := a.CurrentPosition()
+	path, err := PlanMoveL(a.Capabilities(), current, target, options)
+	if err != nil {
+		return err
+	}
+	for _, position := range path {
+		delta := make(Joints, len(position))
+		for i := range position {
+			delta[i] = position[i] - current[i]
+		}
+		err = a.MoveSteppers(motion, delta)
+		if err != nil {
+			return err
+		}
+		current = position
+	}
+	return nil
+}
+
+// approachesSingularity checks if a move ends near a singularity and is worse
+// conditioned than where it started. Moves that start near a singularity may
+// still move away from it.
+func approachesSingularity(dhParameters kinematics.DhParameters, from, to kinematics.StepperTheta) bool {
+	condition := kinematics.ConditionNumber(to, dhParameters)
+	return condition > kinematics.SingularityThreshold && condition > kinematics.ConditionNumber(from, dhParameters)
+}
@@ -0,0 +1,84 @@
+package arm
+
+import (
+	"errors"
+	"github.com/koeng101/armos/utils/kinematics"
+	"math"
+	"testing"
+)
+
+// testArm is a minimal simulated arm for testing.
+type testArm struct {
+	position Joints
+}
+
+func (a *testArm) Capabilities() Capabilities { return testCapabilities }
+func (a *testArm) Echo() error                { return nil }
+func (a *testArm) CurrentPosition() Joints    { return append(Joints{}, a.position...) }
+func (a *testArm) Calibrate(speed int, axes []bool) error {
+	return nil
+}
+func (a *testArm) MoveSteppers(motion MotionParameters, delta Joints) error {
+	target, err := testCapabilities.Add(a.position, delta)
+	if err != nil {
+		return err
+	}
+	a.position = target
+	return nil
+}
+func (a *testArm) SetDirections(directions []bool) error { return nil }
+func (a *testArm) Directions() []bool                    { return make([]bool, 7) }
+
+func TestMoveL(t *testing.T) {
+	a := &testArm{position: Joints{600, 550, 650, 520, 600, 580, 0}}
+	theta, _ := testCapabilities.Theta(a.position)
+	target := kinematics.ForwardKinematics(theta, kinematics.AR3DhParameters)
+	target.X = target.X + 20
+
+	err := MoveL(a, DefaultMotionParameters, target, DefaultMoveLOptions)
+	if err != nil {
+		t.Errorf("MoveL failed: %s", err)
+	}
+
+	// The arm should be close to the target. Steps are rounded, so allow a
+	// few millimeters of error.
+	theta, _ = testCapabilities.Theta(a.position)
+	final := kinematics.ForwardKinematics(theta, kinematics.AR3DhParameters)
+	if math.Abs(final.X-target.X) > 5 || math.Abs(final.Y-target.Y) > 5 || math.Abs(final.Z-target.Z) > 5 {
+		t.Errorf("MoveL ended at %v instead of %v", final, target)
+	}
+}
+
+func TestPlanMoveL_OutOfReach(t *testing.T) {
+	current := Joints{600, 550, 650, 520, 600, 580, 0}
+	theta, _ := testCapabilities.Theta(current)
+	target := kinematics.ForwardKinematics(theta, kinematics.AR3DhParameters)
+	target.X = target.X + 2000
+
+	_, err := PlanMoveL(testCapabilities, current, target, DefaultMoveLOptions)
+	if err == nil {
+		t.Errorf("PlanMoveL should have failed on a target out of reach")
+	}
+
+	// With the track moved, the arm is not where the kinematic model says
+	current[6] = 100
+	target.X = target.X - 2000
+	_, err = PlanMoveL(testCapabilities, current, target, DefaultMoveLOptions)
+	if !errors.Is(err, ErrUnmodeledAxis) {
+		t.Errorf("Expected ErrUnmodeledAxis. Got %v", err)
+	}
+}
+
+func TestPlanMoveL_Singularity(t *testing.T) {
+	// Straighten the wrist by rotating the end effector towards J5 = 0.
+	current := Joints{600, 550, 650, 520, 600, 580, 0}
+	theta, _ := testCapabilities.Theta(current)
+	singular := theta
+	singular.J5 = 0
+	target := kinematics.ForwardKinematics(singular, kinematics.AR3DhParameters)
+
+	_, err := PlanMoveL(testCapabilities, current, target, DefaultMoveLOptions)
+	if err == nil {
+		t.Errorf("PlanMoveL should have failed on a target at a singularity")
+	}
+}
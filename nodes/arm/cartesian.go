@@ -0,0 +1,399 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/koeng101/armos/devices/arm"
+	"github.com/koeng101/armos/utils/kinematics"
+	"io/ioutil"
+	"math"
+	"net/http"
+)
+
+/******************************************************************************
+
+                                armos arm cartesian
+
+1. /movel moves the end effector in a straight line to a pose.
+2. /conditioning rates how close a position is to a singularity.
+3. /pose returns the current pose of the selected tool.
+4. /plan_path checks a path of straight lines through poses without moving.
+
+Poses can be given with rotation as a quaternion (qw, qx, qy, qz) or as a, b, c
+in degrees. a is around the z axis, then b around the new y axis, then c around
+the new x axis, like ARCS. If the quaternion is all zeros, a, b, c are used.
+
+******************************************************************************/
+
+// MoveLInput is the input to a MoveL command. The pose is of the selected tool
+// in millimeters in the world, with rotation as a quaternion or as a, b, c. If
+// segmentlength is 0, segments are 5mm long. Otherwise it must be at least
+// 0.1mm.
+type MoveLInput struct {
+	Speed         int     `json:"speed"`
+	Accdur        int     `json:"accdur"`
+	Accspd        int     `json:"accspd"`
+	Dccdur        int     `json:"dccdur"`
+	Dccspd        int     `json:"dccspd"`
+	X             float64 `json:"x"`
+	Y             float64 `json:"y"`
+	Z             float64 `json:"z"`
+	Qw            float64 `json:"qw"`
+	Qx            float64 `json:"qx"`
+	Qy            float64 `json:"qy"`
+	Qz            float64 `json:"qz"`
+	A             float64 `json:"a"`
+	B             float64 `json:"b"`
+	C             float64 `json:"c"`
+	SegmentLength float64 `json:"segmentlength"`
+}
+
+// minSegmentLength is the shortest segment in millimeters a request may ask
+// for. Shorter segments take too long to plan.
+const minSegmentLength = 0.1
+
+// segmentLength returns the requested segment length, or def if it is 0.
+// Negative lengths and lengths below minSegmentLength return an error.
+func segmentLength(requested, def float64) (float64, error) {
+	if requested == 0 {
+		return def, nil
+	}
+	if requested < minSegmentLength {
+		return 0, fmt.Errorf("Segment length must be at least %gmm. Got %f", minSegmentLength, requested)
+	}
+	return requested, nil
+}
+
+// Pose is the position of the selected tool in millimeters in the world, with
+// rotation both as a quaternion and as a, b, c in degrees.
+type Pose struct {
+	X  float64 `json:"x"`
+	Y  float64 `json:"y"`
+	Z  float64 `json:"z"`
+	Qw float64 `json:"qw"`
+	Qx float64 `json:"qx"`
+	Qy float64 `json:"qy"`
+	Qz float64 `json:"qz"`
+	A  float64 `json:"a"`
+	B  float64 `json:"b"`
+	C  float64 `json:"c"`
+}
+
+// poseOf converts a position and a rotation into an XyzWxyz. The rotation is
+// the quaternion, or a, b, c in degrees if the quaternion is all zeros.
+func poseOf(x, y, z, qw, qx, qy, qz, a, b, c float64) kinematics.XyzWxyz {
+	if qw == 0 && qx == 0 && qy == 0 && qz == 0 {
+		return kinematics.XyzAbc{X: x, Y: y, Z: z, A: a * math.Pi / 180, B: b * math.Pi / 180, C: c * math.Pi / 180}.XyzWxyz()
+	}
+	return kinematics.XyzWxyz{X: x, Y: y, Z: z, Qw: qw, Qx: qx, Qy: qy, Qz: qz}
+}
+
+// abcOf returns the rotation of a pose as a, b, c in degrees.
+func abcOf(pose kinematics.XyzWxyz) (float64, float64, float64) {
+	abc := pose.XyzAbc()
+	return abc.A * 180 / math.Pi, abc.B * 180 / math.Pi, abc.C * 180 / math.Pi
+}
+
+// MoveL moves the selected tool in a straight line.
+// @Summary Move the tool in a straight line
+// @Tags cartesian
+// @Description Moves the selected tool in a straight line to a pose in the world by splitting the path into small joint moves. The whole path is checked against joint ranges, singularities, soft limits and keep-out zones before the arm moves. Paths that move towards a singularity are rejected, and moves are refused with 409 while the arm is jogging.
+// @Accept json
+// @Produce plain
+// @Param move body MoveLInput true "target pose"
+// @Success 200 {string} string
+// @Failure 400 {string} string
+// @Failure 409 {string} string
+// @Router /movel [post]
+func (app *App) MoveL(w http.ResponseWriter, r *http.Request) {
+	// Read body
+	reqBody, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		w.WriteHeader(400)
+		_ = json.NewEncoder(w).Encode(err.Error())
+		return
+	}
+
+	// Unmarshal
+	var m MoveLInput
+	err = json.Unmarshal(reqBody, &m)
+	if err != nil {
+		w.WriteHeader(400)
+		_ = json.NewEncoder(w).Encode(err.Error())
+		return
+	}
+	options := arm.DefaultMoveLOptions
+	options.Frames, err = app.kinematicFrames()
+	if err != nil {
+		w.WriteHeader(400)
+		_ = json.NewEncoder(w).Encode(err.Error())
+		return
+	}
+	options.SegmentLength, err = segmentLength(m.SegmentLength, options.SegmentLength)
+	if err != nil {
+		w.WriteHeader(400)
+		_ = json.NewEncoder(w).Encode(err.Error())
+		return
+	}
+	motion := arm.MotionParameters{Speed: m.Speed, AccelerationDuration: m.Accdur, AccelerationSpeed: m.Accspd, DecelerationDuration: m.Dccdur, DecelerationSpeed: m.Dccspd}
+	target := poseOf(m.X, m.Y, m.Z, m.Qw, m.Qx, m.Qy, m.Qz, m.A, m.B, m.C)
+
+	// Hold the arm until the move is sent, and refuse to move while jogging
+	app.ArmLock.Lock()
+	defer app.ArmLock.Unlock()
+	if app.Jogger.Jogging() {
+		w.WriteHeader(409)
+		_ = json.NewEncoder(w).Encode(errJogging.Error())
+		return
+	}
+
+	// Plan the path
+	current := app.Arm.CurrentPosition()
+	path, err := arm.PlanMoveL(app.Arm.Capabilities(), current, target, options)
+	if err != nil {
+		w.WriteHeader(400)
+		_ = json.NewEncoder(w).Encode(err.Error())
+		return
+	}
+
+	// Check every segment before moving
+	deltas := make([]arm.Joints, len(path))
+	from := current
+	for i, position := range path {
+		deltas[i] = make(arm.Joints, len(position))
+		for j := range position {
+			deltas[i][j] = position[j] - from[j]
+		}
+		err = app.CheckMove(from, deltas[i])
+		if err != nil {
+			w.WriteHeader(400)
+			_ = json.NewEncoder(w).Encode(err.Error())
+			return
+		}
+		from = position
+	}
+
+	// Stream the segments to the arm
+	for _, delta := range deltas {
+		err = app.Arm.MoveSteppers(motion, delta)
+		if err != nil {
+			w.WriteHeader(400)
+			_ = json.NewEncoder(w).Encode(err.Error())
+			return
+		}
+	}
+
+	_ = json.NewEncoder(w).Encode("success")
+}
+
+// ConditioningInput is an absolute stepper position to rate.
+type ConditioningInput struct {
+	J1 int `json:"j1"`
+	J2 int `json:"j2"`
+	J3 int `json:"j3"`
+	J4 int `json:"j4"`
+	J5 int `json:"j5"`
+	J6 int `json:"j6"`
+	Tr int `json:"tr"`
+}
+
+// Conditioning rates how close a position is to a singularity. Manipulability
+// is 0 and the condition number goes to infinity at a singularity.
+type Conditioning struct {
+	Manipulability  float64 `json:"manipulability"`
+	ConditionNumber float64 `json:"conditionnumber"`
+	Singular        bool    `json:"singular"`
+}
+
+// Conditioning rates the conditioning of a position.
+// @Summary Rate the conditioning of a position
+// @Tags cartesian
+// @Description Rates how close an absolute stepper position is to a singularity. Near a singularity, small cartesian moves need large joint moves, so cartesian moves towards one are rejected.
+// @Accept json
+// @Produce json
+// @Param position body ConditioningInput true "absolute stepper position"
+// @Success 200 {object} Conditioning
+// @Failure 400 {string} string
+// @Router /conditioning [post]
+func (app *App) Conditioning(w http.ResponseWriter, r *http.Request) {
+	// Read body
+	reqBody, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		w.WriteHeader(400)
+		_ = json.NewEncoder(w).Encode(err.Error())
+		return
+	}
+
+	// Unmarshal
+	var c ConditioningInput
+	err = json.Unmarshal(reqBody, &c)
+	if err != nil {
+		w.WriteHeader(400)
+		_ = json.NewEncoder(w).Encode(err.Error())
+		return
+	}
+
+	capabilities := app.Arm.Capabilities()
+	theta, err := capabilities.Theta(arm.Joints{c.J1, c.J2, c.J3, c.J4, c.J5, c.J6, c.Tr})
+	if err != nil {
+		w.WriteHeader(400)
+		_ = json.NewEncoder(w).Encode(err.Error())
+		return
+	}
+	conditionNumber := kinematics.ConditionNumber(theta, *capabilities.Kinematics)
+	_ = json.NewEncoder(w).Encode(Conditioning{
+		Manipulability:  kinematics.Manipulability(theta, *capabilities.Kinematics),
+		ConditionNumber: conditionNumber,
+		Singular:        conditionNumber > kinematics.SingularityThreshold,
+	})
+}
+
+// Pose returns the current pose of the selected tool.
+// @Summary Returns the current pose
+// @Tags cartesian
+// @Description Returns the current pose of the selected tool in the world, with rotation both as a quaternion and as a, b, c in degrees. Arms with an axis outside of the kinematic model away from 0, like a track, return 400.
+// @Produce json
+// @Success 200 {object} Pose
+// @Failure 400 {string} string
+// @Router /pose [get]
+func (app *App) Pose(w http.ResponseWriter, r *http.Request) {
+	capabilities := app.Arm.Capabilities()
+	current := app.Arm.CurrentPosition()
+	theta, err := capabilities.Theta(current)
+	if err != nil {
+		w.WriteHeader(400)
+		_ = json.NewEncoder(w).Encode(err.Error())
+		return
+	}
+	err = capabilities.CheckModeled(current)
+	if err != nil {
+		w.WriteHeader(400)
+		_ = json.NewEncoder(w).Encode(err.Error())
+		return
+	}
+	frames, err := app.kinematicFrames()
+	if err != nil {
+		w.WriteHeader(400)
+		_ = json.NewEncoder(w).Encode(err.Error())
+		return
+	}
+	pose := frames.ForwardKinematics(theta, *capabilities.Kinematics).Normalize()
+	a, b, c := abcOf(pose)
+
+	_ = json.NewEncoder(w).Encode(Pose{X: pose.X, Y: pose.Y, Z: pose.Z, Qw: pose.Qw, Qx: pose.Qx, Qy: pose.Qy, Qz: pose.Qz, A: a, B: b, C: c})
+}
+
+// PlanPathInput is the input to a path check. Poses are of the selected tool
+// in the world, and the path starts at the current pose. If segmentlength is 0,
+// samples are 5mm apart. Otherwise it must be at least 0.1mm.
+type PlanPathInput struct {
+	Poses         []Pose  `json:"poses"`
+	SegmentLength float64 `json:"segmentlength"`
+}
+
+// PathSegment is the check of one straight line of a path. MaxJointStep is the
+// largest change in radians of any joint between two samples, and Joint is the
+// name of that joint. Error is empty if the line can be followed.
+type PathSegment struct {
+	Samples      int     `json:"samples"`
+	MaxJointStep float64 `json:"maxjointstep"`
+	Joint        string  `json:"joint"`
+	Error        string  `json:"error"`
+}
+
+// PathPlan is the result of a path check. Valid is true if the whole path can
+// be followed, otherwise Error is the first problem along the path.
+type PathPlan struct {
+	Valid    bool          `json:"valid"`
+	Error    string        `json:"error"`
+	Segments []PathSegment `json:"segments"`
+}
+
+// PlanPath checks a path of straight lines without moving the arm.
+// @Summary Check a path of straight lines
+// @Tags cartesian
+// @Description Plans straight line moves of the selected tool from the current pose through each pose, solving inverse kinematics every few millimeters. Reports unreachable sections and joint jumps, with the largest joint step of each line, before anything moves. Soft limits and keep-out zones are not checked. Paths needing more than 10000 samples are refused, as are arms with an axis outside of the kinematic model away from 0.
+// @Accept json
+// @Produce json
+// @Param path body PlanPathInput true "poses to move through"
+// @Success 200 {object} PathPlan
+// @Failure 400 {string} string
+// @Router /plan_path [post]
+func (app *App) PlanPath(w http.ResponseWriter, r *http.Request) {
+	// Read body
+	reqBody, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		w.WriteHeader(400)
+		_ = json.NewEncoder(w).Encode(err.Error())
+		return
+	}
+
+	// Unmarshal
+	var p PlanPathInput
+	err = json.Unmarshal(reqBody, &p)
+	if err != nil {
+		w.WriteHeader(400)
+		_ = json.NewEncoder(w).Encode(err.Error())
+		return
+	}
+	capabilities := app.Arm.Capabilities()
+	current := app.Arm.CurrentPosition()
+	theta, err := capabilities.Theta(current)
+	if err != nil {
+		w.WriteHeader(400)
+		_ = json.NewEncoder(w).Encode(err.Error())
+		return
+	}
+	err = capabilities.CheckModeled(current)
+	if err != nil {
+		w.WriteHeader(400)
+		_ = json.NewEncoder(w).Encode(err.Error())
+		return
+	}
+	limits, err := capabilities.JointLimits()
+	if err != nil {
+		w.WriteHeader(400)
+		_ = json.NewEncoder(w).Encode(err.Error())
+		return
+	}
+	options := kinematics.DefaultPathOptions
+	options.Nearest.Limits = &limits
+	options.Frames, err = app.kinematicFrames()
+	if err != nil {
+		w.WriteHeader(400)
+		_ = json.NewEncoder(w).Encode(err.Error())
+		return
+	}
+	options.SegmentLength, err = segmentLength(p.SegmentLength, options.SegmentLength)
+	if err != nil {
+		w.WriteHeader(400)
+		_ = json.NewEncoder(w).Encode(err.Error())
+		return
+	}
+	waypoints := make([]kinematics.XyzWxyz, len(p.Poses))
+	for i, pose := range p.Poses {
+		waypoints[i] = poseOf(pose.X, pose.Y, pose.Z, pose.Qw, pose.Qx, pose.Qy, pose.Qz, pose.A, pose.B, pose.C)
+	}
+
+	// Plan the path and report on each segment
+	path, err := kinematics.PlanCartesianPath(waypoints, *capabilities.Kinematics, theta, options)
+	if err != nil && len(path.Segments) == 0 {
+		// The path was refused before planning, like one needing too many samples
+		w.WriteHeader(400)
+		_ = json.NewEncoder(w).Encode(err.Error())
+		return
+	}
+	plan := PathPlan{Valid: err == nil, Segments: []PathSegment{}}
+	if err != nil {
+		plan.Error = err.Error()
+	}
+	for _, segment := range path.Segments {
+		s := PathSegment{Samples: len(segment.Samples), MaxJointStep: segment.MaxJointStep, Joint: capabilities.Axes[segment.MaxStepJoint].Name}
+		if segment.Err != nil {
+			s.Error = segment.Err.Error()
+		}
+		plan.Segments = append(plan.Segments, s)
+	}
+
+	_ = json.NewEncoder(w).Encode(plan)
+}
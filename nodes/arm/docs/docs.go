@@ -23,9 +23,89 @@ var doc = `{
     "host": "{{.Host}}",
     "basePath": "{{.BasePath}}",
     "paths": {
+        "/add_keepout": {
+            "post": {
+                "description": "Adds an axis aligned box, in millimeters in the world, that the selected tool may not enter.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "safety"
+                ],
+                "summary": "Adds a keep-out zone",
+                "parameters": [
+                    {
+                        "description": "keep-out box. The id is ignored",
+                        "name": "keepout",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/main.Keepout"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/main.Keepout"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/add_tool": {
+            "post": {
+                "description": "Adds a tool center point offset from the flange of the arm, with rotation as a quaternion or as a, b, c in degrees. A tool with the same name is replaced.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "text/plain"
+                ],
+                "tags": [
+                    "frames"
+                ],
+                "summary": "Adds a tool",
+                "parameters": [
+                    {
+                        "description": "tool offset",
+                        "name": "tool",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/main.Tool"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
         "/calibrate": {
             "post": {
-                "description": "Calibrates the robot. Should be done occasionally to affirm the robot is where we think it should be.",
+                "description": "Calibrates the robot. Should be done occasionally to affirm the robot is where we think it should be. Returns 409 while the arm is jogging.",
                 "consumes": [
                     "application/json"
                 ],
@@ -38,12 +118,504 @@ var doc = `{
                 "summary": "Calibrate the arm",
                 "parameters": [
                     {
-                        "description": "joints to calibrate",
-                        "name": "joints",
+                        "description": "joints to calibrate",
+                        "name": "joints",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/main.CalibrateInput"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/conditioning": {
+            "post": {
+                "description": "Rates how close an absolute stepper position is to a singularity. Near a singularity, small cartesian moves need large joint moves, so cartesian moves towards one are rejected.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "cartesian"
+                ],
+                "summary": "Rate the conditioning of a position",
+                "parameters": [
+                    {
+                        "description": "absolute stepper position",
+                        "name": "position",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/main.ConditioningInput"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/main.Conditioning"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/delete_keepout": {
+            "post": {
+                "description": "Removes a keep-out zone by its id.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "text/plain"
+                ],
+                "tags": [
+                    "safety"
+                ],
+                "summary": "Deletes a keep-out zone",
+                "parameters": [
+                    {
+                        "description": "id of keep-out zone",
+                        "name": "keepout",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/main.DeleteKeepoutInput"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/delete_tool": {
+            "post": {
+                "description": "Removes a tool by its name. If the tool is selected, cartesian moves go back to using the flange.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "text/plain"
+                ],
+                "tags": [
+                    "frames"
+                ],
+                "summary": "Deletes a tool",
+                "parameters": [
+                    {
+                        "description": "name of tool",
+                        "name": "tool",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/main.ToolNameInput"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/directions": {
+            "get": {
+                "description": "Returns current direction of arm's motors.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "setup"
+                ],
+                "summary": "Returns direction of arm joints",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/main.JointDirections"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/frames": {
+            "get": {
+                "description": "Returns the current base frame and selected tool.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "frames"
+                ],
+                "summary": "Returns the current frames",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/main.ActiveFrames"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/jog": {
+            "get": {
+                "description": "WebSocket channel for jogging the arm. Send JogMessages and receive a JogStatus after each one. Jogging stops if no message is received for 500ms, or when the connection closes. Only one client can be connected at a time; a second client receives a JogStatus with an error and is disconnected. Moves are checked against soft limits and keep-out zones.",
+                "tags": [
+                    "jog"
+                ],
+                "summary": "Jog the arm",
+                "parameters": [
+                    {
+                        "description": "jog message",
+                        "name": "message",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/main.JogMessage"
+                        }
+                    }
+                ],
+                "responses": {
+                    "101": {
+                        "description": "Switching Protocols",
+                        "schema": {
+                            "$ref": "#/definitions/main.JogStatus"
+                        }
+                    }
+                }
+            }
+        },
+        "/keepouts": {
+            "get": {
+                "description": "Returns all keep-out zones of the selected tool.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "safety"
+                ],
+                "summary": "Returns keep-out zones",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/main.Keepout"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/move": {
+            "post": {
+                "description": "Moves the robot's stepper motors. Moves that break a soft limit, fold the arm into itself, or pass through a keep-out zone are rejected, and moves are refused with 409 while the arm is jogging.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "text/plain"
+                ],
+                "tags": [
+                    "low_level"
+                ],
+                "summary": "Move the arm's stepper motors",
+                "parameters": [
+                    {
+                        "description": "steppers coordinates",
+                        "name": "move",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/main.MoveStepperInput"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/movel": {
+            "post": {
+                "description": "Moves the selected tool in a straight line to a pose in the world by splitting the path into small joint moves. The whole path is checked against joint ranges, singularities, soft limits and keep-out zones before the arm moves. Paths that move towards a singularity are rejected, and moves are refused with 409 while the arm is jogging.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "text/plain"
+                ],
+                "tags": [
+                    "cartesian"
+                ],
+                "summary": "Move the tool in a straight line",
+                "parameters": [
+                    {
+                        "description": "target pose",
+                        "name": "move",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/main.MoveLInput"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/ping": {
+            "get": {
+                "produces": [
+                    "text/plain"
+                ],
+                "tags": [
+                    "dev"
+                ],
+                "summary": "A pingable endpoint",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/plan_path": {
+            "post": {
+                "description": "Plans straight line moves of the selected tool from the current pose through each pose, solving inverse kinematics every few millimeters. Reports unreachable sections and joint jumps, with the largest joint step of each line, before anything moves. Soft limits and keep-out zones are not checked. Paths needing more than 10000 samples are refused, as are arms with an axis outside of the kinematic model away from 0.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "cartesian"
+                ],
+                "summary": "Check a path of straight lines",
+                "parameters": [
+                    {
+                        "description": "poses to move through",
+                        "name": "path",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/main.PlanPathInput"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/main.PathPlan"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/pose": {
+            "get": {
+                "description": "Returns the current pose of the selected tool in the world, with rotation both as a quaternion and as a, b, c in degrees. Arms with an axis outside of the kinematic model away from 0, like a track, return 400.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "cartesian"
+                ],
+                "summary": "Returns the current pose",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/main.Pose"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/select_tool": {
+            "post": {
+                "description": "Selects the tool used for cartesian moves, jogging and keep-out zones. An empty name selects the flange.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "text/plain"
+                ],
+                "tags": [
+                    "frames"
+                ],
+                "summary": "Selects a tool",
+                "parameters": [
+                    {
+                        "description": "name of tool",
+                        "name": "tool",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/main.ToolNameInput"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/set_base_frame": {
+            "post": {
+                "description": "Sets the position of the base of the arm in the world, with rotation as a quaternion or as a, b, c in degrees. Cartesian moves and keep-out zones are in the world.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "text/plain"
+                ],
+                "tags": [
+                    "frames"
+                ],
+                "summary": "Sets the base frame",
+                "parameters": [
+                    {
+                        "description": "base frame",
+                        "name": "base",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/main.CalibrateInput"
+                            "$ref": "#/definitions/main.BaseFrame"
                         }
                     }
                 ],
@@ -63,21 +635,35 @@ var doc = `{
                 }
             }
         },
-        "/directions": {
-            "get": {
-                "description": "Returns current direction of arm's motors.",
-                "produces": [
+        "/set_directions": {
+            "post": {
+                "description": "Sets up the robots joints. This only has to be done once during the setup of the robot.",
+                "consumes": [
                     "application/json"
                 ],
+                "produces": [
+                    "text/plain"
+                ],
                 "tags": [
                     "setup"
                 ],
-                "summary": "Returns direction of arm joints",
+                "summary": "Sets direction of arm joints",
+                "parameters": [
+                    {
+                        "description": "direction of joints",
+                        "name": "directions",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/main.JointDirections"
+                        }
+                    }
+                ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/main.JointDirections"
+                            "type": "string"
                         }
                     },
                     "400": {
@@ -89,9 +675,9 @@ var doc = `{
                 }
             }
         },
-        "/move": {
+        "/set_soft_limits": {
             "post": {
-                "description": "Moves the robot's stepper motors.",
+                "description": "Replaces all soft limits with the given list. Joints are named after the axes of the arm, like j1 or tr, and min must not be larger than max. Joints without a soft limit only use the hardware range.",
                 "consumes": [
                     "application/json"
                 ],
@@ -99,17 +685,20 @@ var doc = `{
                     "text/plain"
                 ],
                 "tags": [
-                    "low_level"
+                    "safety"
                 ],
-                "summary": "Move the arm's stepper motors",
+                "summary": "Sets soft limits of arm joints",
                 "parameters": [
                     {
-                        "description": "steppers coordinates",
-                        "name": "move",
+                        "description": "soft limits of joints",
+                        "name": "limits",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/main.MoveStepperInput"
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/main.SoftLimit"
+                            }
                         }
                     }
                 ],
@@ -129,18 +718,28 @@ var doc = `{
                 }
             }
         },
-        "/ping": {
+        "/soft_limits": {
             "get": {
+                "description": "Returns all soft limits of the arm's joints.",
                 "produces": [
-                    "text/plain"
+                    "application/json"
                 ],
                 "tags": [
-                    "dev"
+                    "safety"
                 ],
-                "summary": "A pingable endpoint",
+                "summary": "Returns soft limits of arm joints",
                 "responses": {
                     "200": {
                         "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/main.SoftLimit"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
                         "schema": {
                             "type": "string"
                         }
@@ -148,35 +747,24 @@ var doc = `{
                 }
             }
         },
-        "/set_directions": {
-            "post": {
-                "description": "Sets up the robots joints. This only has to be done once during the setup of the robot.",
-                "consumes": [
-                    "application/json"
-                ],
+        "/tools": {
+            "get": {
+                "description": "Returns all tool center point offsets.",
                 "produces": [
-                    "text/plain"
+                    "application/json"
                 ],
                 "tags": [
-                    "setup"
-                ],
-                "summary": "Sets direction of arm joints",
-                "parameters": [
-                    {
-                        "description": "direction of joints",
-                        "name": "directions",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/main.JointDirections"
-                        }
-                    }
+                    "frames"
                 ],
+                "summary": "Returns tools",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "type": "string"
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/main.Tool"
+                            }
                         }
                     },
                     "400": {
@@ -190,6 +778,52 @@ var doc = `{
         }
     },
     "definitions": {
+        "main.ActiveFrames": {
+            "type": "object",
+            "properties": {
+                "base": {
+                    "$ref": "#/definitions/main.BaseFrame"
+                },
+                "tool": {
+                    "$ref": "#/definitions/main.Tool"
+                }
+            }
+        },
+        "main.BaseFrame": {
+            "type": "object",
+            "properties": {
+                "a": {
+                    "type": "number"
+                },
+                "b": {
+                    "type": "number"
+                },
+                "c": {
+                    "type": "number"
+                },
+                "qw": {
+                    "type": "number"
+                },
+                "qx": {
+                    "type": "number"
+                },
+                "qy": {
+                    "type": "number"
+                },
+                "qz": {
+                    "type": "number"
+                },
+                "x": {
+                    "type": "number"
+                },
+                "y": {
+                    "type": "number"
+                },
+                "z": {
+                    "type": "number"
+                }
+            }
+        },
         "main.CalibrateInput": {
             "type": "object",
             "properties": {
@@ -219,6 +853,91 @@ var doc = `{
                 }
             }
         },
+        "main.Conditioning": {
+            "type": "object",
+            "properties": {
+                "conditionnumber": {
+                    "type": "number"
+                },
+                "manipulability": {
+                    "type": "number"
+                },
+                "singular": {
+                    "type": "boolean"
+                }
+            }
+        },
+        "main.ConditioningInput": {
+            "type": "object",
+            "properties": {
+                "j1": {
+                    "type": "integer"
+                },
+                "j2": {
+                    "type": "integer"
+                },
+                "j3": {
+                    "type": "integer"
+                },
+                "j4": {
+                    "type": "integer"
+                },
+                "j5": {
+                    "type": "integer"
+                },
+                "j6": {
+                    "type": "integer"
+                },
+                "tr": {
+                    "type": "integer"
+                }
+            }
+        },
+        "main.DeleteKeepoutInput": {
+            "type": "object",
+            "properties": {
+                "id": {
+                    "type": "integer"
+                }
+            }
+        },
+        "main.JogMessage": {
+            "type": "object",
+            "properties": {
+                "angular": {
+                    "type": "array",
+                    "items": {
+                        "type": "number"
+                    }
+                },
+                "linear": {
+                    "type": "array",
+                    "items": {
+                        "type": "number"
+                    }
+                },
+                "type": {
+                    "type": "string"
+                },
+                "velocity": {
+                    "type": "array",
+                    "items": {
+                        "type": "number"
+                    }
+                }
+            }
+        },
+        "main.JogStatus": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "type": "string"
+                },
+                "jogging": {
+                    "type": "boolean"
+                }
+            }
+        },
         "main.JointDirections": {
             "type": "object",
             "properties": {
@@ -245,6 +964,88 @@ var doc = `{
                 }
             }
         },
+        "main.Keepout": {
+            "type": "object",
+            "properties": {
+                "id": {
+                    "type": "integer"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "xmax": {
+                    "type": "number"
+                },
+                "xmin": {
+                    "type": "number"
+                },
+                "ymax": {
+                    "type": "number"
+                },
+                "ymin": {
+                    "type": "number"
+                },
+                "zmax": {
+                    "type": "number"
+                },
+                "zmin": {
+                    "type": "number"
+                }
+            }
+        },
+        "main.MoveLInput": {
+            "type": "object",
+            "properties": {
+                "a": {
+                    "type": "number"
+                },
+                "accdur": {
+                    "type": "integer"
+                },
+                "accspd": {
+                    "type": "integer"
+                },
+                "b": {
+                    "type": "number"
+                },
+                "c": {
+                    "type": "number"
+                },
+                "dccdur": {
+                    "type": "integer"
+                },
+                "dccspd": {
+                    "type": "integer"
+                },
+                "qw": {
+                    "type": "number"
+                },
+                "qx": {
+                    "type": "number"
+                },
+                "qy": {
+                    "type": "number"
+                },
+                "qz": {
+                    "type": "number"
+                },
+                "segmentlength": {
+                    "type": "number"
+                },
+                "speed": {
+                    "type": "integer"
+                },
+                "x": {
+                    "type": "number"
+                },
+                "y": {
+                    "type": "number"
+                },
+                "z": {
+                    "type": "number"
+                }
+            }
+        },
         "main.MoveStepperInput": {
             "type": "object",
             "properties": {
@@ -285,6 +1086,149 @@ var doc = `{
                     "type": "integer"
                 }
             }
+        },
+        "main.PathPlan": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "type": "string"
+                },
+                "segments": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/main.PathSegment"
+                    }
+                },
+                "valid": {
+                    "type": "boolean"
+                }
+            }
+        },
+        "main.PathSegment": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "type": "string"
+                },
+                "joint": {
+                    "type": "string"
+                },
+                "maxjointstep": {
+                    "type": "number"
+                },
+                "samples": {
+                    "type": "integer"
+                }
+            }
+        },
+        "main.PlanPathInput": {
+            "type": "object",
+            "properties": {
+                "poses": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/main.Pose"
+                    }
+                },
+                "segmentlength": {
+                    "type": "number"
+                }
+            }
+        },
+        "main.Pose": {
+            "type": "object",
+            "properties": {
+                "a": {
+                    "type": "number"
+                },
+                "b": {
+                    "type": "number"
+                },
+                "c": {
+                    "type": "number"
+                },
+                "qw": {
+                    "type": "number"
+                },
+                "qx": {
+                    "type": "number"
+                },
+                "qy": {
+                    "type": "number"
+                },
+                "qz": {
+                    "type": "number"
+                },
+                "x": {
+                    "type": "number"
+                },
+                "y": {
+                    "type": "number"
+                },
+                "z": {
+                    "type": "number"
+                }
+            }
+        },
+        "main.SoftLimit": {
+            "type": "object",
+            "properties": {
+                "joint": {
+                    "type": "string"
+                },
+                "max": {
+                    "type": "integer"
+                },
+                "min": {
+                    "type": "integer"
+                }
+            }
+        },
+        "main.Tool": {
+            "type": "object",
+            "properties": {
+                "a": {
+                    "type": "number"
+                },
+                "b": {
+                    "type": "number"
+                },
+                "c": {
+                    "type": "number"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "qw": {
+                    "type": "number"
+                },
+                "qx": {
+                    "type": "number"
+                },
+                "qy": {
+                    "type": "number"
+                },
+                "qz": {
+                    "type": "number"
+                },
+                "x": {
+                    "type": "number"
+                },
+                "y": {
+                    "type": "number"
+                },
+                "z": {
+                    "type": "number"
+                }
+            }
+        },
+        "main.ToolNameInput": {
+            "type": "object",
+            "properties": {
+                "name": {
+                    "type": "string"
+                }
+            }
         }
     }
 }`
@@ -0,0 +1,381 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"github.com/koeng101/armos/utils/kinematics"
+	"io/ioutil"
+	"net/http"
+)
+
+/******************************************************************************
+
+                                armos arm frames
+
+1. /add_tool adds or replaces a tool center point offset.
+2. /tools returns all tools.
+3. /delete_tool removes a tool.
+4. /select_tool selects the tool used for cartesian moves.
+5. /set_base_frame sets the position of the base of the arm in the world.
+6. /frames returns the current base frame and tool.
+
+******************************************************************************/
+
+// Tool is the offset of a tool center point from the flange of the arm, in
+// millimeters, with rotation as a quaternion or as a, b, c in degrees.
+type Tool struct {
+	Name string  `json:"name" db:"name"`
+	X    float64 `json:"x" db:"x"`
+	Y    float64 `json:"y" db:"y"`
+	Z    float64 `json:"z" db:"z"`
+	Qw   float64 `json:"qw" db:"qw"`
+	Qx   float64 `json:"qx" db:"qx"`
+	Qy   float64 `json:"qy" db:"qy"`
+	Qz   float64 `json:"qz" db:"qz"`
+	A    float64 `json:"a" db:"-"`
+	B    float64 `json:"b" db:"-"`
+	C    float64 `json:"c" db:"-"`
+}
+
+// BaseFrame is the position of the base of the arm in the world, in
+// millimeters, with rotation as a quaternion or as a, b, c in degrees.
+type BaseFrame struct {
+	X  float64 `json:"x" db:"x"`
+	Y  float64 `json:"y" db:"y"`
+	Z  float64 `json:"z" db:"z"`
+	Qw float64 `json:"qw" db:"qw"`
+	Qx float64 `json:"qx" db:"qx"`
+	Qy float64 `json:"qy" db:"qy"`
+	Qz float64 `json:"qz" db:"qz"`
+	A  float64 `json:"a" db:"-"`
+	B  float64 `json:"b" db:"-"`
+	C  float64 `json:"c" db:"-"`
+}
+
+// ActiveFrames are the current base frame and tool. Tool is null if no tool is
+// selected, in which case cartesian moves use the flange.
+type ActiveFrames struct {
+	Base BaseFrame `json:"base"`
+	Tool *Tool     `json:"tool"`
+}
+
+// ToolNameInput is the input to commands that take a tool name.
+type ToolNameInput struct {
+	Name string `json:"name"`
+}
+
+// activeFrames loads the current base frame and tool from the database.
+func (app *App) activeFrames() (ActiveFrames, error) {
+	var frames ActiveFrames
+	err := app.DB.Get(&frames.Base, "SELECT x, y, z, qw, qx, qy, qz FROM frames WHERE id=1")
+	if err != nil {
+		return frames, err
+	}
+	var tool Tool
+	err = app.DB.Get(&tool, "SELECT tools.name, tools.x, tools.y, tools.z, tools.qw, tools.qx, tools.qy, tools.qz FROM frames JOIN tools ON frames.tool = tools.name WHERE frames.id=1")
+	switch err {
+	case nil:
+		tool.fillAbc()
+		frames.Tool = &tool
+	case sql.ErrNoRows:
+	default:
+		return frames, err
+	}
+	frames.Base.fillAbc()
+	return frames, nil
+}
+
+// pose returns the offset of a tool.
+func (t Tool) pose() kinematics.XyzWxyz {
+	return poseOf(t.X, t.Y, t.Z, t.Qw, t.Qx, t.Qy, t.Qz, t.A, t.B, t.C)
+}
+
+// fillAbc sets the rotation of a stored tool as a, b, c.
+func (t *Tool) fillAbc() {
+	t.A, t.B, t.C = abcOf(t.pose())
+}
+
+// pose returns the position of a base frame.
+func (b BaseFrame) pose() kinematics.XyzWxyz {
+	return poseOf(b.X, b.Y, b.Z, b.Qw, b.Qx, b.Qy, b.Qz, b.A, b.B, b.C)
+}
+
+// fillAbc sets the rotation of a stored base frame as a, b, c.
+func (b *BaseFrame) fillAbc() {
+	b.A, b.B, b.C = abcOf(b.pose())
+}
+
+// kinematicFrames returns the current base frame and tool for use with the
+// kinematics package.
+func (app *App) kinematicFrames() (kinematics.Frames, error) {
+	active, err := app.activeFrames()
+	if err != nil {
+		return kinematics.Frames{}, err
+	}
+	frames := kinematics.Frames{Base: active.Base.pose()}
+	if active.Tool != nil {
+		frames.Tool = active.Tool.pose()
+	}
+	return frames, nil
+}
+
+// updateJogFrames passes the current frames on to the Jogger.
+func (app *App) updateJogFrames() error {
+	frames, err := app.kinematicFrames()
+	if err != nil {
+		return err
+	}
+	app.Jogger.SetFrames(frames)
+	return nil
+}
+
+// AddTool adds or replaces a tool.
+// @Summary Adds a tool
+// @Tags frames
+// @Description Adds a tool center point offset from the flange of the arm, with rotation as a quaternion or as a, b, c in degrees. A tool with the same name is replaced.
+// @Accept json
+// @Produce plain
+// @Param tool body Tool true "tool offset"
+// @Success 200 {string} string
+// @Failure 400 {string} string
+// @Router /add_tool [post]
+func (app *App) AddTool(w http.ResponseWriter, r *http.Request) {
+	// Read body
+	reqBody, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		w.WriteHeader(400)
+		_ = json.NewEncoder(w).Encode(err.Error())
+		return
+	}
+
+	// Unmarshal
+	var t Tool
+	err = json.Unmarshal(reqBody, &t)
+	if err != nil {
+		w.WriteHeader(400)
+		_ = json.NewEncoder(w).Encode(err.Error())
+		return
+	}
+	if t.Name == "" {
+		w.WriteHeader(400)
+		_ = json.NewEncoder(w).Encode("Tool must have a name")
+		return
+	}
+
+	// Insert tool
+	pose := t.pose()
+	_, err = app.DB.Exec("INSERT OR REPLACE INTO tools(name, x, y, z, qw, qx, qy, qz) VALUES (?, ?, ?, ?, ?, ?, ?, ?)", t.Name, pose.X, pose.Y, pose.Z, pose.Qw, pose.Qx, pose.Qy, pose.Qz)
+	if err != nil {
+		w.WriteHeader(400)
+		_ = json.NewEncoder(w).Encode(err.Error())
+		return
+	}
+	err = app.updateJogFrames()
+	if err != nil {
+		w.WriteHeader(400)
+		_ = json.NewEncoder(w).Encode(err.Error())
+		return
+	}
+
+	_ = json.NewEncoder(w).Encode("success")
+}
+
+// Tools returns all tools.
+// @Summary Returns tools
+// @Tags frames
+// @Description Returns all tool center point offsets.
+// @Produce json
+// @Success 200 {array} Tool
+// @Failure 400 {string} string
+// @Router /tools [get]
+func (app *App) Tools(w http.ResponseWriter, r *http.Request) {
+	tools := []Tool{}
+	err := app.DB.Select(&tools, "SELECT name, x, y, z, qw, qx, qy, qz FROM tools ORDER BY name")
+	if err != nil {
+		w.WriteHeader(400)
+		_ = json.NewEncoder(w).Encode(err.Error())
+		return
+	}
+	for i := range tools {
+		tools[i].fillAbc()
+	}
+
+	_ = json.NewEncoder(w).Encode(tools)
+}
+
+// DeleteTool removes a tool.
+// @Summary Deletes a tool
+// @Tags frames
+// @Description Removes a tool by its name. If the tool is selected, cartesian moves go back to using the flange.
+// @Accept json
+// @Produce plain
+// @Param tool body ToolNameInput true "name of tool"
+// @Success 200 {string} string
+// @Failure 400 {string} string
+// @Router /delete_tool [post]
+func (app *App) DeleteTool(w http.ResponseWriter, r *http.Request) {
+	// Read body
+	reqBody, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		w.WriteHeader(400)
+		_ = json.NewEncoder(w).Encode(err.Error())
+		return
+	}
+
+	// Unmarshal
+	var t ToolNameInput
+	err = json.Unmarshal(reqBody, &t)
+	if err != nil {
+		w.WriteHeader(400)
+		_ = json.NewEncoder(w).Encode(err.Error())
+		return
+	}
+
+	// Delete tool
+	_, err = app.DB.Exec("UPDATE frames SET tool=NULL WHERE tool=?", t.Name)
+	if err != nil {
+		w.WriteHeader(400)
+		_ = json.NewEncoder(w).Encode(err.Error())
+		return
+	}
+	_, err = app.DB.Exec("DELETE FROM tools WHERE name=?", t.Name)
+	if err != nil {
+		w.WriteHeader(400)
+		_ = json.NewEncoder(w).Encode(err.Error())
+		return
+	}
+	err = app.updateJogFrames()
+	if err != nil {
+		w.WriteHeader(400)
+		_ = json.NewEncoder(w).Encode(err.Error())
+		return
+	}
+
+	_ = json.NewEncoder(w).Encode("success")
+}
+
+// SelectTool selects the tool used for cartesian moves.
+// @Summary Selects a tool
+// @Tags frames
+// @Description Selects the tool used for cartesian moves, jogging and keep-out zones. An empty name selects the flange.
+// @Accept json
+// @Produce plain
+// @Param tool body ToolNameInput true "name of tool"
+// @Success 200 {string} string
+// @Failure 400 {string} string
+// @Router /select_tool [post]
+func (app *App) SelectTool(w http.ResponseWriter, r *http.Request) {
+	// Read body
+	reqBody, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		w.WriteHeader(400)
+		_ = json.NewEncoder(w).Encode(err.Error())
+		return
+	}
+
+	// Unmarshal
+	var t ToolNameInput
+	err = json.Unmarshal(reqBody, &t)
+	if err != nil {
+		w.WriteHeader(400)
+		_ = json.NewEncoder(w).Encode(err.Error())
+		return
+	}
+
+	// Select tool
+	var tool sql.NullString
+	if t.Name != "" {
+		var count int
+		err = app.DB.Get(&count, "SELECT COUNT(*) FROM tools WHERE name=?", t.Name)
+		if err != nil {
+			w.WriteHeader(400)
+			_ = json.NewEncoder(w).Encode(err.Error())
+			return
+		}
+		if count == 0 {
+			w.WriteHeader(400)
+			_ = json.NewEncoder(w).Encode("Tool " + t.Name + " does not exist")
+			return
+		}
+		tool = sql.NullString{String: t.Name, Valid: true}
+	}
+	_, err = app.DB.Exec("UPDATE frames SET tool=? WHERE id=1", tool)
+	if err != nil {
+		w.WriteHeader(400)
+		_ = json.NewEncoder(w).Encode(err.Error())
+		return
+	}
+	err = app.updateJogFrames()
+	if err != nil {
+		w.WriteHeader(400)
+		_ = json.NewEncoder(w).Encode(err.Error())
+		return
+	}
+
+	_ = json.NewEncoder(w).Encode("success")
+}
+
+// SetBaseFrame sets the position of the base of the arm in the world.
+// @Summary Sets the base frame
+// @Tags frames
+// @Description Sets the position of the base of the arm in the world, with rotation as a quaternion or as a, b, c in degrees. Cartesian moves and keep-out zones are in the world.
+// @Accept json
+// @Produce plain
+// @Param base body BaseFrame true "base frame"
+// @Success 200 {string} string
+// @Failure 400 {string} string
+// @Router /set_base_frame [post]
+func (app *App) SetBaseFrame(w http.ResponseWriter, r *http.Request) {
+	// Read body
+	reqBody, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		w.WriteHeader(400)
+		_ = json.NewEncoder(w).Encode(err.Error())
+		return
+	}
+
+	// Unmarshal
+	var b BaseFrame
+	err = json.Unmarshal(reqBody, &b)
+	if err != nil {
+		w.WriteHeader(400)
+		_ = json.NewEncoder(w).Encode(err.Error())
+		return
+	}
+
+	// Update base frame
+	pose := b.pose()
+	_, err = app.DB.Exec("UPDATE frames SET x=?, y=?, z=?, qw=?, qx=?, qy=?, qz=? WHERE id=1", pose.X, pose.Y, pose.Z, pose.Qw, pose.Qx, pose.Qy, pose.Qz)
+	if err != nil {
+		w.WriteHeader(400)
+		_ = json.NewEncoder(w).Encode(err.Error())
+		return
+	}
+	err = app.updateJogFrames()
+	if err != nil {
+		w.WriteHeader(400)
+		_ = json.NewEncoder(w).Encode(err.Error())
+		return
+	}
+
+	_ = json.NewEncoder(w).Encode("success")
+}
+
+// Frames returns the current base frame and tool.
+// @Summary Returns the current frames
+// @Tags frames
+// @Description Returns the current base frame and selected tool.
+// @Produce json
+// @Success 200 {object} ActiveFrames
+// @Failure 400 {string} string
+// @Router /frames [get]
+func (app *App) Frames(w http.ResponseWriter, r *http.Request) {
+	frames, err := app.activeFrames()
+	if err != nil {
+		w.WriteHeader(400)
+		_ = json.NewEncoder(w).Encode(err.Error())
+		return
+	}
+
+	_ = json.NewEncoder(w).Encode(frames)
+}
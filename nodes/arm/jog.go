@@ -0,0 +1,91 @@
+package main
+
+import (
+	"errors"
+	"golang.org/x/net/websocket"
+)
+
+/******************************************************************************
+
+                                armos arm jog
+
+1. /jog is a WebSocket channel for jogging the arm in joint or cartesian space.
+
+******************************************************************************/
+
+// errJogging is returned by handlers that move the arm while it is jogging.
+var errJogging = errors.New("Cannot move the arm while it is jogging")
+
+// errJogSession is sent to a client that connects to /jog while another client
+// is connected.
+var errJogSession = errors.New("Another client is already jogging the arm")
+
+// JogMessage is a message sent over the jog WebSocket. Type is one of:
+//  joint: jog in joint space. Velocity is in steps per second for each axis.
+//  cartesian: jog in cartesian space. Linear is in millimeters per second and
+//   angular is in radians per second, of the selected tool in the world.
+//  heartbeat: keep jogging at the current velocity.
+//  stop: stop jogging.
+// Every message counts as a heartbeat. If no message is received for 500ms,
+// jogging stops.
+type JogMessage struct {
+	Type     string     `json:"type"`
+	Velocity []float64  `json:"velocity"`
+	Linear   [3]float64 `json:"linear"`
+	Angular  [3]float64 `json:"angular"`
+}
+
+// JogStatus is sent back after every JogMessage.
+type JogStatus struct {
+	Jogging bool   `json:"jogging"`
+	Error   string `json:"error"`
+}
+
+// Jog is a WebSocket channel for jogging the arm, for example with a gamepad
+// or a UI. Jogging stops when the connection closes. Only one client can jog
+// at a time.
+// @Summary Jog the arm
+// @Tags jog
+// @Description WebSocket channel for jogging the arm. Send JogMessages and receive a JogStatus after each one. Jogging stops if no message is received for 500ms, or when the connection closes. Only one client can be connected at a time; a second client receives a JogStatus with an error and is disconnected. Moves are checked against soft limits and keep-out zones.
+// @Param message body JogMessage true "jog message"
+// @Success 101 {object} JogStatus
+// @Router /jog [get]
+func (app *App) Jog(ws *websocket.Conn) {
+	select {
+	case app.jogSession <- struct{}{}:
+	default:
+		_ = websocket.JSON.Send(ws, JogStatus{Jogging: app.Jogger.Jogging(), Error: errJogSession.Error()})
+		return
+	}
+	defer func() { <-app.jogSession }()
+	defer app.Jogger.Stop()
+	for {
+		var m JogMessage
+		err := websocket.JSON.Receive(ws, &m)
+		if err != nil {
+			return
+		}
+
+		app.Jogger.Heartbeat()
+		switch m.Type {
+		case "joint":
+			err = app.Jogger.JogJoints(m.Velocity)
+		case "cartesian":
+			err = app.Jogger.JogCartesian(m.Linear, m.Angular)
+		case "stop":
+			app.Jogger.Stop()
+		}
+
+		status := JogStatus{Jogging: app.Jogger.Jogging()}
+		if err == nil {
+			err = app.Jogger.Err()
+		}
+		if err != nil {
+			status.Error = err.Error()
+		}
+		err = websocket.JSON.Send(ws, status)
+		if err != nil {
+			return
+		}
+	}
+}
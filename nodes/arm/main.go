@@ -1,8 +1,10 @@
 /* Package main is a Golang REST API for interacting with robotic arms.
 
 The armos server sends this API requests for the robot to move or to go to an
-`a,b,c,x,y,z` position. This only supports the AR3 robotic arm right now, but
-we hope to add more in the future.
+`a,b,c,x,y,z` position. Rotations can be given either as a quaternion or as
+a,b,c angles in degrees. Any arm implementing the arm.Arm interface can be
+hosted, though only the AR3 robotic arm is supported right now. We hope to add
+more in the future.
 */
 package main
 
@@ -13,32 +15,44 @@ import (
 	"encoding/json"
 	"fmt"
 	"github.com/koeng101/armos/devices/ar3"
+	"github.com/koeng101/armos/devices/arm"
 	_ "github.com/koeng101/armos/nodes/arm/docs" // API docs generated by swaggo/swag
 	"github.com/swaggo/swag"
+	"golang.org/x/net/websocket"
 	"io/ioutil"
 	"log"
 	"net/http"
+	"sync"
 )
 
 // App is a struct containing all information about the currently deployed
 // application, such as the router and database.
 type App struct {
-	Router *http.ServeMux
-	DB     *sqlx.DB
-	Arm    ar3.AR3
+	Router  *http.ServeMux
+	DB      *sqlx.DB
+	Arm     arm.Arm
+	Jogger  *arm.Jogger
+	// ArmLock is held from checking a move until it is sent, so that moves
+	// from jogging and from REST handlers do not interleave.
+	ArmLock *sync.Mutex
+
+	// jogSession holds a value while a client is connected to /jog.
+	jogSession chan struct{}
 }
 
 // initalizeApp initializes an App for all endpoints to use.
-func initializeApp(db *sqlx.DB, ar3 ar3.AR3) App {
+func initializeApp(db *sqlx.DB, a arm.Arm) App {
 	var app App
 	app.Router = http.NewServeMux()
 	app.DB = db
-	app.Arm = ar3
+	app.Arm = a
+	app.ArmLock = &sync.Mutex{}
+	app.jogSession = make(chan struct{}, 1)
 
 	// Set arm directions from database
 	var j JointDirections
 	_ = app.DB.Get(&j, "SELECT j1, j2, j3, j4, j5, j6, tr FROM directions WHERE id=1")
-	app.Arm.SetDirections(j.J1, j.J2, j.J3, j.J4, j.J5, j.J6, j.Tr)
+	_ = app.Arm.SetDirections([]bool{j.J1, j.J2, j.J3, j.J4, j.J5, j.J6, j.Tr})
 
 	// Basic routes
 	app.Router.HandleFunc("/api/ping", app.Ping)
@@ -49,10 +63,39 @@ func initializeApp(db *sqlx.DB, ar3 ar3.AR3) App {
 	app.Router.HandleFunc("/api/set_directions", app.SetDirections)
 	app.Router.HandleFunc("/api/directions", app.Directions)
 
+	// Safety routes
+	app.Router.HandleFunc("/api/set_soft_limits", app.SetSoftLimits)
+	app.Router.HandleFunc("/api/soft_limits", app.SoftLimits)
+	app.Router.HandleFunc("/api/add_keepout", app.AddKeepout)
+	app.Router.HandleFunc("/api/keepouts", app.Keepouts)
+	app.Router.HandleFunc("/api/delete_keepout", app.DeleteKeepout)
+
+	// Frame routes
+	app.Router.HandleFunc("/api/add_tool", app.AddTool)
+	app.Router.HandleFunc("/api/tools", app.Tools)
+	app.Router.HandleFunc("/api/delete_tool", app.DeleteTool)
+	app.Router.HandleFunc("/api/select_tool", app.SelectTool)
+	app.Router.HandleFunc("/api/set_base_frame", app.SetBaseFrame)
+	app.Router.HandleFunc("/api/frames", app.Frames)
+
 	// Low level routes
 	app.Router.HandleFunc("/api/calibrate", app.Calibrate)
 	app.Router.HandleFunc("/api/movesteppers", app.MoveSteppers)
 
+	// Cartesian routes
+	app.Router.HandleFunc("/api/movel", app.MoveL)
+	app.Router.HandleFunc("/api/conditioning", app.Conditioning)
+	app.Router.HandleFunc("/api/pose", app.Pose)
+	app.Router.HandleFunc("/api/plan_path", app.PlanPath)
+
+	// Jog routes
+	jogOptions := arm.DefaultJogOptions
+	jogOptions.Check = app.CheckMove
+	jogOptions.Lock = app.ArmLock
+	app.Jogger = arm.NewJogger(app.Arm, arm.DefaultMotionParameters, jogOptions)
+	_ = app.updateJogFrames()
+	app.Router.Handle("/api/jog", websocket.Handler(app.Jog))
+
 	return app
 }
 
@@ -179,7 +222,48 @@ CREATE TABLE IF NOT EXISTS history(
 	commandjson TEXT NOT NULL
 );
 
+CREATE TABLE IF NOT EXISTS soft_limits(
+	joint TEXT PRIMARY KEY,
+	min INTEGER NOT NULL,
+	max INTEGER NOT NULL CHECK (max >= min)
+);
+
+CREATE TABLE IF NOT EXISTS keepouts(
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	name TEXT NOT NULL,
+	xmin REAL NOT NULL,
+	ymin REAL NOT NULL,
+	zmin REAL NOT NULL,
+	xmax REAL NOT NULL,
+	ymax REAL NOT NULL,
+	zmax REAL NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS tools(
+	name TEXT PRIMARY KEY,
+	x REAL NOT NULL,
+	y REAL NOT NULL,
+	z REAL NOT NULL,
+	qw REAL NOT NULL,
+	qx REAL NOT NULL,
+	qy REAL NOT NULL,
+	qz REAL NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS frames(
+	id INTEGER PRIMARY KEY,
+	x REAL NOT NULL DEFAULT 0,
+	y REAL NOT NULL DEFAULT 0,
+	z REAL NOT NULL DEFAULT 0,
+	qw REAL NOT NULL DEFAULT 1,
+	qx REAL NOT NULL DEFAULT 0,
+	qy REAL NOT NULL DEFAULT 0,
+	qz REAL NOT NULL DEFAULT 0,
+	tool TEXT REFERENCES tools(name)
+);
+
 INSERT OR IGNORE INTO directions(id) VALUES (1);
+INSERT OR IGNORE INTO frames(id) VALUES (1);
 `
 
 // JointDirections saves the directions of stepper motors in the database.
@@ -231,7 +315,12 @@ func (app *App) SetDirections(w http.ResponseWriter, r *http.Request) {
         }
 
 	// Set the direction of the arm itself.
-	app.Arm.SetDirections(j.J1, j.J2, j.J3, j.J4, j.J5, j.J6, j.Tr)
+	err = app.Arm.SetDirections([]bool{j.J1, j.J2, j.J3, j.J4, j.J5, j.J6, j.Tr})
+	if err != nil {
+		w.WriteHeader(400)
+		_ = json.NewEncoder(w).Encode(err.Error())
+		return
+	}
 
 	_ = json.NewEncoder(w).Encode("success")
 }
@@ -246,14 +335,15 @@ func (app *App) SetDirections(w http.ResponseWriter, r *http.Request) {
 // @Router /directions [get]
 func (app *App) Directions(w http.ResponseWriter, r *http.Request) {
 	var j JointDirections
-	j1, j2, j3, j4, j5, j6, tr := app.Arm.GetDirections()
-	j.J1 = j1
-	j.J2 = j2
-	j.J3 = j3
-	j.J4 = j4
-	j.J5 = j5
-	j.J6 = j6
-	j.Tr = tr
+	directions := make([]bool, 7)
+	copy(directions, app.Arm.Directions())
+	j.J1 = directions[0]
+	j.J2 = directions[1]
+	j.J3 = directions[2]
+	j.J4 = directions[3]
+	j.J5 = directions[4]
+	j.J6 = directions[5]
+	j.Tr = directions[6]
 
 	_ = json.NewEncoder(w).Encode(j)
 }
@@ -283,12 +373,13 @@ type CalibrateInput struct {
 // Calibrate calibrates the robot to its limit switches.
 // @Summary Calibrate the arm
 // @Tags low_level
-// @Description Calibrates the robot. Should be done occasionally to affirm the robot is where we think it should be.
+// @Description Calibrates the robot. Should be done occasionally to affirm the robot is where we think it should be. Returns 409 while the arm is jogging.
 // @Accept json
 // @Produce plain
 // @Param joints body CalibrateInput true "joints to calibrate"
 // @Success 200 {string} string
 // @Failure 400 {string} string
+// @Failure 409 {string} string
 // @Router /calibrate [post]
 func (app *App) Calibrate(w http.ResponseWriter, r *http.Request) {
 	// Read body
@@ -308,8 +399,17 @@ func (app *App) Calibrate(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Hold the arm until the move is sent, and refuse to move while jogging
+	app.ArmLock.Lock()
+	defer app.ArmLock.Unlock()
+	if app.Jogger.Jogging() {
+		w.WriteHeader(409)
+		_ = json.NewEncoder(w).Encode(errJogging.Error())
+		return
+	}
+
 	// Calibrate those joints
-	err = app.Arm.Calibrate(c.Speed, c.J1, c.J2, c.J3, c.J4, c.J5, c.J6, c.Tr)
+	err = app.Arm.Calibrate(c.Speed, []bool{c.J1, c.J2, c.J3, c.J4, c.J5, c.J6, c.Tr})
 	if err != nil {
 		w.WriteHeader(400)
 		_ = json.NewEncoder(w).Encode(err.Error())
@@ -335,15 +435,26 @@ type MoveStepperInput struct {
 	Tr     int `json:"tr"`
 }
 
+// motion returns the motion parameters of a MoveStepperInput.
+func (m MoveStepperInput) motion() arm.MotionParameters {
+	return arm.MotionParameters{Speed: m.Speed, AccelerationDuration: m.Accdur, AccelerationSpeed: m.Accspd, DecelerationDuration: m.Dccdur, DecelerationSpeed: m.Dccspd}
+}
+
+// joints returns the relative move of a MoveStepperInput.
+func (m MoveStepperInput) joints() arm.Joints {
+	return arm.Joints{m.J1, m.J2, m.J3, m.J4, m.J5, m.J6, m.Tr}
+}
+
 // MoveSteppers moves the robots stepper motors a certain number of steps.
 // @Summary Move the arm's stepper motors
 // @Tags low_level
-// @Description Moves the robot's stepper motors.
+// @Description Moves the robot's stepper motors. Moves that break a soft limit, fold the arm into itself, or pass through a keep-out zone are rejected, and moves are refused with 409 while the arm is jogging.
 // @Accept json
 // @Produce plain
 // @Param move body MoveStepperInput true "steppers coordinates"
 // @Success 200 {string} string
 // @Failure 400 {string} string
+// @Failure 409 {string} string
 // @Router /move [post]
 func (app *App) MoveSteppers(w http.ResponseWriter, r *http.Request) {
 	// Read body
@@ -363,8 +474,25 @@ func (app *App) MoveSteppers(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Hold the arm until the move is sent, and refuse to move while jogging
+	app.ArmLock.Lock()
+	defer app.ArmLock.Unlock()
+	if app.Jogger.Jogging() {
+		w.WriteHeader(409)
+		_ = json.NewEncoder(w).Encode(errJogging.Error())
+		return
+	}
+
+	// Check the move against soft limits and keep-out zones
+	err = app.CheckMove(app.Arm.CurrentPosition(), m.joints())
+	if err != nil {
+		w.WriteHeader(400)
+		_ = json.NewEncoder(w).Encode(err.Error())
+		return
+	}
+
 	// MoveSteppers
-	err = app.Arm.MoveSteppers(m.Speed, m.Accdur, m.Accspd, m.Dccdur, m.Dccspd, m.J1, m.J2, m.J3, m.J4, m.J5, m.J6, m.Tr)
+	err = app.Arm.MoveSteppers(m.motion(), m.joints())
 	if err != nil {
 		w.WriteHeader(400)
 		_ = json.NewEncoder(w).Encode(err.Error())
@@ -1,14 +1,22 @@
 package main
 
 import (
+	"encoding/json"
+	"errors"
+	"fmt"
 	"github.com/koeng101/armos/devices/ar3"
+	"github.com/koeng101/armos/devices/arm"
+	"github.com/koeng101/armos/utils/kinematics"
 	"log"
+	"math"
 	"github.com/jmoiron/sqlx"
 	_ "modernc.org/sqlite"
 	"net/http/httptest"
 	"os"
 	"strings"
+	"golang.org/x/net/websocket"
 	"testing"
+	"time"
 )
 
 var app App
@@ -42,3 +50,429 @@ func TestPing(t *testing.T) {
 		t.Errorf("Unexpected response. Expected: " + r + "\nGot: " + resp.Body.String())
 	}
 }
+
+func TestSoftLimits(t *testing.T) {
+	req := httptest.NewRequest("POST", "/api/set_soft_limits", strings.NewReader(`[{"joint":"j1","min":0,"max":100}]`))
+	resp := httptest.NewRecorder()
+	app.Router.ServeHTTP(resp, req)
+	if resp.Code != 200 {
+		t.Errorf("Failed to set soft limits. Got: " + resp.Body.String())
+	}
+
+	// A move past the soft limit should be rejected
+	req = httptest.NewRequest("POST", "/api/movesteppers", strings.NewReader(`{"speed":25,"accdur":15,"accspd":10,"dccdur":20,"dccspd":5,"j1":200}`))
+	resp = httptest.NewRecorder()
+	app.Router.ServeHTTP(resp, req)
+	if resp.Code != 400 {
+		t.Errorf("Move past soft limit should have failed")
+	}
+
+	// Joints must be axes of the arm, and min must not be larger than max
+	for _, invalid := range []string{`[{"joint":"j7","min":0,"max":100}]`, `[{"joint":"j2","min":100,"max":0}]`} {
+		req = httptest.NewRequest("POST", "/api/set_soft_limits", strings.NewReader(invalid))
+		resp = httptest.NewRecorder()
+		app.Router.ServeHTTP(resp, req)
+		if resp.Code != 400 {
+			t.Errorf("Expected %s to be rejected", invalid)
+		}
+	}
+
+	// Axis names are case insensitive, and the track can be limited too
+	req = httptest.NewRequest("POST", "/api/set_soft_limits", strings.NewReader(`[{"joint":"J1","min":0,"max":100000},{"joint":"tr","min":0,"max":0}]`))
+	resp = httptest.NewRecorder()
+	app.Router.ServeHTTP(resp, req)
+	if resp.Code != 200 {
+		t.Errorf("Failed to set soft limits. Got: " + resp.Body.String())
+	}
+	req = httptest.NewRequest("POST", "/api/movesteppers", strings.NewReader(`{"speed":25,"accdur":15,"accspd":10,"dccdur":20,"dccspd":5,"tr":10}`))
+	resp = httptest.NewRecorder()
+	app.Router.ServeHTTP(resp, req)
+	if resp.Code != 400 || !strings.Contains(resp.Body.String(), "tr out of soft limit") {
+		t.Errorf("Move of the track past its soft limit should have failed. Got: " + resp.Body.String())
+	}
+
+	// Clear soft limits
+	req = httptest.NewRequest("POST", "/api/set_soft_limits", strings.NewReader(`[]`))
+	resp = httptest.NewRecorder()
+	app.Router.ServeHTTP(resp, req)
+	req = httptest.NewRequest("GET", "/api/soft_limits", nil)
+	resp = httptest.NewRecorder()
+	app.Router.ServeHTTP(resp, req)
+	if strings.TrimSpace(resp.Body.String()) != "[]" {
+		t.Errorf("Soft limits should be empty. Got: " + resp.Body.String())
+	}
+}
+
+func TestKeepouts(t *testing.T) {
+	// Add a keep-out zone around the entire arm
+	req := httptest.NewRequest("POST", "/api/add_keepout", strings.NewReader(`{"name":"everything","xmin":-10000,"ymin":-10000,"zmin":-10000,"xmax":10000,"ymax":10000,"zmax":10000}`))
+	resp := httptest.NewRecorder()
+	app.Router.ServeHTTP(resp, req)
+	if resp.Code != 200 {
+		t.Errorf("Failed to add keep-out. Got: " + resp.Body.String())
+	}
+	var k Keepout
+	_ = json.Unmarshal(resp.Body.Bytes(), &k)
+
+	// Any move should be rejected
+	req = httptest.NewRequest("POST", "/api/movesteppers", strings.NewReader(`{"speed":25,"accdur":15,"accspd":10,"dccdur":20,"dccspd":5,"j1":10}`))
+	resp = httptest.NewRecorder()
+	app.Router.ServeHTTP(resp, req)
+	if resp.Code != 400 {
+		t.Errorf("Move into keep-out should have failed")
+	}
+
+	// Moving the track would move the arm out from under the kinematic model
+	if err := app.CheckMove(app.Arm.CurrentPosition(), arm.Joints{0, 0, 0, 0, 0, 0, 10}); !errors.Is(err, arm.ErrUnmodeledAxis) {
+		t.Errorf("Expected moving the track to be refused with keep-outs set. Got %v", err)
+	}
+
+	// After deleting the keep-out, the move should succeed
+	req = httptest.NewRequest("POST", "/api/delete_keepout", strings.NewReader(fmt.Sprintf(`{"id":%d}`, k.ID)))
+	resp = httptest.NewRecorder()
+	app.Router.ServeHTTP(resp, req)
+	req = httptest.NewRequest("POST", "/api/movesteppers", strings.NewReader(`{"speed":25,"accdur":15,"accspd":10,"dccdur":20,"dccspd":5,"j1":10}`))
+	resp = httptest.NewRecorder()
+	app.Router.ServeHTTP(resp, req)
+	if resp.Code != 200 {
+		t.Errorf("Move should have succeeded. Got: " + resp.Body.String())
+	}
+}
+
+func TestKeepouts_ThinBox(t *testing.T) {
+	// Put a thin box on the path of a long J1 move, between the first two of
+	// 20 evenly spaced points along it
+	capabilities := app.Arm.Capabilities()
+	frames, _ := app.kinematicFrames()
+	from := app.Arm.CurrentPosition()
+	delta := make(arm.Joints, len(from))
+	delta[0] = 4000
+	sample := make(arm.Joints, len(from))
+	copy(sample, from)
+	sample[0] = from[0] + int(0.5/19*float64(delta[0]))
+	theta, _ := capabilities.Theta(sample)
+	center := frames.ForwardKinematics(theta, *capabilities.Kinematics)
+	keepout := fmt.Sprintf(`{"name":"thin","xmin":%f,"ymin":%f,"zmin":%f,"xmax":%f,"ymax":%f,"zmax":%f}`, center.X-3, center.Y-3, center.Z-3, center.X+3, center.Y+3, center.Z+3)
+	req := httptest.NewRequest("POST", "/api/add_keepout", strings.NewReader(keepout))
+	resp := httptest.NewRecorder()
+	app.Router.ServeHTTP(resp, req)
+	if resp.Code != 200 {
+		t.Fatalf("Failed to add keep-out. Got: " + resp.Body.String())
+	}
+	var k Keepout
+	_ = json.Unmarshal(resp.Body.Bytes(), &k)
+	defer func() {
+		req := httptest.NewRequest("POST", "/api/delete_keepout", strings.NewReader(fmt.Sprintf(`{"id":%d}`, k.ID)))
+		app.Router.ServeHTTP(httptest.NewRecorder(), req)
+	}()
+
+	if err := app.CheckMove(from, delta); err == nil || !strings.Contains(err.Error(), "thin") {
+		t.Errorf("Expected the move to enter the thin keep-out. Got %v", err)
+	}
+}
+
+func TestSelfCollision(t *testing.T) {
+	// Reaching the wrist down into the base should be rejected
+	capabilities := app.Arm.Capabilities()
+	current := app.Arm.CurrentPosition()
+	target, _ := capabilities.Steps(kinematics.StepperTheta{J1: -0.97, J2: -0.11, J3: 2.44, J4: 0.12, J5: 1.13, J6: -2}, current)
+	move := fmt.Sprintf(`{"speed":25,"accdur":15,"accspd":10,"dccdur":20,"dccspd":5,"j1":%d,"j2":%d,"j3":%d,"j4":%d,"j5":%d,"j6":%d}`, target[0]-current[0], target[1]-current[1], target[2]-current[2], target[3]-current[3], target[4]-current[4], target[5]-current[5])
+	req := httptest.NewRequest("POST", "/api/movesteppers", strings.NewReader(move))
+	resp := httptest.NewRecorder()
+	app.Router.ServeHTTP(resp, req)
+	if resp.Code != 400 || !strings.Contains(resp.Body.String(), "collides") {
+		t.Errorf("Move into self-collision should have failed. Got: " + resp.Body.String())
+	}
+	if fmt.Sprint(app.Arm.CurrentPosition()) != fmt.Sprint(current) {
+		t.Errorf("Arm should not have moved")
+	}
+}
+
+func TestMoveL(t *testing.T) {
+	// Move the arm away from its limit switches
+	target := arm.Joints{7000, 3650, 3925, 7000, 3000, 3000, 0}
+	current := app.Arm.CurrentPosition()
+	move := fmt.Sprintf(`{"speed":25,"accdur":15,"accspd":10,"dccdur":20,"dccspd":5,"j1":%d,"j2":%d,"j3":%d,"j4":%d,"j5":%d,"j6":%d}`, target[0]-current[0], target[1]-current[1], target[2]-current[2], target[3]-current[3], target[4]-current[4], target[5]-current[5])
+	req := httptest.NewRequest("POST", "/api/movesteppers", strings.NewReader(move))
+	resp := httptest.NewRecorder()
+	app.Router.ServeHTTP(resp, req)
+	if resp.Code != 200 {
+		t.Errorf("Failed to move arm. Got: " + resp.Body.String())
+	}
+
+	// Move the end effector 10mm along z
+	theta, _ := app.Arm.Capabilities().Theta(app.Arm.CurrentPosition())
+	pose := kinematics.ForwardKinematics(theta, kinematics.AR3DhParameters)
+	movel := fmt.Sprintf(`{"speed":25,"accdur":15,"accspd":10,"dccdur":20,"dccspd":5,"x":%f,"y":%f,"z":%f,"qw":%f,"qx":%f,"qy":%f,"qz":%f}`, pose.X, pose.Y, pose.Z+10, pose.Qw, pose.Qx, pose.Qy, pose.Qz)
+	req = httptest.NewRequest("POST", "/api/movel", strings.NewReader(movel))
+	resp = httptest.NewRecorder()
+	app.Router.ServeHTTP(resp, req)
+	if resp.Code != 200 {
+		t.Errorf("Failed to movel arm. Got: " + resp.Body.String())
+	}
+
+	// Tiny and negative segment lengths should be refused
+	for _, length := range []float64{1e-9, -5} {
+		movel = fmt.Sprintf(`{"speed":25,"accdur":15,"accspd":10,"dccdur":20,"dccspd":5,"x":%f,"y":%f,"z":%f,"qw":%f,"qx":%f,"qy":%f,"qz":%f,"segmentlength":%g}`, pose.X, pose.Y, pose.Z, pose.Qw, pose.Qx, pose.Qy, pose.Qz, length)
+		req = httptest.NewRequest("POST", "/api/movel", strings.NewReader(movel))
+		resp = httptest.NewRecorder()
+		app.Router.ServeHTTP(resp, req)
+		if resp.Code != 400 {
+			t.Errorf("Expected segment length %g to be refused. Got %d: %s", length, resp.Code, resp.Body.String())
+		}
+	}
+}
+
+func TestFrames(t *testing.T) {
+	// Add and select a tool 100mm out from the flange
+	req := httptest.NewRequest("POST", "/api/add_tool", strings.NewReader(`{"name":"gripper","z":100,"qw":1}`))
+	resp := httptest.NewRecorder()
+	app.Router.ServeHTTP(resp, req)
+	if resp.Code != 200 {
+		t.Errorf("Failed to add tool. Got: " + resp.Body.String())
+	}
+	req = httptest.NewRequest("POST", "/api/select_tool", strings.NewReader(`{"name":"gripper"}`))
+	resp = httptest.NewRecorder()
+	app.Router.ServeHTTP(resp, req)
+	if resp.Code != 200 {
+		t.Errorf("Failed to select tool. Got: " + resp.Body.String())
+	}
+	req = httptest.NewRequest("POST", "/api/select_tool", strings.NewReader(`{"name":"missing"}`))
+	resp = httptest.NewRecorder()
+	app.Router.ServeHTTP(resp, req)
+	if resp.Code != 400 {
+		t.Errorf("Selecting a missing tool should fail")
+	}
+
+	req = httptest.NewRequest("GET", "/api/frames", nil)
+	resp = httptest.NewRecorder()
+	app.Router.ServeHTTP(resp, req)
+	var frames ActiveFrames
+	_ = json.Unmarshal(resp.Body.Bytes(), &frames)
+	if frames.Tool == nil || frames.Tool.Name != "gripper" || frames.Base.Qw != 1 {
+		t.Errorf("Unexpected frames. Got: " + resp.Body.String())
+	}
+
+	// Move the tool 10mm along z
+	theta, _ := app.Arm.Capabilities().Theta(app.Arm.CurrentPosition())
+	toolFrames := kinematics.Frames{Tool: kinematics.XyzWxyz{Z: 100, Qw: 1}}
+	pose := toolFrames.ForwardKinematics(theta, kinematics.AR3DhParameters)
+	movel := fmt.Sprintf(`{"speed":25,"accdur":15,"accspd":10,"dccdur":20,"dccspd":5,"x":%f,"y":%f,"z":%f,"qw":%f,"qx":%f,"qy":%f,"qz":%f}`, pose.X, pose.Y, pose.Z+10, pose.Qw, pose.Qx, pose.Qy, pose.Qz)
+	req = httptest.NewRequest("POST", "/api/movel", strings.NewReader(movel))
+	resp = httptest.NewRecorder()
+	app.Router.ServeHTTP(resp, req)
+	if resp.Code != 200 {
+		t.Errorf("Failed to movel tool. Got: " + resp.Body.String())
+	}
+	theta, _ = app.Arm.Capabilities().Theta(app.Arm.CurrentPosition())
+	moved := toolFrames.ForwardKinematics(theta, kinematics.AR3DhParameters)
+	if math.Abs(moved.Z-pose.Z-10) > 1 || math.Abs(moved.X-pose.X) > 1 || math.Abs(moved.Y-pose.Y) > 1 {
+		t.Errorf("Tool should have moved 10mm along z. Got: %+v from %+v", moved, pose)
+	}
+
+	// Deleting the tool goes back to the flange
+	req = httptest.NewRequest("POST", "/api/delete_tool", strings.NewReader(`{"name":"gripper"}`))
+	resp = httptest.NewRecorder()
+	app.Router.ServeHTTP(resp, req)
+	req = httptest.NewRequest("GET", "/api/frames", nil)
+	resp = httptest.NewRecorder()
+	app.Router.ServeHTTP(resp, req)
+	frames = ActiveFrames{}
+	_ = json.Unmarshal(resp.Body.Bytes(), &frames)
+	if frames.Tool != nil {
+		t.Errorf("Tool should have been deleted. Got: " + resp.Body.String())
+	}
+}
+
+func TestPose(t *testing.T) {
+	req := httptest.NewRequest("GET", "/api/pose", nil)
+	resp := httptest.NewRecorder()
+	app.Router.ServeHTTP(resp, req)
+	var pose Pose
+	_ = json.Unmarshal(resp.Body.Bytes(), &pose)
+	expected := kinematics.XyzAbc{X: pose.X, Y: pose.Y, Z: pose.Z, A: pose.A * math.Pi / 180, B: pose.B * math.Pi / 180, C: pose.C * math.Pi / 180}.XyzWxyz()
+	if expected.Angle(kinematics.XyzWxyz{Qw: pose.Qw, Qx: pose.Qx, Qy: pose.Qy, Qz: pose.Qz}) > 1e-6 {
+		t.Errorf("a, b, c should match the quaternion. Got: " + resp.Body.String())
+	}
+
+	// Move 10mm along z using a, b, c
+	movel := fmt.Sprintf(`{"speed":25,"accdur":15,"accspd":10,"dccdur":20,"dccspd":5,"x":%f,"y":%f,"z":%f,"a":%f,"b":%f,"c":%f}`, pose.X, pose.Y, pose.Z+10, pose.A, pose.B, pose.C)
+	req = httptest.NewRequest("POST", "/api/movel", strings.NewReader(movel))
+	resp = httptest.NewRecorder()
+	app.Router.ServeHTTP(resp, req)
+	if resp.Code != 200 {
+		t.Errorf("Failed to movel arm with a, b, c. Got: " + resp.Body.String())
+	}
+
+	// Tools can be given as a, b, c
+	req = httptest.NewRequest("POST", "/api/add_tool", strings.NewReader(`{"name":"angled","z":50,"b":90}`))
+	resp = httptest.NewRecorder()
+	app.Router.ServeHTTP(resp, req)
+	req = httptest.NewRequest("GET", "/api/tools", nil)
+	resp = httptest.NewRecorder()
+	app.Router.ServeHTTP(resp, req)
+	var tools []Tool
+	_ = json.Unmarshal(resp.Body.Bytes(), &tools)
+	if len(tools) != 1 || math.Abs(tools[0].B-90) > 1e-6 || math.Abs(tools[0].Qy-math.Sqrt2/2) > 1e-6 {
+		t.Errorf("Expected a tool rotated 90 degrees around y. Got: " + resp.Body.String())
+	}
+	req = httptest.NewRequest("POST", "/api/delete_tool", strings.NewReader(`{"name":"angled"}`))
+	resp = httptest.NewRecorder()
+	app.Router.ServeHTTP(resp, req)
+
+	// With the track moved, the pose in the world is unknown
+	req = httptest.NewRequest("POST", "/api/movesteppers", strings.NewReader(`{"speed":25,"accdur":15,"accspd":10,"dccdur":20,"dccspd":5,"tr":10}`))
+	resp = httptest.NewRecorder()
+	app.Router.ServeHTTP(resp, req)
+	if resp.Code != 200 {
+		t.Fatalf("Failed to move track. Got: " + resp.Body.String())
+	}
+	req = httptest.NewRequest("GET", "/api/pose", nil)
+	resp = httptest.NewRecorder()
+	app.Router.ServeHTTP(resp, req)
+	if resp.Code != 400 || !strings.Contains(resp.Body.String(), arm.ErrUnmodeledAxis.Error()) {
+		t.Errorf("Expected the pose to be refused with the track moved. Got %d: %s", resp.Code, resp.Body.String())
+	}
+	req = httptest.NewRequest("POST", "/api/movesteppers", strings.NewReader(`{"speed":25,"accdur":15,"accspd":10,"dccdur":20,"dccspd":5,"tr":-10}`))
+	resp = httptest.NewRecorder()
+	app.Router.ServeHTTP(resp, req)
+}
+
+func TestPlanPath(t *testing.T) {
+	before := app.Arm.CurrentPosition()
+	theta, _ := app.Arm.Capabilities().Theta(before)
+	pose := kinematics.ForwardKinematics(theta, kinematics.AR3DhParameters)
+
+	// Approach and retract, then go out of reach
+	body := fmt.Sprintf(`{"poses":[{"x":%[1]f,"y":%[2]f,"z":%[3]f,"qw":%[4]f,"qx":%[5]f,"qy":%[6]f,"qz":%[7]f},{"x":%[1]f,"y":%[2]f,"z":%[8]f,"qw":%[4]f,"qx":%[5]f,"qy":%[6]f,"qz":%[7]f}]}`, pose.X, pose.Y, pose.Z-20, pose.Qw, pose.Qx, pose.Qy, pose.Qz, pose.Z)
+	req := httptest.NewRequest("POST", "/api/plan_path", strings.NewReader(body))
+	resp := httptest.NewRecorder()
+	app.Router.ServeHTTP(resp, req)
+	var plan PathPlan
+	_ = json.Unmarshal(resp.Body.Bytes(), &plan)
+	if !plan.Valid || len(plan.Segments) != 2 || plan.Segments[0].Samples != 4 || plan.Segments[0].MaxJointStep == 0 {
+		t.Errorf("Expected a valid path with 2 segments. Got: " + resp.Body.String())
+	}
+
+	body = fmt.Sprintf(`{"poses":[{"x":%f,"y":%f,"z":%f,"qw":%f,"qx":%f,"qy":%f,"qz":%f}]}`, pose.X+2000, pose.Y, pose.Z, pose.Qw, pose.Qx, pose.Qy, pose.Qz)
+	req = httptest.NewRequest("POST", "/api/plan_path", strings.NewReader(body))
+	resp = httptest.NewRecorder()
+	app.Router.ServeHTTP(resp, req)
+	plan = PathPlan{}
+	_ = json.Unmarshal(resp.Body.Bytes(), &plan)
+	if plan.Valid || plan.Segments[0].Error == "" {
+		t.Errorf("Expected an unreachable path. Got: " + resp.Body.String())
+	}
+	if fmt.Sprint(app.Arm.CurrentPosition()) != fmt.Sprint(before) {
+		t.Errorf("Checking a path should not move the arm")
+	}
+
+	// Tiny segments and paths needing too many samples should be refused
+	for _, body := range []string{
+		fmt.Sprintf(`{"poses":[{"x":%f,"y":%f,"z":%f,"qw":%f,"qx":%f,"qy":%f,"qz":%f}],"segmentlength":-5}`, pose.X, pose.Y, pose.Z, pose.Qw, pose.Qx, pose.Qy, pose.Qz),
+		fmt.Sprintf(`{"poses":[{"x":%f,"y":%f,"z":%f,"qw":%f,"qx":%f,"qy":%f,"qz":%f}]}`, pose.X+1e9, pose.Y, pose.Z, pose.Qw, pose.Qx, pose.Qy, pose.Qz),
+	} {
+		req = httptest.NewRequest("POST", "/api/plan_path", strings.NewReader(body))
+		resp = httptest.NewRecorder()
+		app.Router.ServeHTTP(resp, req)
+		if resp.Code != 400 {
+			t.Errorf("Expected path to be refused. Got %d: %s", resp.Code, resp.Body.String())
+		}
+	}
+
+	// With the track moved, the path cannot be planned in the world
+	req = httptest.NewRequest("POST", "/api/movesteppers", strings.NewReader(`{"speed":25,"accdur":15,"accspd":10,"dccdur":20,"dccspd":5,"tr":10}`))
+	resp = httptest.NewRecorder()
+	app.Router.ServeHTTP(resp, req)
+	if resp.Code != 200 {
+		t.Fatalf("Failed to move track. Got: " + resp.Body.String())
+	}
+	body = fmt.Sprintf(`{"poses":[{"x":%f,"y":%f,"z":%f,"qw":%f,"qx":%f,"qy":%f,"qz":%f}]}`, pose.X, pose.Y, pose.Z-20, pose.Qw, pose.Qx, pose.Qy, pose.Qz)
+	req = httptest.NewRequest("POST", "/api/plan_path", strings.NewReader(body))
+	resp = httptest.NewRecorder()
+	app.Router.ServeHTTP(resp, req)
+	if resp.Code != 400 || !strings.Contains(resp.Body.String(), arm.ErrUnmodeledAxis.Error()) {
+		t.Errorf("Expected the path to be refused with the track moved. Got %d: %s", resp.Code, resp.Body.String())
+	}
+	req = httptest.NewRequest("POST", "/api/movesteppers", strings.NewReader(`{"speed":25,"accdur":15,"accspd":10,"dccdur":20,"dccspd":5,"tr":-10}`))
+	resp = httptest.NewRecorder()
+	app.Router.ServeHTTP(resp, req)
+}
+
+func TestConditioning(t *testing.T) {
+	// J5 in the middle of its range is a wrist singularity.
+	req := httptest.NewRequest("POST", "/api/conditioning", strings.NewReader(`{"j1":7000,"j2":3650,"j3":3925,"j4":7000,"j5":2288,"j6":3000}`))
+	resp := httptest.NewRecorder()
+	app.Router.ServeHTTP(resp, req)
+	var conditioning Conditioning
+	_ = json.Unmarshal(resp.Body.Bytes(), &conditioning)
+	if !conditioning.Singular {
+		t.Errorf("Expected a wrist singularity. Got: " + resp.Body.String())
+	}
+
+	req = httptest.NewRequest("POST", "/api/conditioning", strings.NewReader(`{"j1":7000,"j2":3650,"j3":3925,"j4":7000,"j5":3000,"j6":3000}`))
+	resp = httptest.NewRecorder()
+	app.Router.ServeHTTP(resp, req)
+	conditioning = Conditioning{}
+	_ = json.Unmarshal(resp.Body.Bytes(), &conditioning)
+	if conditioning.Singular || conditioning.Manipulability == 0 {
+		t.Errorf("Expected a well conditioned position. Got: " + resp.Body.String())
+	}
+}
+
+func TestJog(t *testing.T) {
+	server := httptest.NewServer(app.Router)
+	defer server.Close()
+	ws, err := websocket.Dial("ws"+strings.TrimPrefix(server.URL, "http")+"/api/jog", "", server.URL)
+	if err != nil {
+		t.Fatalf("Failed to connect to jog WebSocket: %s", err)
+	}
+	defer ws.Close()
+
+	// Jog J1 and make sure the arm moves
+	start := app.Arm.CurrentPosition()
+	var status JogStatus
+	_ = websocket.JSON.Send(ws, JogMessage{Type: "joint", Velocity: []float64{1000, 0, 0, 0, 0, 0, 0}})
+	_ = websocket.JSON.Receive(ws, &status)
+	if !status.Jogging {
+		t.Errorf("Arm should be jogging. Got error: %s", status.Error)
+	}
+	time.Sleep(300 * time.Millisecond)
+	_ = websocket.JSON.Send(ws, JogMessage{Type: "heartbeat"})
+	_ = websocket.JSON.Receive(ws, &status)
+
+	// Other moves are refused while jogging
+	for _, path := range []string{"/api/movesteppers", "/api/movel", "/api/calibrate"} {
+		req := httptest.NewRequest("POST", path, strings.NewReader(`{}`))
+		resp := httptest.NewRecorder()
+		app.Router.ServeHTTP(resp, req)
+		if resp.Code != 409 {
+			t.Errorf("Expected %s to be refused while jogging. Got %d: %s", path, resp.Code, resp.Body.String())
+		}
+	}
+
+	// A second client cannot jog at the same time
+	second, err := websocket.Dial("ws"+strings.TrimPrefix(server.URL, "http")+"/api/jog", "", server.URL)
+	if err != nil {
+		t.Fatalf("Failed to connect to jog WebSocket: %s", err)
+	}
+	var refused JogStatus
+	_ = websocket.JSON.Receive(second, &refused)
+	second.Close()
+	if refused.Error == "" {
+		t.Errorf("Expected a second client to be refused")
+	}
+	_ = websocket.JSON.Send(ws, JogMessage{Type: "heartbeat"})
+	_ = websocket.JSON.Receive(ws, &status)
+	if !status.Jogging {
+		t.Errorf("Arm should still be jogging after a second client was refused. Got error: %s", status.Error)
+	}
+	_ = websocket.JSON.Send(ws, JogMessage{Type: "stop"})
+	_ = websocket.JSON.Receive(ws, &status)
+	if status.Jogging {
+		t.Errorf("Arm should have stopped jogging")
+	}
+	if app.Arm.CurrentPosition()[0] <= start[0] {
+		t.Errorf("J1 should have moved while jogging")
+	}
+}
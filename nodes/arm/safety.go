@@ -0,0 +1,385 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/koeng101/armos/devices/arm"
+	"github.com/koeng101/armos/utils/kinematics"
+	"io/ioutil"
+	"math"
+	"net/http"
+	"strings"
+)
+
+/******************************************************************************
+
+                                armos arm safety
+
+1. /set_soft_limits sets per-joint soft limits on the stepper positions.
+2. /soft_limits returns the current soft limits.
+3. /add_keepout adds a cartesian keep-out box.
+4. /keepouts returns all keep-out boxes.
+5. /delete_keepout removes a keep-out box.
+
+******************************************************************************/
+
+// keepoutStep is the largest change of any joint, in radians, between the
+// points along a move that are checked against keep-out zones. This is about
+// 5mm of tool travel at the AR3's full reach.
+const keepoutStep = 0.5 * math.Pi / 180
+
+// collisionStep is the largest change of any joint, in radians, between the
+// points along a move that are checked for self-collisions.
+const collisionStep = 2 * math.Pi / 180
+
+// SoftLimit restricts a joint to a range of absolute stepper positions that is
+// narrower than the hardware range. Joint is the name of an axis of the arm in
+// lower case, like j1 or tr.
+type SoftLimit struct {
+	Joint string `json:"joint" db:"joint"`
+	Min   int    `json:"min" db:"min"`
+	Max   int    `json:"max" db:"max"`
+}
+
+// Keepout is an axis aligned box, in millimeters in the world, that the
+// selected tool may not enter.
+type Keepout struct {
+	ID   int     `json:"id" db:"id"`
+	Name string  `json:"name" db:"name"`
+	Xmin float64 `json:"xmin" db:"xmin"`
+	Ymin float64 `json:"ymin" db:"ymin"`
+	Zmin float64 `json:"zmin" db:"zmin"`
+	Xmax float64 `json:"xmax" db:"xmax"`
+	Ymax float64 `json:"ymax" db:"ymax"`
+	Zmax float64 `json:"zmax" db:"zmax"`
+}
+
+// Contains checks if a point is inside of the keep-out box.
+func (k Keepout) Contains(x, y, z float64) bool {
+	return x >= k.Xmin && x <= k.Xmax && y >= k.Ymin && y <= k.Ymax && z >= k.Zmin && z <= k.Zmax
+}
+
+// DeleteKeepoutInput is the input to a DeleteKeepout command.
+type DeleteKeepoutInput struct {
+	ID int `json:"id"`
+}
+
+// CheckMove checks that a relative stepper move from the given position stays
+// inside of the soft limits, that the arm does not collide with itself, and
+// that the selected tool does not enter a keep-out zone along the move.
+func (app *App) CheckMove(from arm.Joints, delta arm.Joints) error {
+	capabilities := app.Arm.Capabilities()
+	to, err := capabilities.Add(from, delta)
+	if err != nil {
+		return err
+	}
+
+	// Check soft limits. Moves are linear in joint space, so only the final
+	// position needs to be checked.
+	var limits []SoftLimit
+	err = app.DB.Select(&limits, "SELECT joint, min, max FROM soft_limits")
+	if err != nil {
+		return err
+	}
+	for _, limit := range limits {
+		i, err := axisIndex(capabilities, limit.Joint)
+		if err != nil {
+			return err
+		}
+		position := to[i]
+		if position < limit.Min || position > limit.Max {
+			return fmt.Errorf("%s out of soft limit range. Must be between %d and %d. Got %d", limit.Joint, limit.Min, limit.Max, position)
+		}
+	}
+
+	// Check self-collisions along the move. Axes outside of the kinematic
+	// model, like a track, move the whole arm, so they do not matter here.
+	if capabilities.Collision != nil {
+		fromTheta, err := capabilities.Theta(from)
+		if err != nil {
+			return err
+		}
+		toTheta, err := capabilities.Theta(to)
+		if err != nil {
+			return err
+		}
+		err = capabilities.Collision.CheckSelfCollisionAlong([]kinematics.StepperTheta{fromTheta, toTheta}, collisionStep)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Check keep-out zones by sampling the tool position along the move, so
+	// that no joint moves by more than keepoutStep between samples.
+	var keepouts []Keepout
+	err = app.DB.Select(&keepouts, "SELECT id, name, xmin, ymin, zmin, xmax, ymax, zmax FROM keepouts")
+	if err != nil {
+		return err
+	}
+	if len(keepouts) == 0 {
+		return nil
+	}
+	if capabilities.Kinematics == nil {
+		return fmt.Errorf("Keep-out zones are set, but %s", arm.ErrNoKinematics)
+	}
+	for _, position := range []arm.Joints{from, to} {
+		err = capabilities.CheckModeled(position)
+		if err != nil {
+			return fmt.Errorf("Keep-out zones are set, but %w", err)
+		}
+	}
+	frames, err := app.kinematicFrames()
+	if err != nil {
+		return err
+	}
+	fromTheta, err := capabilities.Theta(from)
+	if err != nil {
+		return err
+	}
+	toTheta, err := capabilities.Theta(to)
+	if err != nil {
+		return err
+	}
+	largest := 0.0
+	for _, d := range []float64{toTheta.J1 - fromTheta.J1, toTheta.J2 - fromTheta.J2, toTheta.J3 - fromTheta.J3, toTheta.J4 - fromTheta.J4, toTheta.J5 - fromTheta.J5, toTheta.J6 - fromTheta.J6} {
+		largest = math.Max(largest, math.Abs(d))
+	}
+	steps := int(math.Max(math.Ceil(largest/keepoutStep), 1))
+	sample := make(arm.Joints, len(from))
+	for s := 0; s <= steps; s++ {
+		fraction := float64(s) / float64(steps)
+		for i := range sample {
+			sample[i] = from[i] + int(fraction*float64(delta[i]))
+		}
+		theta, err := capabilities.Theta(sample)
+		if err != nil {
+			return err
+		}
+		endEffector := frames.ForwardKinematics(theta, *capabilities.Kinematics)
+		for _, keepout := range keepouts {
+			if keepout.Contains(endEffector.X, endEffector.Y, endEffector.Z) {
+				return fmt.Errorf("Move enters keep-out zone %s at x=%f y=%f z=%f", keepout.Name, endEffector.X, endEffector.Y, endEffector.Z)
+			}
+		}
+	}
+	return nil
+}
+
+// axisIndex returns the index of the axis of an arm with the given name,
+// ignoring case.
+func axisIndex(capabilities arm.Capabilities, name string) (int, error) {
+	for i, axis := range capabilities.Axes {
+		if strings.EqualFold(axis.Name, name) {
+			return i, nil
+		}
+	}
+	return 0, fmt.Errorf("%s has no axis named %s", capabilities.Model, name)
+}
+
+// SetSoftLimits replaces the soft limits of the arm's joints.
+// @Summary Sets soft limits of arm joints
+// @Tags safety
+// @Description Replaces all soft limits with the given list. Joints are named after the axes of the arm, like j1 or tr, and min must not be larger than max. Joints without a soft limit only use the hardware range.
+// @Accept json
+// @Produce plain
+// @Param limits body []SoftLimit true "soft limits of joints"
+// @Success 200 {string} string
+// @Failure 400 {string} string
+// @Router /set_soft_limits [post]
+func (app *App) SetSoftLimits(w http.ResponseWriter, r *http.Request) {
+	// Read body
+	reqBody, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		w.WriteHeader(400)
+		_ = json.NewEncoder(w).Encode(err.Error())
+		return
+	}
+
+	// Unmarshal
+	var limits []SoftLimit
+	err = json.Unmarshal(reqBody, &limits)
+	if err != nil {
+		w.WriteHeader(400)
+		_ = json.NewEncoder(w).Encode(err.Error())
+		return
+	}
+
+	// Validate soft limits against the axes of the arm
+	capabilities := app.Arm.Capabilities()
+	for i, limit := range limits {
+		axis, err := axisIndex(capabilities, limit.Joint)
+		if err != nil {
+			w.WriteHeader(400)
+			_ = json.NewEncoder(w).Encode(err.Error())
+			return
+		}
+		if limit.Min > limit.Max {
+			w.WriteHeader(400)
+			_ = json.NewEncoder(w).Encode(fmt.Sprintf("%s soft limit min %d is larger than max %d", limit.Joint, limit.Min, limit.Max))
+			return
+		}
+		limits[i].Joint = strings.ToLower(capabilities.Axes[axis].Name)
+	}
+
+	// Replace soft limits
+	tx, err := app.DB.Beginx()
+	if err != nil {
+		w.WriteHeader(400)
+		_ = json.NewEncoder(w).Encode(err.Error())
+		return
+	}
+	_, err = tx.Exec("DELETE FROM soft_limits")
+	if err != nil {
+		_ = tx.Rollback()
+		w.WriteHeader(400)
+		_ = json.NewEncoder(w).Encode(err.Error())
+		return
+	}
+	for _, limit := range limits {
+		_, err = tx.Exec("INSERT INTO soft_limits(joint, min, max) VALUES (?, ?, ?)", limit.Joint, limit.Min, limit.Max)
+		if err != nil {
+			_ = tx.Rollback()
+			w.WriteHeader(400)
+			_ = json.NewEncoder(w).Encode(err.Error())
+			return
+		}
+	}
+	err = tx.Commit()
+	if err != nil {
+		w.WriteHeader(400)
+		_ = json.NewEncoder(w).Encode(err.Error())
+		return
+	}
+
+	_ = json.NewEncoder(w).Encode("success")
+}
+
+// SoftLimits returns the current soft limits of the arm's joints.
+// @Summary Returns soft limits of arm joints
+// @Tags safety
+// @Description Returns all soft limits of the arm's joints.
+// @Produce json
+// @Success 200 {array} SoftLimit
+// @Failure 400 {string} string
+// @Router /soft_limits [get]
+func (app *App) SoftLimits(w http.ResponseWriter, r *http.Request) {
+	limits := []SoftLimit{}
+	err := app.DB.Select(&limits, "SELECT joint, min, max FROM soft_limits ORDER BY joint")
+	if err != nil {
+		w.WriteHeader(400)
+		_ = json.NewEncoder(w).Encode(err.Error())
+		return
+	}
+
+	_ = json.NewEncoder(w).Encode(limits)
+}
+
+// AddKeepout adds a keep-out zone for the end effector.
+// @Summary Adds a keep-out zone
+// @Tags safety
+// @Description Adds an axis aligned box, in millimeters in the world, that the selected tool may not enter.
+// @Accept json
+// @Produce json
+// @Param keepout body Keepout true "keep-out box. The id is ignored"
+// @Success 200 {object} Keepout
+// @Failure 400 {string} string
+// @Router /add_keepout [post]
+func (app *App) AddKeepout(w http.ResponseWriter, r *http.Request) {
+	// Read body
+	reqBody, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		w.WriteHeader(400)
+		_ = json.NewEncoder(w).Encode(err.Error())
+		return
+	}
+
+	// Unmarshal
+	var k Keepout
+	err = json.Unmarshal(reqBody, &k)
+	if err != nil {
+		w.WriteHeader(400)
+		_ = json.NewEncoder(w).Encode(err.Error())
+		return
+	}
+	if k.Xmin > k.Xmax || k.Ymin > k.Ymax || k.Zmin > k.Zmax {
+		w.WriteHeader(400)
+		_ = json.NewEncoder(w).Encode("Keep-out minimums must be smaller than maximums")
+		return
+	}
+
+	// Insert keep-out zone
+	result, err := app.DB.Exec("INSERT INTO keepouts(name, xmin, ymin, zmin, xmax, ymax, zmax) VALUES (?, ?, ?, ?, ?, ?, ?)", k.Name, k.Xmin, k.Ymin, k.Zmin, k.Xmax, k.Ymax, k.Zmax)
+	if err != nil {
+		w.WriteHeader(400)
+		_ = json.NewEncoder(w).Encode(err.Error())
+		return
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		w.WriteHeader(400)
+		_ = json.NewEncoder(w).Encode(err.Error())
+		return
+	}
+	k.ID = int(id)
+
+	_ = json.NewEncoder(w).Encode(k)
+}
+
+// Keepouts returns all keep-out zones.
+// @Summary Returns keep-out zones
+// @Tags safety
+// @Description Returns all keep-out zones of the selected tool.
+// @Produce json
+// @Success 200 {array} Keepout
+// @Failure 400 {string} string
+// @Router /keepouts [get]
+func (app *App) Keepouts(w http.ResponseWriter, r *http.Request) {
+	keepouts := []Keepout{}
+	err := app.DB.Select(&keepouts, "SELECT id, name, xmin, ymin, zmin, xmax, ymax, zmax FROM keepouts ORDER BY id")
+	if err != nil {
+		w.WriteHeader(400)
+		_ = json.NewEncoder(w).Encode(err.Error())
+		return
+	}
+
+	_ = json.NewEncoder(w).Encode(keepouts)
+}
+
+// DeleteKeepout removes a keep-out zone.
+// @Summary Deletes a keep-out zone
+// @Tags safety
+// @Description Removes a keep-out zone by its id.
+// @Accept json
+// @Produce plain
+// @Param keepout body DeleteKeepoutInput true "id of keep-out zone"
+// @Success 200 {string} string
+// @Failure 400 {string} string
+// @Router /delete_keepout [post]
+func (app *App) DeleteKeepout(w http.ResponseWriter, r *http.Request) {
+	// Read body
+	reqBody, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		w.WriteHeader(400)
+		_ = json.NewEncoder(w).Encode(err.Error())
+		return
+	}
+
+	// Unmarshal
+	var d DeleteKeepoutInput
+	err = json.Unmarshal(reqBody, &d)
+	if err != nil {
+		w.WriteHeader(400)
+		_ = json.NewEncoder(w).Encode(err.Error())
+		return
+	}
+
+	// Delete keep-out zone
+	_, err = app.DB.Exec("DELETE FROM keepouts WHERE id=?", d.ID)
+	if err != nil {
+		w.WriteHeader(400)
+		_ = json.NewEncoder(w).Encode(err.Error())
+		return
+	}
+
+	_ = json.NewEncoder(w).Encode("success")
+}
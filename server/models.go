@@ -1,7 +1,9 @@
 package armos
 
 import (
+	"fmt"
 	"github.com/jmoiron/sqlx"
+	"github.com/koeng101/armos/utils/kinematics"
 )
 
 var Schema = `
@@ -25,6 +27,23 @@ CREATE TABLE IF NOT EXISTS transformation (
 	qz REAL NOT NULL
 );
 
+CREATE TABLE IF NOT EXISTS geometry (
+	object TEXT NOT NULL REFERENCES object(uuid) ON DELETE CASCADE,
+	shape TEXT NOT NULL CHECK (shape IN ('box', 'sphere', 'cylinder')),
+	x REAL NOT NULL,
+	y REAL NOT NULL,
+	z REAL NOT NULL,
+	qw REAL NOT NULL,
+	qx REAL NOT NULL,
+	qy REAL NOT NULL,
+	qz REAL NOT NULL,
+	sizex REAL NOT NULL DEFAULT 0,
+	sizey REAL NOT NULL DEFAULT 0,
+	sizez REAL NOT NULL DEFAULT 0,
+	radius REAL NOT NULL DEFAULT 0,
+	length REAL NOT NULL DEFAULT 0
+);
+
 CREATE TABLE IF NOT EXISTS queue (
 	createdat INT NOT NULL,
 	startedat INT NOT NULL,
@@ -62,6 +81,43 @@ type Transformation struct {
 	Qz     float64 `db:"qz"`
 }
 
+// Geometry is collision geometry of an object, in the frame of the object.
+// Shape is "box", "sphere" or "cylinder". Boxes are centered on their position
+// with edges of SizeX, SizeY and SizeZ. Spheres are centered on their position
+// with Radius. Cylinders have a base of Radius centered on their position, and
+// run Length along their z axis. An object may have any number of shapes.
+type Geometry struct {
+	Object string  `db:"object"`
+	Shape  string  `db:"shape"`
+	X      float64 `db:"x"`
+	Y      float64 `db:"y"`
+	Z      float64 `db:"z"`
+	Qw     float64 `db:"qw"`
+	Qx     float64 `db:"qx"`
+	Qy     float64 `db:"qy"`
+	Qz     float64 `db:"qz"`
+	SizeX  float64 `db:"sizex"`
+	SizeY  float64 `db:"sizey"`
+	SizeZ  float64 `db:"sizez"`
+	Radius float64 `db:"radius"`
+	Length float64 `db:"length"`
+}
+
+// Pose returns the position of the object in the frame of its parent. A
+// rotation of all zeros is treated as no rotation.
+func (t Transformation) Pose() kinematics.XyzWxyz {
+	return kinematics.XyzWxyz{X: t.X, Y: t.Y, Z: t.Z, Qw: t.Qw, Qx: t.Qx, Qy: t.Qy, Qz: t.Qz}
+}
+
+// Pose returns the position of the shape in the frame of its object. A
+// rotation of all zeros is treated as no rotation.
+func (g Geometry) Pose() kinematics.XyzWxyz {
+	return kinematics.XyzWxyz{X: g.X, Y: g.Y, Z: g.Z, Qw: g.Qw, Qx: g.Qx, Qy: g.Qy, Qz: g.Qz}
+}
+
+// shapeTypes are the kinematics shape of each shape of Geometry.
+var shapeTypes = map[string]kinematics.ShapeType{"box": kinematics.Box, "sphere": kinematics.Sphere, "cylinder": kinematics.Cylinder}
+
 func (o *Object) Insert(tx *sqlx.Tx) error {
 	_, err := tx.Exec("INSERT INTO object (uuid, name, address, type) VALUES (?, ?, ?, ?)", o.Uuid, o.Name, o.Address, o.ObjectType)
 	if err != nil {
@@ -70,6 +126,14 @@ func (o *Object) Insert(tx *sqlx.Tx) error {
 	return nil
 }
 
+func (g *Geometry) Insert(tx *sqlx.Tx) error {
+	_, err := tx.Exec("INSERT INTO geometry (object, shape, x, y, z, qw, qx, qy, qz, sizex, sizey, sizez, radius, length) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)", g.Object, g.Shape, g.X, g.Y, g.Z, g.Qw, g.Qx, g.Qy, g.Qz, g.SizeX, g.SizeY, g.SizeZ, g.Radius, g.Length)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
 func (t *Transformation) Insert(tx *sqlx.Tx) error {
 	_, err := tx.Exec("INSERT INTO transformation (parent, object, x, y, z, qw, qx, qy, qz) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)", t.Parent, t.Object, t.X, t.Y, t.Z, t.Qw, t.Qx, t.Qy, t.Qz)
 	if err != nil {
@@ -78,8 +142,9 @@ func (t *Transformation) Insert(tx *sqlx.Tx) error {
 	return nil
 }
 
-// Transforms between two target devices given their UUIDs
-func TransformationsBetween(tx *sqlx.Tx, source string, target string) ([]Transformation, error) {
+// pathToRoot returns the transformations from an object up to the root node,
+// given its UUID.
+func pathToRoot(tx *sqlx.Tx, uuid string) ([]Transformation, error) {
 	// Use a recursive CTE to path up towards root node
 	sqlCte := `WITH RECURSIVE traverse(parentT, obj, xT, yT, zT, qwT, qxT, qyT, qzT) AS (
   SELECT ?, "test", 0.0, 0.0, 0.0, 0.0, 0.0, 0.0, 0.0
@@ -87,21 +152,27 @@ func TransformationsBetween(tx *sqlx.Tx, source string, target string) ([]Transf
   SELECT parent, parentT, x, y, z, qw, qx, qy, qz FROM transformation JOIN traverse ON object = parentT
 ) SELECT parentT AS parent, obj AS object, xT AS x, yT AS y, zT AS z, qwT AS qw, qxT AS qx, qyT AS qy, qzT AS qz FROM traverse;`
 
-	// Get path from source to root
 	var toRoot []Transformation
-	err := tx.Select(&toRoot, sqlCte, source)
+	err := tx.Select(&toRoot, sqlCte, uuid)
+	if err != nil {
+		return []Transformation{}, err
+	}
+	return toRoot[1:], nil
+}
+
+// Transforms between two target devices given their UUIDs
+func TransformationsBetween(tx *sqlx.Tx, source string, target string) ([]Transformation, error) {
+	// Get path from source to root
+	toRoot, err := pathToRoot(tx, source)
 	if err != nil {
 		return []Transformation{}, err
 	}
-	toRoot = toRoot[1:]
 
 	// Get path from target to root
-	var fromRoot []Transformation
-	err = tx.Select(&fromRoot, sqlCte, target)
+	fromRoot, err := pathToRoot(tx, target)
 	if err != nil {
 		return []Transformation{}, err
 	}
-	fromRoot = fromRoot[1:]
 
 	// Reverse fromRoot
 	for i, j := 0, len(fromRoot)-1; i < j; i, j = i+1, j-1 {
@@ -111,3 +182,96 @@ func TransformationsBetween(tx *sqlx.Tx, source string, target string) ([]Transf
 	// Append them together and return
 	return append(toRoot, fromRoot...), nil
 }
+
+// PoseInRoot returns the position of an object in the frame of the root node,
+// given its UUID.
+func PoseInRoot(tx *sqlx.Tx, uuid string) (kinematics.XyzWxyz, error) {
+	toRoot, err := pathToRoot(tx, uuid)
+	if err != nil {
+		return kinematics.XyzWxyz{}, err
+	}
+	pose := kinematics.IdentityPose
+	for _, transformation := range toRoot {
+		pose = transformation.Pose().Mul(pose)
+	}
+	return pose, nil
+}
+
+// PoseBetween returns the position of the target in the frame of the source,
+// given their UUIDs.
+func PoseBetween(tx *sqlx.Tx, source string, target string) (kinematics.XyzWxyz, error) {
+	sourcePose, err := PoseInRoot(tx, source)
+	if err != nil {
+		return kinematics.XyzWxyz{}, err
+	}
+	targetPose, err := PoseInRoot(tx, target)
+	if err != nil {
+		return kinematics.XyzWxyz{}, err
+	}
+	return sourcePose.Inverse().Mul(targetPose), nil
+}
+
+// Obstacles returns the collision geometry of every object in the frame of an
+// arm, given the UUID of the arm. Each shape is named after its object.
+// Objects mounted on the arm, like its end effector, move with the arm, so they
+// are left out along with the arm itself.
+func Obstacles(tx *sqlx.Tx, arm string) ([]kinematics.Geometry, error) {
+	var geometries []Geometry
+	err := tx.Select(&geometries, "SELECT object, shape, x, y, z, qw, qx, qy, qz, sizex, sizey, sizez, radius, length FROM geometry")
+	if err != nil {
+		return []kinematics.Geometry{}, err
+	}
+
+	var obstacles []kinematics.Geometry
+	for _, geometry := range geometries {
+		// Skip the arm and anything mounted on it
+		toRoot, err := pathToRoot(tx, geometry.Object)
+		if err != nil {
+			return []kinematics.Geometry{}, err
+		}
+		mounted := geometry.Object == arm
+		for _, transformation := range toRoot {
+			mounted = mounted || transformation.Parent == arm
+		}
+		if mounted {
+			continue
+		}
+
+		// Place the shape relative to the arm
+		var name string
+		err = tx.Get(&name, "SELECT name FROM object WHERE uuid = ?", geometry.Object)
+		if err != nil {
+			return []kinematics.Geometry{}, err
+		}
+		pose, err := PoseBetween(tx, arm, geometry.Object)
+		if err != nil {
+			return []kinematics.Geometry{}, err
+		}
+		shape, ok := shapeTypes[geometry.Shape]
+		if !ok {
+			return []kinematics.Geometry{}, fmt.Errorf("Unknown shape %s of %s", geometry.Shape, name)
+		}
+		obstacles = append(obstacles, kinematics.Geometry{
+			Name:   name,
+			Shape:  shape,
+			Pose:   pose.Mul(geometry.Pose()),
+			Radius: geometry.Radius,
+			Length: geometry.Length,
+			Size:   [3]float64{geometry.SizeX, geometry.SizeY, geometry.SizeZ},
+		})
+	}
+	return obstacles, nil
+}
+
+// CheckCollision checks that an arm does not collide with the objects around
+// it along a path of joint angles, given the UUID of the arm. A path of one set
+// of joint angles checks a single configuration. Joints move linearly between
+// joint angles, and are checked at steps where no joint moves by more than
+// maxStep radians.
+func CheckCollision(tx *sqlx.Tx, arm string, model kinematics.CollisionModel, path []kinematics.StepperTheta, maxStep float64) error {
+	obstacles, err := Obstacles(tx, arm)
+	if err != nil {
+		return err
+	}
+	return model.CheckCollisionAlong(path, obstacles, maxStep)
+}
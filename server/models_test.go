@@ -1,9 +1,12 @@
 package armos
 
 import (
+	"errors"
 	"fmt"
 	"github.com/jmoiron/sqlx"
+	"github.com/koeng101/armos/utils/kinematics"
 	"log"
+	"math"
 	_ "modernc.org/sqlite"
 	"os"
 	"testing"
@@ -70,3 +73,71 @@ func ExampleTransformationsBetween() {
 	fmt.Println(transformations)
 	// Output: [{1 2 0 0 10 0 0 0 0} {0 1 10 10 0 0 0 0 0} {0 3 30 30 0 0 0 0 0}]
 }
+
+func ExamplePoseBetween() {
+	tx := db.MustBegin()
+	// Position of opentrons relative to ar3_end_effector
+	pose, _ := PoseBetween(tx, "2", "3")
+	_ = tx.Rollback()
+
+	fmt.Printf("%.1f %.1f %.1f\n", pose.X, pose.Y, pose.Z)
+	// Output: 20.0 20.0 -10.0
+}
+
+func TestPoseBetween(t *testing.T) {
+	tx := db.MustBegin()
+	defer func() { _ = tx.Rollback() }()
+
+	// A camera on the ar3, 5mm along x and rotated 90 degrees around z
+	camera := Object{"4", "camera", "https://192.168.1.20", "camera"}
+	_ = camera.Insert(tx)
+	transformation := Transformation{"1", "4", 5.0, 0.0, 0.0, math.Cos(math.Pi / 4), 0.0, 0.0, math.Sin(math.Pi / 4)}
+	_ = transformation.Insert(tx)
+
+	// opentrons is at (20, 20, 0) relative to the ar3. From the camera, that
+	// is 15mm along x of the ar3, which is -y of the camera.
+	pose, err := PoseBetween(tx, "4", "3")
+	if err != nil {
+		t.Fatalf("PoseBetween failed with error: %s", err)
+	}
+	if math.Abs(pose.X-20) > 1e-9 || math.Abs(pose.Y+15) > 1e-9 || math.Abs(pose.Z) > 1e-9 {
+		t.Errorf("Expected opentrons at (20, -15, 0) from the camera. Got %v", pose)
+	}
+	if math.Abs(pose.Qw-math.Cos(math.Pi/4)) > 1e-9 || math.Abs(pose.Qz+math.Sin(math.Pi/4)) > 1e-9 {
+		t.Errorf("Expected opentrons rotated -90 degrees around z from the camera. Got %v", pose)
+	}
+}
+
+func TestCheckCollision(t *testing.T) {
+	tx := db.MustBegin()
+	defer func() { _ = tx.Rollback() }()
+
+	// The opentrons deck is 400mm along x of the opentrons, which is at (20,
+	// 20, 0) relative to the ar3. The end effector has a large sphere, which
+	// moves with the arm and is not an obstacle.
+	deck := Geometry{Object: "3", Shape: "box", X: 400, Qw: 1, SizeX: 200, SizeY: 200, SizeZ: 100}
+	_ = deck.Insert(tx)
+	gripper := Geometry{Object: "2", Shape: "sphere", Radius: 1000}
+	_ = gripper.Insert(tx)
+
+	obstacles, err := Obstacles(tx, "1")
+	if err != nil {
+		t.Fatalf("Obstacles failed with error: %s", err)
+	}
+	if len(obstacles) != 1 || obstacles[0].Name != "opentrons" || obstacles[0].Shape != kinematics.Box {
+		t.Fatalf("Expected the opentrons deck as the only obstacle. Got %v", obstacles)
+	}
+	if pose := obstacles[0].Pose; math.Abs(pose.X-420) > 1e-9 || math.Abs(pose.Y-20) > 1e-9 {
+		t.Errorf("Expected the deck at (420, 20, 0) from the ar3. Got %v", pose)
+	}
+
+	// Home reaches over the deck, and pointing the forearm down reaches into it
+	model := kinematics.AR3CollisionModel
+	if err := CheckCollision(tx, "1", model, []kinematics.StepperTheta{{}}, 0.01); err != nil {
+		t.Errorf("Expected home to be clear. Got %s", err)
+	}
+	err = CheckCollision(tx, "1", model, []kinematics.StepperTheta{{}, {J3: math.Pi / 2}}, 0.01)
+	if !errors.Is(err, kinematics.ErrCollision) {
+		t.Errorf("Expected to hit the deck. Got %v", err)
+	}
+}
@@ -0,0 +1,232 @@
+package kinematics
+
+import (
+	"errors"
+	"math"
+)
+
+// ErrNoAnalyticSolution is returned by AnalyticInverseKinematics when the
+// DhParameters do not describe an arm with a closed-form solution.
+var ErrNoAnalyticSolution = errors.New("DhParameters do not describe a 6R arm with a spherical wrist")
+
+// ErrOutOfReach is returned when no joint angles can reach a pose.
+var ErrOutOfReach = errors.New("Pose is out of reach")
+
+// analyticTolerance is the tolerance used when checking the shape of
+// DhParameters and the accuracy of analytic solutions.
+const analyticTolerance = 1e-6
+
+// AnalyticInverseKinematics calculates every set of joint angles that reach an
+// XyzWxyz end effector position for arms with a closed-form solution, such as
+// the AR3. There are up to 8 solutions: the shoulder can point towards or away
+// from the target, the elbow can be up or down, and the wrist can be flipped.
+// Joint angles are returned between -pi and pi.
+//
+// A closed-form solution exists for 6R arms with a spherical wrist, which in
+// DhParameters means that joint 2 and 3 are parallel (AlphaValues[1] is 0),
+// that the alphas of joints 1, 3, 4 and 5 are +/- pi/2, and that the wrist axes
+// intersect (AValues[3:6] and DValues[4] are 0). Other arms return
+// ErrNoAnalyticSolution.
+func AnalyticInverseKinematics(desiredEndEffector XyzWxyz, dhParameters DhParameters) ([]StepperTheta, error) {
+	if !hasSphericalWrist(dhParameters) {
+		return nil, ErrNoAnalyticSolution
+	}
+	alpha := dhParameters.AlphaValues
+	a := dhParameters.AValues
+	d := dhParameters.DValues
+	sigma1 := math.Sin(alpha[0])
+	sigma3 := math.Sin(alpha[2])
+	sigma4 := math.Sin(alpha[3])
+	sigma5 := math.Sin(alpha[4])
+
+	// Find the wrist center by backing off from the end effector along the
+	// z axis of joint 5.
+	r06 := quaternionToRotation(desiredEndEffector.Qw, desiredEndEffector.Qx, desiredEndEffector.Qy, desiredEndEffector.Qz)
+	wristAxis := mulVec3(r06, [3]float64{0, math.Sin(alpha[5]), math.Cos(alpha[5])})
+	px := desiredEndEffector.X - d[5]*wristAxis[0]
+	py := desiredEndEffector.Y - d[5]*wristAxis[1]
+	pz := desiredEndEffector.Z - d[5]*wristAxis[2]
+
+	// Offset of the wrist center from the plane of joints 2 and 3.
+	dz := d[1] + d[2] + d[3]*math.Cos(alpha[2])
+	radial := px*px + py*py - dz*dz
+	if radial < 0 {
+		return nil, ErrOutOfReach
+	}
+
+	var solutions []StepperTheta
+	for _, r := range []float64{math.Sqrt(radial), -math.Sqrt(radial)} {
+		q1 := math.Atan2(py, px) - math.Atan2(-sigma1*dz, r)
+
+		// Position of the wrist center in the plane of joints 2 and 3
+		x := r - a[0]
+		y := sigma1 * (pz - d[0])
+
+		// Law of cosines for the elbow
+		k := (x*x + y*y - a[1]*a[1] - a[2]*a[2] - d[3]*d[3]) / (2 * a[1])
+		l := math.Sqrt(a[2]*a[2] + d[3]*d[3])
+		if math.Abs(k) > l*(1+analyticTolerance) {
+			continue
+		}
+		phi := math.Atan2(d[3]*sigma3, a[2])
+		elbow := math.Acos(math.Max(-1, math.Min(1, k/l)))
+		for _, q3 := range []float64{phi + elbow, phi - elbow} {
+			u := a[2]*math.Cos(q3) + d[3]*sigma3*math.Sin(q3)
+			v := a[2]*math.Sin(q3) - d[3]*sigma3*math.Cos(q3)
+			q2 := math.Atan2(y, x) - math.Atan2(v, u+a[1])
+
+			// Solve the spherical wrist from the rotation left over after
+			// the first three joints.
+			r03 := identity3()
+			for i, q := range []float64{q1, q2, q3} {
+				r03 = mulMat3(r03, dhRotation(q, alpha[i]))
+			}
+			m := mulMat3(mulMat3(transpose3(r03), r06), rotationX(-alpha[5]))
+			c5 := -sigma4 * sigma5 * m[2][2]
+			s5 := math.Sqrt(m[0][2]*m[0][2] + m[1][2]*m[1][2])
+			for _, wrist := range []float64{s5, -s5} {
+				var q4, q5, q6 float64
+				q5 = math.Atan2(wrist, c5)
+				if math.Abs(wrist) < analyticTolerance {
+					// At a wrist singularity joints 4 and 6 are aligned, so
+					// we keep joint 4 at 0 and let joint 6 do the rotation.
+					if wrist < 0 {
+						continue
+					}
+					n := mulMat3(mulMat3(dhRotation(0, alpha[3]), rotationZ(q5)), rotationX(alpha[4]))
+					rz6 := mulMat3(transpose3(n), m)
+					q6 = math.Atan2(rz6[1][0], rz6[0][0])
+				} else {
+					q4 = math.Atan2(sigma5*m[1][2]/wrist, sigma5*m[0][2]/wrist)
+					q6 = math.Atan2(-sigma4*m[2][1]/wrist, sigma4*m[2][0]/wrist)
+				}
+
+				q := [6]float64{q1, q2, q3, q4, q5, q6}
+				var theta [6]float64
+				for i := range q {
+					theta[i] = wrapAngle(q[i] - dhParameters.ThetaOffsets[i])
+				}
+				solution := StepperTheta{theta[0], theta[1], theta[2], theta[3], theta[4], theta[5]}
+				if poseMatches(ForwardKinematics(solution, dhParameters), desiredEndEffector) {
+					solutions = append(solutions, solution)
+				}
+			}
+		}
+	}
+	if len(solutions) == 0 {
+		return nil, ErrOutOfReach
+	}
+	return solutions, nil
+}
+
+// InverseKinematicsSolutions calculates joint angles that reach an XyzWxyz end
+// effector position. Arms with a closed-form solution return every solution
+// from AnalyticInverseKinematics. Other arms fall back to the numeric
+// InverseKinematics, which returns a single solution.
+func InverseKinematicsSolutions(desiredEndEffector XyzWxyz, dhParameters DhParameters) ([]StepperTheta, error) {
+	solutions, err := AnalyticInverseKinematics(desiredEndEffector, dhParameters)
+	if err != ErrNoAnalyticSolution {
+		return solutions, err
+	}
+	solution, err := InverseKinematics(desiredEndEffector, dhParameters)
+	if err != nil {
+		return nil, err
+	}
+	return []StepperTheta{solution}, nil
+}
+
+// hasSphericalWrist checks if DhParameters describe a 6R arm with parallel
+// joints 2 and 3 and a spherical wrist.
+func hasSphericalWrist(dhParameters DhParameters) bool {
+	alpha := dhParameters.AlphaValues
+	a := dhParameters.AValues
+	d := dhParameters.DValues
+	isZero := func(v float64) bool {
+		return math.Abs(v) < analyticTolerance
+	}
+	if !isZero(math.Sin(alpha[1])) || math.Cos(alpha[1]) < 0 || isZero(a[1]) {
+		return false
+	}
+	for _, i := range []int{0, 2, 3, 4} {
+		if !isZero(math.Cos(alpha[i])) {
+			return false
+		}
+	}
+	return isZero(a[3]) && isZero(a[4]) && isZero(a[5]) && isZero(d[4])
+}
+
+// poseMatches checks if two XyzWxyz positions are the same within
+// analyticTolerance. Quaternions q and -q are the same rotation.
+func poseMatches(a, b XyzWxyz) bool {
+	scale := 1 + math.Max(math.Abs(b.X), math.Max(math.Abs(b.Y), math.Abs(b.Z)))
+	if math.Abs(a.X-b.X) > analyticTolerance*scale || math.Abs(a.Y-b.Y) > analyticTolerance*scale || math.Abs(a.Z-b.Z) > analyticTolerance*scale {
+		return false
+	}
+	a, b = a.Normalize(), b.Normalize()
+	dot := a.Qw*b.Qw + a.Qx*b.Qx + a.Qy*b.Qy + a.Qz*b.Qz
+	return math.Abs(dot) > 1-analyticTolerance
+}
+
+// wrapAngle wraps an angle to between -pi and pi.
+func wrapAngle(angle float64) float64 {
+	return angle - 2*math.Pi*math.Round(angle/(2*math.Pi))
+}
+
+/******************************************************************************
+
+                                3x3 rotation helpers
+
+******************************************************************************/
+
+func identity3() [3][3]float64 {
+	return [3][3]float64{{1, 0, 0}, {0, 1, 0}, {0, 0, 1}}
+}
+
+func mulMat3(a, b [3][3]float64) [3][3]float64 {
+	var c [3][3]float64
+	for i := 0; i < 3; i++ {
+		for j := 0; j < 3; j++ {
+			c[i][j] = a[i][0]*b[0][j] + a[i][1]*b[1][j] + a[i][2]*b[2][j]
+		}
+	}
+	return c
+}
+
+func mulVec3(a [3][3]float64, v [3]float64) [3]float64 {
+	return [3]float64{
+		a[0][0]*v[0] + a[0][1]*v[1] + a[0][2]*v[2],
+		a[1][0]*v[0] + a[1][1]*v[1] + a[1][2]*v[2],
+		a[2][0]*v[0] + a[2][1]*v[1] + a[2][2]*v[2],
+	}
+}
+
+func transpose3(a [3][3]float64) [3][3]float64 {
+	return [3][3]float64{{a[0][0], a[1][0], a[2][0]}, {a[0][1], a[1][1], a[2][1]}, {a[0][2], a[1][2], a[2][2]}}
+}
+
+func rotationX(angle float64) [3][3]float64 {
+	c, s := math.Cos(angle), math.Sin(angle)
+	return [3][3]float64{{1, 0, 0}, {0, c, -s}, {0, s, c}}
+}
+
+func rotationZ(angle float64) [3][3]float64 {
+	c, s := math.Cos(angle), math.Sin(angle)
+	return [3][3]float64{{c, -s, 0}, {s, c, 0}, {0, 0, 1}}
+}
+
+// dhRotation is the rotation part of a standard DH transform.
+func dhRotation(theta, alpha float64) [3][3]float64 {
+	return mulMat3(rotationZ(theta), rotationX(alpha))
+}
+
+// quaternionToRotation converts a quaternion into a rotation matrix. The
+// quaternion is normalized first.
+func quaternionToRotation(qw, qx, qy, qz float64) [3][3]float64 {
+	norm := math.Sqrt(qw*qw + qx*qx + qy*qy + qz*qz)
+	qw, qx, qy, qz = qw/norm, qx/norm, qy/norm, qz/norm
+	return [3][3]float64{
+		{1 - 2*(qy*qy+qz*qz), 2 * (qx*qy - qz*qw), 2 * (qx*qz + qy*qw)},
+		{2 * (qx*qy + qz*qw), 1 - 2*(qx*qx+qz*qz), 2 * (qy*qz - qx*qw)},
+		{2 * (qx*qz - qy*qw), 2 * (qy*qz + qx*qw), 1 - 2*(qx*qx+qy*qy)},
+	}
+}
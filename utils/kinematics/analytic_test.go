@@ -0,0 +1,74 @@
+package kinematics
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+func TestAnalyticInverseKinematics(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < 200; i++ {
+		randTheta := func() float64 {
+			return math.Pi * (2*r.Float64() - 1)
+		}
+		thetas := StepperTheta{randTheta(), randTheta(), randTheta(), randTheta(), randTheta(), randTheta()}
+		desiredEndEffector := ForwardKinematics(thetas, AR3DhParameters)
+		solutions, err := AnalyticInverseKinematics(desiredEndEffector, AR3DhParameters)
+		if err != nil {
+			t.Fatalf("Analytic inverse kinematics failed on %v with error: %s", thetas, err)
+		}
+
+		// Every solution should reach the desired end effector, and one of
+		// them should be the original joint angles.
+		found := false
+		for _, solution := range solutions {
+			if !poseMatches(ForwardKinematics(solution, AR3DhParameters), desiredEndEffector) {
+				t.Errorf("Solution %v does not reach %v", solution, desiredEndEffector)
+			}
+			if math.Abs(wrapAngle(solution.J1-thetas.J1))+math.Abs(wrapAngle(solution.J2-thetas.J2))+math.Abs(wrapAngle(solution.J3-thetas.J3))+math.Abs(wrapAngle(solution.J4-thetas.J4))+math.Abs(wrapAngle(solution.J5-thetas.J5))+math.Abs(wrapAngle(solution.J6-thetas.J6)) < 1e-6 {
+				found = true
+			}
+		}
+		if !found {
+			t.Errorf("Original joint angles %v not in solutions %v", thetas, solutions)
+		}
+	}
+}
+
+func TestAnalyticInverseKinematics_WristSingularity(t *testing.T) {
+	// With J5 at 0, joints 4 and 6 are aligned and there are infinite solutions.
+	thetas := StepperTheta{0.3, -0.5, 0.8, 0.4, 0, -0.2}
+	desiredEndEffector := ForwardKinematics(thetas, AR3DhParameters)
+	solutions, err := AnalyticInverseKinematics(desiredEndEffector, AR3DhParameters)
+	if err != nil {
+		t.Fatalf("Analytic inverse kinematics failed at wrist singularity: %s", err)
+	}
+	for _, solution := range solutions {
+		if !poseMatches(ForwardKinematics(solution, AR3DhParameters), desiredEndEffector) {
+			t.Errorf("Solution %v does not reach %v", solution, desiredEndEffector)
+		}
+	}
+}
+
+func TestAnalyticInverseKinematics_OutOfReach(t *testing.T) {
+	_, err := AnalyticInverseKinematics(XyzWxyz{2000, 0, 0, 0, 0, 0, 1}, AR3DhParameters)
+	if err != ErrOutOfReach {
+		t.Errorf("Expected ErrOutOfReach. Got %v", err)
+	}
+}
+
+func TestInverseKinematicsSolutions_Fallback(t *testing.T) {
+	// Offsetting the wrist removes the closed-form solution.
+	dhParameters := AR3DhParameters
+	dhParameters.AValues[4] = 10
+	desiredEndEffector := ForwardKinematics(StepperTheta{0.3, -0.5, 0.8, 0.4, 0.6, -0.2}, dhParameters)
+	_, err := AnalyticInverseKinematics(desiredEndEffector, dhParameters)
+	if err != ErrNoAnalyticSolution {
+		t.Errorf("Expected ErrNoAnalyticSolution. Got %v", err)
+	}
+	solutions, err := InverseKinematicsSolutions(desiredEndEffector, dhParameters)
+	if err != nil || len(solutions) != 1 {
+		t.Errorf("Expected numeric fallback to return 1 solution. Got %d with error %v", len(solutions), err)
+	}
+}
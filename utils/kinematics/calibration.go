@@ -0,0 +1,213 @@
+package kinematics
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"gonum.org/v1/gonum/mat"
+	"io"
+	"math"
+)
+
+// ErrNotEnoughSamples is returned when there are fewer calibration samples
+// than needed to fit every DH parameter.
+var ErrNotEnoughSamples = errors.New("Not enough calibration samples")
+
+// calibrationParameters is the number of DH parameters fit by Calibrate.
+const calibrationParameters = 24
+
+// CalibrationSample is a set of commanded joint angles and the position of the
+// tool measured at them, in millimeters relative to the base of the arm, for
+// example by a laser tracker or by touching off on a known point.
+type CalibrationSample struct {
+	Theta    StepperTheta
+	Position [3]float64
+}
+
+// CalibrationOptions configure Calibrate.
+//
+// Tool is the measured point on the tool, in millimeters in the flange frame.
+// MaxIterations limits the number of least squares steps. Calibrate stops
+// early once a step improves the RMS residual by less than Tolerance
+// millimeters.
+type CalibrationOptions struct {
+	Tool          [3]float64
+	MaxIterations int
+	Tolerance     float64
+}
+
+// DefaultCalibrationOptions measure the origin of the flange.
+var DefaultCalibrationOptions = CalibrationOptions{MaxIterations: 100, Tolerance: 1e-9}
+
+// CalibrationResult is the result of Calibrate.
+//
+// DhParameters are the fitted parameters. Before and After are the distance,
+// in millimeters, between the measured and predicted tool position of each
+// sample with the nominal and fitted parameters, and RMSBefore and RMSAfter
+// are their root mean square.
+type CalibrationResult struct {
+	DhParameters DhParameters
+	Before       []float64
+	After        []float64
+	RMSBefore    float64
+	RMSAfter     float64
+	Iterations   int
+}
+
+// Calibrate fits DhParameters to measured tool positions with
+// Levenberg-Marquardt least squares, starting from the nominal parameters.
+// Every theta offset, alpha, a and d is fit, so at least 8 samples are needed,
+// and samples should be spread over the whole range of every joint. Parameters
+// that measurements cannot tell apart, like the d values of two parallel
+// joints, are corrected together and barely move from their nominal values.
+func Calibrate(samples []CalibrationSample, nominal DhParameters, options CalibrationOptions) (CalibrationResult, error) {
+	if 3*len(samples) < calibrationParameters {
+		return CalibrationResult{}, fmt.Errorf("%w. Need at least %d, got %d", ErrNotEnoughSamples, calibrationParameters/3, len(samples))
+	}
+	residuals := func(parameters []float64) []float64 {
+		dh := dhFromVector(parameters)
+		r := make([]float64, 3*len(samples))
+		for i, sample := range samples {
+			position := toolPosition(sample.Theta, dh, options.Tool)
+			for j := 0; j < 3; j++ {
+				r[3*i+j] = position[j] - sample.Position[j]
+			}
+		}
+		return r
+	}
+
+	parameters := dhToVector(nominal)
+	r := residuals(parameters)
+	cost := dot(r, r)
+	result := CalibrationResult{Before: sampleDistances(r)}
+	result.RMSBefore = rms(result.Before)
+
+	damping := 1e-3
+	for result.Iterations < options.MaxIterations {
+		result.Iterations++
+
+		// Jacobian of the residuals by central differences
+		jacobian := mat.NewDense(len(r), calibrationParameters, nil)
+		for j := range parameters {
+			step := 1e-6 * math.Max(1, math.Abs(parameters[j]))
+			original := parameters[j]
+			parameters[j] = original + step
+			plus := residuals(parameters)
+			parameters[j] = original - step
+			minus := residuals(parameters)
+			parameters[j] = original
+			for i := range r {
+				jacobian.Set(i, j, (plus[i]-minus[i])/(2*step))
+			}
+		}
+		var normal mat.Dense
+		normal.Mul(jacobian.T(), jacobian)
+		var gradient mat.VecDense
+		gradient.MulVec(jacobian.T(), mat.NewVecDense(len(r), r))
+
+		// Increase damping until a step lowers the cost
+		var candidate, candidateResiduals []float64
+		candidateCost := math.Inf(1)
+		for ; damping < 1e12; damping = damping * 10 {
+			damped := mat.DenseCopyOf(&normal)
+			for j := 0; j < calibrationParameters; j++ {
+				damped.Set(j, j, normal.At(j, j)*(1+damping)+1e-12)
+			}
+			var step mat.VecDense
+			if err := step.SolveVec(damped, &gradient); err != nil {
+				continue
+			}
+			candidate = make([]float64, len(parameters))
+			for j := range candidate {
+				candidate[j] = parameters[j] - step.AtVec(j)
+			}
+			candidateResiduals = residuals(candidate)
+			candidateCost = dot(candidateResiduals, candidateResiduals)
+			if candidateCost < cost {
+				break
+			}
+		}
+		if candidateCost >= cost {
+			break
+		}
+		improvement := math.Sqrt(cost/float64(len(samples))) - math.Sqrt(candidateCost/float64(len(samples)))
+		parameters, r, cost = candidate, candidateResiduals, candidateCost
+		damping = damping / 10
+		if improvement < options.Tolerance {
+			break
+		}
+	}
+
+	result.DhParameters = dhFromVector(parameters)
+	result.After = sampleDistances(r)
+	result.RMSAfter = rms(result.After)
+	return result, nil
+}
+
+// WriteJSON exports DhParameters as JSON, so that calibrated parameters can be
+// saved and loaded with ReadDhParameters.
+func (dhParameters DhParameters) WriteJSON(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(dhParameters)
+}
+
+// ReadDhParameters reads DhParameters exported by WriteJSON.
+func ReadDhParameters(r io.Reader) (DhParameters, error) {
+	var dhParameters DhParameters
+	err := json.NewDecoder(r).Decode(&dhParameters)
+	return dhParameters, err
+}
+
+// toolPosition returns the position of a point in the flange frame relative to
+// the base of the arm.
+func toolPosition(thetas StepperTheta, dhParameters DhParameters, tool [3]float64) [3]float64 {
+	frame := identityFrame4()
+	for i, theta := range thetas.toFloat() {
+		frame.mulJoint(&dhParameters, i, theta)
+	}
+	return frame.transformPoint(tool)
+}
+
+// dhToVector flattens DhParameters into theta offsets, alphas, as and ds.
+func dhToVector(dhParameters DhParameters) []float64 {
+	vector := make([]float64, 0, calibrationParameters)
+	vector = append(vector, dhParameters.ThetaOffsets[:]...)
+	vector = append(vector, dhParameters.AlphaValues[:]...)
+	vector = append(vector, dhParameters.AValues[:]...)
+	return append(vector, dhParameters.DValues[:]...)
+}
+
+// dhFromVector is the inverse of dhToVector.
+func dhFromVector(vector []float64) DhParameters {
+	var dhParameters DhParameters
+	copy(dhParameters.ThetaOffsets[:], vector[0:6])
+	copy(dhParameters.AlphaValues[:], vector[6:12])
+	copy(dhParameters.AValues[:], vector[12:18])
+	copy(dhParameters.DValues[:], vector[18:24])
+	return dhParameters
+}
+
+// sampleDistances returns the length of the residual of each sample.
+func sampleDistances(residuals []float64) []float64 {
+	distances := make([]float64, len(residuals)/3)
+	for i := range distances {
+		distances[i] = norm3([3]float64{residuals[3*i], residuals[3*i+1], residuals[3*i+2]})
+	}
+	return distances
+}
+
+func dot(a, b []float64) float64 {
+	sum := 0.0
+	for i := range a {
+		sum = sum + a[i]*b[i]
+	}
+	return sum
+}
+
+func rms(values []float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+	return math.Sqrt(dot(values, values) / float64(len(values)))
+}
@@ -0,0 +1,84 @@
+package kinematics
+
+import (
+	"bytes"
+	"errors"
+	"math"
+	"math/rand"
+	"testing"
+)
+
+// randomTheta returns random joint angles within the AR3 joint limits.
+func randomTheta(r *rand.Rand) StepperTheta {
+	return AR3JointLimits.sample(r.Float64)
+}
+
+func TestCalibrate(t *testing.T) {
+	// A real arm that is a little off from the nominal parameters, measured
+	// at a point on a tool.
+	actual := AR3DhParameters
+	actual.ThetaOffsets[1] = actual.ThetaOffsets[1] + 0.01
+	actual.ThetaOffsets[2] = actual.ThetaOffsets[2] - 0.005
+	actual.AlphaValues[2] = actual.AlphaValues[2] + 0.003
+	actual.AValues[1] = actual.AValues[1] + 1.5
+	actual.DValues[0] = actual.DValues[0] - 2
+	actual.DValues[3] = actual.DValues[3] + 0.8
+	options := DefaultCalibrationOptions
+	options.Tool = [3]float64{20, 0, -80}
+
+	r := rand.New(rand.NewSource(1))
+	samples := make([]CalibrationSample, 40)
+	for i := range samples {
+		theta := randomTheta(r)
+		samples[i] = CalibrationSample{Theta: theta, Position: toolPosition(theta, actual, options.Tool)}
+	}
+	result, err := Calibrate(samples, AR3DhParameters, options)
+	if err != nil {
+		t.Fatalf("Calibrate failed with error: %s", err)
+	}
+	if result.RMSBefore < 1 || result.RMSAfter > 1e-3 {
+		t.Errorf("Expected the residual to drop from millimeters to under a micron. Got %f to %f", result.RMSBefore, result.RMSAfter)
+	}
+	if len(result.Before) != len(samples) || len(result.After) != len(samples) {
+		t.Errorf("Expected a residual for each sample. Got %d and %d", len(result.Before), len(result.After))
+	}
+
+	// The fitted parameters predict the actual arm away from the samples
+	for i := 0; i < 20; i++ {
+		theta := randomTheta(r)
+		expected := toolPosition(theta, actual, options.Tool)
+		got := toolPosition(theta, result.DhParameters, options.Tool)
+		if norm3(sub3(expected, got)) > 1e-3 {
+			t.Errorf("Expected the tool at %v at %v. Got %v", expected, theta, got)
+		}
+	}
+
+	// Exported parameters can be read back
+	var b bytes.Buffer
+	if err := result.DhParameters.WriteJSON(&b); err != nil {
+		t.Fatalf("WriteJSON failed with error: %s", err)
+	}
+	read, err := ReadDhParameters(&b)
+	if err != nil || read != result.DhParameters {
+		t.Errorf("Expected to read back %v. Got %v, %v", result.DhParameters, read, err)
+	}
+}
+
+func TestCalibrate_Errors(t *testing.T) {
+	samples := make([]CalibrationSample, 7)
+	if _, err := Calibrate(samples, AR3DhParameters, DefaultCalibrationOptions); !errors.Is(err, ErrNotEnoughSamples) {
+		t.Errorf("Expected too few samples to fail. Got %v", err)
+	}
+
+	// Samples measured on the nominal arm are already calibrated
+	r := rand.New(rand.NewSource(2))
+	samples = make([]CalibrationSample, 10)
+	for i := range samples {
+		theta := randomTheta(r)
+		samples[i] = CalibrationSample{Theta: theta, Position: toolPosition(theta, AR3DhParameters, [3]float64{})}
+	}
+	result, err := Calibrate(samples, AR3DhParameters, DefaultCalibrationOptions)
+	if err != nil || result.RMSBefore > 1e-9 || math.Abs(result.RMSAfter-result.RMSBefore) > 1e-9 {
+		t.Errorf("Expected no residual before or after. Got %f to %f, %v", result.RMSBefore, result.RMSAfter, err)
+	}
+}
@@ -0,0 +1,443 @@
+package kinematics
+
+import (
+	"errors"
+	"fmt"
+	"gonum.org/v1/gonum/mat"
+	"gonum.org/v1/gonum/optimize"
+	"math"
+	"math/rand"
+	"time"
+)
+
+// ErrJointCount is the panic value of functions given a different number of
+// joint positions than the joints of a Chain or POEModel. Like gonum's
+// mat.ErrShape, it is a programming error, so it is not returned.
+var ErrJointCount = errors.New("Number of joint positions does not match the number of joints")
+
+// JointType is the type of motion of a Link in a Chain.
+type JointType int
+
+const (
+	// Revolute joints rotate about the z axis of the previous frame.
+	Revolute JointType = iota
+	// Prismatic joints slide along the z axis of the previous frame.
+	Prismatic
+	// Fixed links do not move. They are used to reorient frames in ways
+	// that a single set of DH parameters cannot, like laying a linear track
+	// on its side.
+	Fixed
+)
+
+// Link is a single link of a Chain described by standard DH parameters.
+// Revolute joints add the joint position to Theta, and prismatic joints add
+// the joint position to D. Fixed links only use the parameters.
+//
+// Min and Max are the range of the joint in radians for revolute joints and
+// millimeters for prismatic joints. InverseKinematics picks random seeds
+// within them and prefers solutions within them. They are ignored if Max is
+// not larger than Min.
+type Link struct {
+	Type  JointType
+	Theta float64
+	Alpha float64
+	A     float64
+	D     float64
+	Min   float64
+	Max   float64
+}
+
+// Chain is a serial kinematic chain of any length. Joint positions of a Chain
+// are passed around as a []float64 with one value per moving link, in order,
+// skipping Fixed links.
+type Chain []Link
+
+// ChainIKResult is the result of Chain.InverseKinematics. Fields other than
+// Joints are the same as in IKResult.
+type ChainIKResult struct {
+	Joints           []float64
+	Residual         float64
+	Restarts         int
+	PositionError    float64
+	OrientationError float64
+}
+
+// Chain converts DhParameters into a Chain of 6 revolute joints.
+func (dhParameters DhParameters) Chain() Chain {
+	chain := make(Chain, 6)
+	for i := range chain {
+		chain[i] = Link{Type: Revolute, Theta: dhParameters.ThetaOffsets[i], Alpha: dhParameters.AlphaValues[i], A: dhParameters.AValues[i], D: dhParameters.DValues[i]}
+	}
+	return chain
+}
+
+// DOF returns the number of moving joints of a Chain.
+func (chain Chain) DOF() int {
+	dof := 0
+	for _, link := range chain {
+		if link.Type != Fixed {
+			dof++
+		}
+	}
+	return dof
+}
+
+// dh returns the DH parameters of each link at the given joint positions.
+func (chain Chain) dh(joints []float64) (thetas, alphas, as, ds []float64) {
+	thetas = make([]float64, len(chain))
+	alphas = make([]float64, len(chain))
+	as = make([]float64, len(chain))
+	ds = make([]float64, len(chain))
+	joint := 0
+	for i, link := range chain {
+		thetas[i], alphas[i], as[i], ds[i] = link.Theta, link.Alpha, link.A, link.D
+		switch link.Type {
+		case Revolute:
+			thetas[i] = joints[joint] + link.Theta
+			joint++
+		case Prismatic:
+			ds[i] = joints[joint] + link.D
+			joint++
+		}
+	}
+	return thetas, alphas, as, ds
+}
+
+// ForwardKinematics calculates the end effector XyzWxyz coordinates of a
+// Chain given its joint positions. It does not allocate. ForwardKinematics
+// panics with ErrJointCount if len(joints) is not chain.DOF().
+func (chain Chain) ForwardKinematics(joints []float64) XyzWxyz {
+	if len(joints) != chain.DOF() {
+		panic(ErrJointCount)
+	}
+	frame := identityFrame4()
+	joint := 0
+	for _, link := range chain {
+		theta, d := link.Theta, link.D
+		switch link.Type {
+		case Revolute:
+			theta = joints[joint] + link.Theta
+			joint++
+		case Prismatic:
+			d = joints[joint] + link.D
+			joint++
+		}
+		sinAlpha, cosAlpha := math.Sincos(link.Alpha)
+		frame.mulDH(theta, sinAlpha, cosAlpha, link.A, d)
+	}
+	return frame.pose()
+}
+
+// PreparedChain is a Chain with the sine and cosine of the twist of each link
+// calculated ahead of time, for code that calculates forward kinematics of the
+// same Chain many times, like the inverse kinematics solver.
+type PreparedChain struct {
+	links []preparedLink
+	dof   int
+}
+
+type preparedLink struct {
+	Link
+	sinAlpha float64
+	cosAlpha float64
+}
+
+// Prepare prepares a Chain for repeated forward kinematics.
+func (chain Chain) Prepare() PreparedChain {
+	prepared := PreparedChain{links: make([]preparedLink, len(chain)), dof: chain.DOF()}
+	for i, link := range chain {
+		sinAlpha, cosAlpha := math.Sincos(link.Alpha)
+		prepared.links[i] = preparedLink{Link: link, sinAlpha: sinAlpha, cosAlpha: cosAlpha}
+	}
+	return prepared
+}
+
+// ForwardKinematics calculates the end effector XyzWxyz coordinates of a
+// PreparedChain given its joint positions, the same way as the Chain it was
+// prepared from. It does not allocate. ForwardKinematics panics with
+// ErrJointCount if len(joints) is not the DOF of the Chain.
+func (chain PreparedChain) ForwardKinematics(joints []float64) XyzWxyz {
+	if len(joints) != chain.dof {
+		panic(ErrJointCount)
+	}
+	frame := identityFrame4()
+	joint := 0
+	for _, link := range chain.links {
+		theta, d := link.Theta, link.D
+		switch link.Type {
+		case Revolute:
+			theta = joints[joint] + link.Theta
+			joint++
+		case Prismatic:
+			d = joints[joint] + link.D
+			joint++
+		}
+		frame.mulDH(theta, link.sinAlpha, link.cosAlpha, link.A, d)
+	}
+	return frame.pose()
+}
+
+// frame4 is a homogeneous transform stored as the top three rows of a 4x4
+// matrix. The bottom row is always 0, 0, 0, 1, so it is left out.
+type frame4 [3][4]float64
+
+func identityFrame4() frame4 {
+	return frame4{{1, 0, 0, 0}, {0, 1, 0, 0}, {0, 0, 1, 0}}
+}
+
+// mulDH multiplies a frame by the transform of a standard DH link in place,
+// given the sine and cosine of the twist of the link.
+func (frame *frame4) mulDH(theta, sinAlpha, cosAlpha, a, d float64) {
+	sinTheta, cosTheta := math.Sincos(theta)
+	link := frame4{
+		{cosTheta, -sinTheta * cosAlpha, sinTheta * sinAlpha, a * cosTheta},
+		{sinTheta, cosTheta * cosAlpha, -cosTheta * sinAlpha, a * sinTheta},
+		{0, sinAlpha, cosAlpha, d},
+	}
+	for i := range frame {
+		row := frame[i]
+		for j := 0; j < 3; j++ {
+			frame[i][j] = row[0]*link[0][j] + row[1]*link[1][j] + row[2]*link[2][j]
+		}
+		frame[i][3] = row[0]*link[0][3] + row[1]*link[1][3] + row[2]*link[2][3] + row[3]
+	}
+}
+
+// mulJoint multiplies a frame by the transform of joint i of DhParameters at
+// joint angle theta in place.
+func (frame *frame4) mulJoint(dhParameters *DhParameters, i int, theta float64) {
+	sinAlpha, cosAlpha := math.Sincos(dhParameters.AlphaValues[i])
+	frame.mulDH(theta+dhParameters.ThetaOffsets[i], sinAlpha, cosAlpha, dhParameters.AValues[i], dhParameters.DValues[i])
+}
+
+// transformPoint moves a point from a frame into the frame it is relative to.
+func (frame *frame4) transformPoint(point [3]float64) [3]float64 {
+	var moved [3]float64
+	for i := range moved {
+		moved[i] = frame[i][0]*point[0] + frame[i][1]*point[1] + frame[i][2]*point[2] + frame[i][3]
+	}
+	return moved
+}
+
+// transform converts a frame into a transform.
+func (frame *frame4) transform() transform {
+	var t transform
+	for i := range t.r {
+		t.r[i] = [3]float64{frame[i][0], frame[i][1], frame[i][2]}
+		t.p[i] = frame[i][3]
+	}
+	return t
+}
+
+// pose converts a frame into an XyzWxyz.
+func (frame *frame4) pose() XyzWxyz {
+	var pose XyzWxyz
+	pose.X, pose.Y, pose.Z = frame[0][3], frame[1][3], frame[2][3]
+	pose.Qw, pose.Qx, pose.Qy, pose.Qz = rotationToQuaternion([3][3]float64{
+		{frame[0][0], frame[0][1], frame[0][2]},
+		{frame[1][0], frame[1][1], frame[1][2]},
+		{frame[2][0], frame[2][1], frame[2][2]},
+	})
+	return pose
+}
+
+// Jacobian calculates the geometric Jacobian of a Chain, which is a 6xDOF
+// matrix. Rows are the same as in the Jacobian function. Columns of prismatic
+// joints are in millimeters per second per millimeter per second. Jacobian
+// panics with ErrJointCount if len(joints) is not chain.DOF().
+func (chain Chain) Jacobian(joints []float64) *mat.Dense {
+	if len(joints) != chain.DOF() {
+		panic(ErrJointCount)
+	}
+	thetas, alphas, as, ds := chain.dh(joints)
+	var axes, origins [][3]float64
+	var types []JointType
+	rotation := identity3()
+	var position [3]float64
+	for i, link := range chain {
+		// Joint i moves along or about the z axis of the frame before it.
+		if link.Type != Fixed {
+			axes = append(axes, [3]float64{rotation[0][2], rotation[1][2], rotation[2][2]})
+			origins = append(origins, position)
+			types = append(types, link.Type)
+		}
+		offset := mulVec3(rotation, [3]float64{as[i] * math.Cos(thetas[i]), as[i] * math.Sin(thetas[i]), ds[i]})
+		for j := range position {
+			position[j] = position[j] + offset[j]
+		}
+		rotation = mulMat3(rotation, dhRotation(thetas[i], alphas[i]))
+	}
+
+	jacobian := mat.NewDense(6, len(axes), nil)
+	for i, z := range axes {
+		if types[i] == Prismatic {
+			jacobian.Set(0, i, z[0])
+			jacobian.Set(1, i, z[1])
+			jacobian.Set(2, i, z[2])
+			continue
+		}
+		r := [3]float64{position[0] - origins[i][0], position[1] - origins[i][1], position[2] - origins[i][2]}
+		jacobian.Set(0, i, z[1]*r[2]-z[2]*r[1])
+		jacobian.Set(1, i, z[2]*r[0]-z[0]*r[2])
+		jacobian.Set(2, i, z[0]*r[1]-z[1]*r[0])
+		jacobian.Set(3, i, z[0])
+		jacobian.Set(4, i, z[1])
+		jacobian.Set(5, i, z[2])
+	}
+	return jacobian
+}
+
+// InverseKinematics calculates joint positions of a Chain that reach an
+// XyzWxyz end effector position, starting from a seed. It uses the same
+// numeric solver as InverseKinematicsWithOptions. Chains with fewer than 6
+// joints usually cannot reach an arbitrary rotation, and should set
+// PositionOnly in the options. Revolute joints of the result are wrapped to
+// within their range where possible, or to between -pi and pi if they have
+// no range. A solution with a joint outside of its range does not count as
+// converged, so the solver restarts until it finds one within every range.
+func (chain Chain) InverseKinematics(desiredEndEffector XyzWxyz, seed []float64, options IKOptions) (ChainIKResult, error) {
+	dof := chain.DOF()
+	if len(seed) != dof {
+		return ChainIKResult{}, fmt.Errorf("Chain has %d joints. Got seed with %d", dof, len(seed))
+	}
+	randFloat := rand.Float64
+	if options.Rand != nil {
+		randFloat = rand.New(options.Rand).Float64
+	}
+	var deadline time.Time
+	if options.TimeBudget > 0 {
+		deadline = time.Now().Add(options.TimeBudget)
+	}
+
+	problem := optimize.Problem{Func: chain.objectiveFunction(desiredEndEffector, options.PositionOnly)}
+	best := ChainIKResult{Residual: math.Inf(1)}
+	bestWithinRange := false
+	initial := append([]float64{}, seed...)
+	for restarts := 0; ; restarts++ {
+		settings := &optimize.Settings{}
+		if !deadline.IsZero() {
+			settings.Runtime = time.Until(deadline)
+		}
+		result, err := optimize.Minimize(problem, initial, settings, options.Method)
+		if err != nil {
+			return best, err
+		}
+		joints := chain.wrap(append([]float64{}, result.Location.X...))
+		withinRange := chain.withinRange(joints)
+		if (withinRange && !bestWithinRange) || (withinRange == bestWithinRange && result.Location.F < best.Residual) {
+			best.Joints = joints
+			best.Residual = result.Location.F
+			bestWithinRange = withinRange
+		}
+		best.Restarts = restarts
+		if bestWithinRange && best.Residual <= options.Tolerance {
+			break
+		}
+		if restarts >= options.MaxRestarts {
+			return chain.diagnostics(best, desiredEndEffector), fmt.Errorf("%w after %d restarts", ErrNoConvergence, restarts)
+		}
+		if !deadline.IsZero() && time.Now().After(deadline) {
+			return chain.diagnostics(best, desiredEndEffector), fmt.Errorf("%w within time budget of %s", ErrNoConvergence, options.TimeBudget)
+		}
+
+		// If the results aren't up to spec, restart from a random seed.
+		initial = chain.randomSeed(randFloat, seed)
+	}
+	return chain.diagnostics(best, desiredEndEffector), nil
+}
+
+// randomSeed returns random joint positions. Joints with a range are seeded
+// within it. Revolute joints without a range are seeded between -pi and pi,
+// and prismatic joints without a range keep their original seed.
+func (chain Chain) randomSeed(randFloat func() float64, seed []float64) []float64 {
+	random := make([]float64, 0, len(seed))
+	for _, link := range chain {
+		switch {
+		case link.Type == Fixed:
+			continue
+		case link.Max > link.Min:
+			random = append(random, link.Min+(link.Max-link.Min)*randFloat())
+		case link.Type == Revolute:
+			random = append(random, -math.Pi+2*math.Pi*randFloat())
+		default:
+			random = append(random, seed[len(random)])
+		}
+	}
+	return random
+}
+
+// wrap adds or removes full turns from revolute joint positions in place.
+// Joints with a range are brought as close as possible to the middle of it,
+// which puts them within the range if they can be, and other joints are
+// brought between -pi and pi. It returns joints.
+func (chain Chain) wrap(joints []float64) []float64 {
+	joint := 0
+	for _, link := range chain {
+		switch link.Type {
+		case Fixed:
+			continue
+		case Revolute:
+			middle := 0.0
+			if link.Max > link.Min {
+				middle = (link.Min + link.Max) / 2
+			}
+			joints[joint] = middle + wrapAngle(joints[joint]-middle)
+		}
+		joint++
+	}
+	return joints
+}
+
+// withinRange checks that every joint with a range is within it.
+func (chain Chain) withinRange(joints []float64) bool {
+	joint := 0
+	for _, link := range chain {
+		if link.Type == Fixed {
+			continue
+		}
+		if link.Max > link.Min && (joints[joint] < link.Min || joints[joint] > link.Max) {
+			return false
+		}
+		joint++
+	}
+	return true
+}
+
+// objectiveFunction returns the error between the end effector position of a
+// set of joint positions and the desired end effector position, for use with
+// an optimizer. If positionOnly is true, rotation is ignored.
+func (chain Chain) objectiveFunction(desiredEndEffector XyzWxyz, positionOnly bool) func(s []float64) float64 {
+	prepared := chain.Prepare()
+	return func(s []float64) float64 {
+		currentEndEffector := prepared.ForwardKinematics(s)
+
+		// Get XYZ offsets
+		xOffset := desiredEndEffector.X - currentEndEffector.X
+		yOffset := desiredEndEffector.Y - currentEndEffector.Y
+		zOffset := desiredEndEffector.Z - currentEndEffector.Z
+		if positionOnly {
+			return ((xOffset * xOffset) + (yOffset * yOffset) + (zOffset * zOffset)) * 0.25
+		}
+
+		// Get rotational offsets. Essentially, do this in Golang (from python): np.arccos(np.clip(2*(np.dot(target_quat, source_quat)**2) - 1, -1, 1))
+		dotOffset := (desiredEndEffector.Qw * currentEndEffector.Qw) + (desiredEndEffector.Qx * currentEndEffector.Qx) + (desiredEndEffector.Qy * currentEndEffector.Qy) + (desiredEndEffector.Qz * currentEndEffector.Qz)
+		dotOffset = (2*(dotOffset*dotOffset) - 1)
+		if dotOffset > 1 {
+			dotOffset = 1
+		}
+		rotationalOffset := math.Acos(dotOffset)
+
+		// Get the error vector
+		errorVector := ((xOffset * xOffset) + (yOffset * yOffset) + (zOffset * zOffset) + (rotationalOffset * rotationalOffset)) * 0.25
+
+		return errorVector
+	}
+}
+
+// diagnostics fills in the position and orientation error of a result.
+func (chain Chain) diagnostics(result ChainIKResult, desiredEndEffector XyzWxyz) ChainIKResult {
+	reached := chain.ForwardKinematics(result.Joints)
+	result.PositionError = reached.Distance(desiredEndEffector)
+	result.OrientationError = reached.Angle(desiredEndEffector)
+	return result
+}
@@ -0,0 +1,164 @@
+package kinematics
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+// scaraChain is a 4-axis SCARA arm: two revolute joints in the horizontal
+// plane, a prismatic z axis, and a revolute tool rotation.
+var scaraChain = Chain{
+	{Type: Revolute, A: 325, D: 400},
+	{Type: Revolute, A: 225, Alpha: math.Pi},
+	{Type: Prismatic, Min: 0, Max: 200},
+	{Type: Revolute},
+}
+
+// planarChain is a 3-axis arm with three parallel revolute joints.
+var planarChain = Chain{
+	{Type: Revolute, A: 200},
+	{Type: Revolute, A: 150},
+	{Type: Revolute, A: 100},
+}
+
+func TestChain_ForwardKinematics(t *testing.T) {
+	thetas := StepperTheta{0.3, -1.2, 1.5, 0.4, 0.6, -0.2}
+	expected := ForwardKinematics(thetas, AR3DhParameters)
+	if AR3DhParameters.Chain().ForwardKinematics(thetas.toFloat()) != expected {
+		t.Errorf("Chain of DhParameters should match ForwardKinematics")
+	}
+
+	// Moving the track moves the AR3 along y without rotating it.
+	onTrack := AR3TrackChain.ForwardKinematics([]float64{250, 0.3, -1.2, 1.5, 0.4, 0.6, -0.2})
+	if math.Abs(onTrack.X-expected.X) > 1e-9 || math.Abs(onTrack.Y-expected.Y-250) > 1e-9 || math.Abs(onTrack.Z-expected.Z) > 1e-9 {
+		t.Errorf("Expected %v moved 250mm along y. Got %v", expected, onTrack)
+	}
+	if !poseMatches(XyzWxyz{Qw: onTrack.Qw, Qx: onTrack.Qx, Qy: onTrack.Qy, Qz: onTrack.Qz}, XyzWxyz{Qw: expected.Qw, Qx: expected.Qx, Qy: expected.Qy, Qz: expected.Qz}) {
+		t.Errorf("Track should not rotate the AR3")
+	}
+
+	// The SCARA tool points down, and the prismatic joint lowers it.
+	scara := scaraChain.ForwardKinematics([]float64{0, 0, 50, 0})
+	if math.Abs(scara.X-550) > 1e-9 || math.Abs(scara.Z-350) > 1e-9 {
+		t.Errorf("Expected SCARA at x=550 z=350. Got %v", scara)
+	}
+}
+
+func TestChain_InverseKinematics(t *testing.T) {
+	options := DefaultIKOptions
+	options.Rand = rand.NewSource(1)
+
+	// 7DOF AR3 on a track
+	desiredEndEffector := AR3TrackChain.ForwardKinematics([]float64{250, 0.3, -1.2, 1.5, 0.4, 0.6, -0.2})
+	result, err := AR3TrackChain.InverseKinematics(desiredEndEffector, []float64{0, 0.2, -1, 1.4, 0.3, 0.5, -0.1}, options)
+	if err != nil {
+		t.Errorf("AR3 on track inverse kinematics failed with error: %s", err)
+	}
+	if result.PositionError > 0.01 || result.OrientationError > 0.01 {
+		t.Errorf("AR3 on track missed target by %fmm and %f radians", result.PositionError, result.OrientationError)
+	}
+
+	// 4DOF SCARA
+	desiredEndEffector = scaraChain.ForwardKinematics([]float64{0.5, 1, 120, -0.3})
+	result, err = scaraChain.InverseKinematics(desiredEndEffector, []float64{0, 0.5, 100, 0}, options)
+	if err != nil {
+		t.Errorf("SCARA inverse kinematics failed with error: %s", err)
+	}
+	if result.PositionError > 0.01 || result.OrientationError > 0.01 {
+		t.Errorf("SCARA missed target by %fmm and %f radians", result.PositionError, result.OrientationError)
+	}
+
+	// 3DOF planar arm, which can only reach positions
+	options.PositionOnly = true
+	desiredEndEffector = XyzWxyz{X: 250, Y: 150, Qw: 1}
+	result, err = planarChain.InverseKinematics(desiredEndEffector, []float64{0, 0, 0}, options)
+	if err != nil {
+		t.Errorf("Planar inverse kinematics failed with error: %s", err)
+	}
+	if result.PositionError > 0.01 {
+		t.Errorf("Planar arm missed target by %fmm", result.PositionError)
+	}
+
+	// Results are wrapped, even from a seed many turns away
+	result, err = planarChain.InverseKinematics(desiredEndEffector, []float64{20, -30, 40}, options)
+	if err != nil {
+		t.Errorf("Planar inverse kinematics failed with error: %s", err)
+	}
+	for _, joint := range result.Joints {
+		if joint < -math.Pi || joint > math.Pi {
+			t.Errorf("Expected joints between -pi and pi. Got %v", result.Joints)
+		}
+	}
+}
+
+func TestChain_wrap(t *testing.T) {
+	chain := Chain{
+		{Type: Revolute},
+		{Type: Fixed},
+		{Type: Revolute, Min: 0, Max: 2 * math.Pi},
+		{Type: Prismatic},
+	}
+	joints := chain.wrap([]float64{3 * math.Pi / 2, -math.Pi / 2, 1000})
+	expected := []float64{-math.Pi / 2, 3 * math.Pi / 2, 1000}
+	for i := range expected {
+		if math.Abs(joints[i]-expected[i]) > 1e-12 {
+			t.Errorf("Expected %v. Got %v", expected, joints)
+		}
+	}
+}
+
+func TestChain_Jacobian(t *testing.T) {
+	jacobian := AR3TrackChain.Jacobian([]float64{250, 0.3, -1.2, 1.5, 0.4, 0.6, -0.2})
+	rows, columns := jacobian.Dims()
+	if rows != 6 || columns != 7 {
+		t.Fatalf("Expected a 6x7 Jacobian. Got %dx%d", rows, columns)
+	}
+	// The track moves the end effector along y without rotating it.
+	for row, expected := range []float64{0, 1, 0, 0, 0, 0} {
+		if math.Abs(jacobian.At(row, 0)-expected) > 1e-9 {
+			t.Errorf("Expected track column to be %f at row %d. Got %f", expected, row, jacobian.At(row, 0))
+		}
+	}
+}
+
+func TestPreparedChain_ForwardKinematics(t *testing.T) {
+	joints := []float64{250, 0.3, -1.2, 1.5, 0.4, 0.6, -0.2}
+	prepared := AR3TrackChain.Prepare()
+	if expected, got := AR3TrackChain.ForwardKinematics(joints), prepared.ForwardKinematics(joints); expected != got {
+		t.Errorf("Expected %v. Got %v", expected, got)
+	}
+	allocations := testing.AllocsPerRun(100, func() {
+		_ = prepared.ForwardKinematics(joints)
+	})
+	if allocations != 0 {
+		t.Errorf("Expected PreparedChain.ForwardKinematics to not allocate. Got %f allocations", allocations)
+	}
+}
+
+func TestChain_JointCount(t *testing.T) {
+	for name, f := range map[string]func(){
+		"Chain.ForwardKinematics":         func() { AR3TrackChain.ForwardKinematics(make([]float64, 6)) },
+		"PreparedChain.ForwardKinematics": func() { AR3TrackChain.Prepare().ForwardKinematics(make([]float64, 8)) },
+		"Chain.Jacobian":                  func() { planarChain.Jacobian(make([]float64, 2)) },
+		"POEModel.ForwardKinematics":      func() { planarChain.POE().ForwardKinematics(make([]float64, 4)) },
+		"ModifiedChain.ForwardKinematics": func() { AR3TrackChain.Modified().ForwardKinematics(make([]float64, 8)) },
+	} {
+		func() {
+			defer func() {
+				if r := recover(); r != ErrJointCount {
+					t.Errorf("Expected %s to panic with ErrJointCount. Got %v", name, r)
+				}
+			}()
+			f()
+		}()
+	}
+}
+
+func BenchmarkChain_ForwardKinematics(b *testing.B) {
+	joints := []float64{250, 0.3, -1.2, 1.5, 0.4, 0.6, -0.2}
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		_ = AR3TrackChain.ForwardKinematics(joints)
+	}
+}
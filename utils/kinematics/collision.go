@@ -0,0 +1,380 @@
+package kinematics
+
+import (
+	"errors"
+	"fmt"
+	"math"
+)
+
+// ErrSelfCollision is returned when two links of an arm collide.
+var ErrSelfCollision = errors.New("Arm collides with itself")
+
+// ErrCollision is returned when a link of an arm collides with an obstacle.
+var ErrCollision = errors.New("Arm collides with an obstacle")
+
+// ShapeType is the type of shape of a Geometry.
+type ShapeType int
+
+const (
+	// Capsule shapes are every point within Radius of a line segment that
+	// starts at the origin of the shape and runs Length along its z axis.
+	Capsule ShapeType = iota
+	// Box shapes are boxes centered on the origin of the shape, with edges of
+	// Size along its x, y and z axes.
+	Box
+	// Sphere shapes are every point within Radius of the origin of the shape.
+	Sphere
+	// Cylinder shapes have a base of Radius centered on the origin of the
+	// shape, and run Length along its z axis. Cylinders are treated as their
+	// bounding box when checked against boxes and other cylinders.
+	Cylinder
+)
+
+// Geometry is a convex shape attached to a frame of an arm, used to check for
+// collisions. Frame 0 is the base of the arm, and frame i is the DH frame after
+// joint i, so frame 6 is the flange. Pose is the position of the shape in its
+// frame. A rotation of all zeros is treated as no rotation.
+//
+// Geometry is also used for obstacles around an arm, in which case Frame is
+// ignored and Pose is the position of the shape relative to the base of the
+// arm.
+type Geometry struct {
+	Name   string
+	Frame  int
+	Shape  ShapeType
+	Pose   XyzWxyz
+	Radius float64
+	Length float64
+	Size   [3]float64
+}
+
+// CapsuleBetween returns a capsule attached to a frame that runs from start to
+// end, which are points in the frame.
+func CapsuleBetween(name string, frame int, start, end [3]float64, radius float64) Geometry {
+	direction := [3]float64{end[0] - start[0], end[1] - start[1], end[2] - start[2]}
+	length := norm3(direction)
+	pose := IdentityPose
+	if length > 0 {
+		axis := cross3([3]float64{0, 0, 1}, direction)
+		angle := math.Atan2(norm3(axis), direction[2])
+		if norm3(axis) < 1e-12 {
+			axis = [3]float64{1, 0, 0}
+		}
+		pose = PoseFromAngleAxis(angle, axis)
+	}
+	pose.X, pose.Y, pose.Z = start[0], start[1], start[2]
+	return Geometry{Name: name, Frame: frame, Shape: Capsule, Pose: pose, Radius: radius, Length: length}
+}
+
+// CollisionModel is the collision geometry of a 6 joint arm. Links are checked
+// against each other, except for links attached to the same frame and pairs of
+// links named in Allowed. Links that meet at a joint always touch, so they
+// must be allowed.
+type CollisionModel struct {
+	DhParameters DhParameters
+	Links        []Geometry
+	Allowed      [][2]string
+}
+
+// CheckSelfCollision checks if any two links of an arm collide at the given
+// joint angles. Joint limits are not checked.
+func (model CollisionModel) CheckSelfCollision(thetas StepperTheta) error {
+	shapes := model.place(thetas)
+	for i := range model.Links {
+		for j := i + 1; j < len(model.Links); j++ {
+			if model.Links[i].Frame == model.Links[j].Frame || model.allowed(model.Links[i].Name, model.Links[j].Name) {
+				continue
+			}
+			if intersects(shapes[i], shapes[j]) {
+				return fmt.Errorf("%w. %s hits %s", ErrSelfCollision, model.Links[i].Name, model.Links[j].Name)
+			}
+		}
+	}
+	return nil
+}
+
+// CheckSelfCollisionAlong checks for collisions along moves between joint
+// angles, like the samples of a CartesianPath. Joints move linearly between
+// each pair of joint angles, which are checked at steps where no joint moves
+// by more than maxStep radians. maxStep must be positive.
+func (model CollisionModel) CheckSelfCollisionAlong(path []StepperTheta, maxStep float64) error {
+	return checkAlong(path, maxStep, model.CheckSelfCollision)
+}
+
+// CheckCollision checks if any link of an arm collides with any of the
+// obstacles at the given joint angles. Links attached to frame 0 are checked
+// too, so the surface an arm is mounted on should sit just below its base.
+func (model CollisionModel) CheckCollision(thetas StepperTheta, obstacles []Geometry) error {
+	shapes := model.place(thetas)
+	for _, obstacle := range obstacles {
+		placed := placeGeometry(obstacle, identityTransform())
+		for i, shape := range shapes {
+			if intersects(shape, placed) {
+				return fmt.Errorf("%w. %s hits %s", ErrCollision, model.Links[i].Name, obstacle.Name)
+			}
+		}
+	}
+	return nil
+}
+
+// CheckCollisionAlong checks for collisions with obstacles along moves between
+// joint angles, in the same way as CheckSelfCollisionAlong.
+func (model CollisionModel) CheckCollisionAlong(path []StepperTheta, obstacles []Geometry, maxStep float64) error {
+	return checkAlong(path, maxStep, func(thetas StepperTheta) error {
+		return model.CheckCollision(thetas, obstacles)
+	})
+}
+
+// checkAlong checks joint angles along moves between joint angles, at steps
+// where no joint moves by more than maxStep radians.
+func checkAlong(path []StepperTheta, maxStep float64, check func(StepperTheta) error) error {
+	if maxStep <= 0 {
+		return fmt.Errorf("Step between checked joint angles must be positive. Got %f", maxStep)
+	}
+	if len(path) == 0 {
+		return nil
+	}
+	err := check(path[0])
+	if err != nil {
+		return fmt.Errorf("At the start of the path: %w", err)
+	}
+	for i := 1; i < len(path); i++ {
+		from, to := path[i-1], path[i]
+		step, _ := maxJointStep(from, to)
+		steps := int(math.Ceil(step / maxStep))
+		for s := 1; s <= steps; s++ {
+			err = check(interpolateTheta(from, to, float64(s)/float64(steps)))
+			if err != nil {
+				return fmt.Errorf("Move %d of %d at %.0f%%: %w", i, len(path)-1, 100*float64(s)/float64(steps), err)
+			}
+		}
+	}
+	return nil
+}
+
+// allowed checks if two links are allowed to touch.
+func (model CollisionModel) allowed(a, b string) bool {
+	for _, pair := range model.Allowed {
+		if (pair[0] == a && pair[1] == b) || (pair[0] == b && pair[1] == a) {
+			return true
+		}
+	}
+	return false
+}
+
+// place returns the shape of each link relative to the base of the arm.
+func (model CollisionModel) place(thetas StepperTheta) []placedShape {
+	var frames [7]transform
+	frame := identityFrame4()
+	frames[0] = frame.transform()
+	for i, theta := range thetas.toFloat() {
+		frame.mulJoint(&model.DhParameters, i, theta)
+		frames[i+1] = frame.transform()
+	}
+	shapes := make([]placedShape, len(model.Links))
+	for i, link := range model.Links {
+		shapes[i] = placeGeometry(link, frames[link.Frame])
+	}
+	return shapes
+}
+
+// interpolateTheta moves linearly from one set of joint angles to another.
+func interpolateTheta(from, to StepperTheta, fraction float64) StepperTheta {
+	a, b := from.toFloat(), to.toFloat()
+	for i := range a {
+		a[i] = a[i] + (b[i]-a[i])*fraction
+	}
+	return StepperTheta{a[0], a[1], a[2], a[3], a[4], a[5]}
+}
+
+/******************************************************************************
+
+                                Shape intersection
+
+******************************************************************************/
+
+// placedShape is a Geometry placed relative to a common frame.
+type placedShape struct {
+	shape  ShapeType
+	pose   transform
+	radius float64
+	length float64
+	half   [3]float64
+}
+
+// placeGeometry places a Geometry attached to a frame. Spheres are placed as
+// capsules with no length.
+func placeGeometry(geometry Geometry, frame transform) placedShape {
+	shape := placedShape{
+		shape:  geometry.Shape,
+		pose:   frame.mul(transformOf(geometry.Pose.Normalize())),
+		radius: geometry.Radius,
+		length: geometry.Length,
+		half:   [3]float64{geometry.Size[0] / 2, geometry.Size[1] / 2, geometry.Size[2] / 2},
+	}
+	if shape.shape == Sphere {
+		shape.shape, shape.length = Capsule, 0
+	}
+	return shape
+}
+
+// boundingBox returns the bounding box of a cylinder.
+func (s placedShape) boundingBox() placedShape {
+	box := placedShape{shape: Box, pose: s.pose, half: [3]float64{s.radius, s.radius, s.length / 2}}
+	for i := range box.pose.p {
+		box.pose.p[i] = box.pose.p[i] + s.length/2*s.pose.r[i][2]
+	}
+	return box
+}
+
+// segment returns the ends of the line segment of a capsule.
+func (s placedShape) segment() ([3]float64, [3]float64) {
+	end := s.pose.p
+	for i := range end {
+		end[i] = end[i] + s.length*s.pose.r[i][2]
+	}
+	return s.pose.p, end
+}
+
+// intersects checks if two shapes touch or overlap.
+func intersects(a, b placedShape) bool {
+	if a.shape != Capsule && b.shape == Capsule {
+		a, b = b, a
+	}
+	if a.shape != Capsule && a.shape != Box {
+		a = a.boundingBox()
+	}
+	switch {
+	case a.shape == Capsule && b.shape == Capsule:
+		a0, a1 := a.segment()
+		b0, b1 := b.segment()
+		return segmentDistance(a0, a1, b0, b1) <= a.radius+b.radius
+	case a.shape == Capsule && b.shape == Cylinder:
+		a0, a1 := a.segment()
+		return segmentConvexDistance(a0, a1, b.pointCylinderDistance) <= a.radius
+	case a.shape == Capsule:
+		a0, a1 := a.segment()
+		return segmentConvexDistance(a0, a1, b.pointBoxDistance) <= a.radius
+	case b.shape == Cylinder:
+		return boxesOverlap(a, b.boundingBox())
+	default:
+		return boxesOverlap(a, b)
+	}
+}
+
+// segmentDistance returns the shortest distance between two line segments.
+// It follows Real-Time Collision Detection by Christer Ericson, section 5.1.9.
+func segmentDistance(p1, q1, p2, q2 [3]float64) float64 {
+	d1, d2, r := sub3(q1, p1), sub3(q2, p2), sub3(p1, p2)
+	a, e, f := dot3(d1, d1), dot3(d2, d2), dot3(d2, r)
+	var s, t float64
+	switch {
+	case a <= 1e-12 && e <= 1e-12:
+	case a <= 1e-12:
+		t = clamp(f/e, 0, 1)
+	default:
+		c := dot3(d1, r)
+		if e <= 1e-12 {
+			s = clamp(-c/a, 0, 1)
+		} else {
+			b := dot3(d1, d2)
+			denominator := a*e - b*b
+			if denominator > 1e-12 {
+				s = clamp((b*f-c*e)/denominator, 0, 1)
+			}
+			t = (b*s + f) / e
+			if t < 0 {
+				t, s = 0, clamp(-c/a, 0, 1)
+			} else if t > 1 {
+				t, s = 1, clamp((b-c)/a, 0, 1)
+			}
+		}
+	}
+	closest1 := [3]float64{p1[0] + d1[0]*s, p1[1] + d1[1]*s, p1[2] + d1[2]*s}
+	closest2 := [3]float64{p2[0] + d2[0]*t, p2[1] + d2[1]*t, p2[2] + d2[2]*t}
+	return norm3(sub3(closest1, closest2))
+}
+
+// pointBoxDistance returns the distance from a point to a box, which is 0 for
+// points inside of the box.
+func (box placedShape) pointBoxDistance(point [3]float64) float64 {
+	local := mulVec3(transpose3(box.pose.r), sub3(point, box.pose.p))
+	var outside [3]float64
+	for i := range local {
+		outside[i] = local[i] - clamp(local[i], -box.half[i], box.half[i])
+	}
+	return norm3(outside)
+}
+
+// pointCylinderDistance returns the distance from a point to a cylinder, which
+// is 0 for points inside of the cylinder.
+func (cylinder placedShape) pointCylinderDistance(point [3]float64) float64 {
+	local := mulVec3(transpose3(cylinder.pose.r), sub3(point, cylinder.pose.p))
+	radial := math.Max(0, math.Hypot(local[0], local[1])-cylinder.radius)
+	axial := local[2] - clamp(local[2], 0, cylinder.length)
+	return math.Hypot(radial, axial)
+}
+
+// segmentConvexDistance returns the shortest distance between a line segment
+// and a convex shape, given the distance from a point to the shape. The
+// distance to a convex shape is convex along the segment, so the closest point
+// is found with a ternary search.
+func segmentConvexDistance(start, end [3]float64, distance func([3]float64) float64) float64 {
+	at := func(t float64) float64 {
+		return distance([3]float64{start[0] + (end[0]-start[0])*t, start[1] + (end[1]-start[1])*t, start[2] + (end[2]-start[2])*t})
+	}
+	low, high := 0.0, 1.0
+	for i := 0; i < 60; i++ {
+		a, b := low+(high-low)/3, high-(high-low)/3
+		if at(a) < at(b) {
+			high = b
+		} else {
+			low = a
+		}
+	}
+	return at((low + high) / 2)
+}
+
+// boxesOverlap checks if two boxes overlap with the separating axis test.
+func boxesOverlap(a, b placedShape) bool {
+	var axes [][3]float64
+	for i := 0; i < 3; i++ {
+		axes = append(axes, column3(a.pose.r, i), column3(b.pose.r, i))
+	}
+	for i := 0; i < 3; i++ {
+		for j := 0; j < 3; j++ {
+			axis := cross3(column3(a.pose.r, i), column3(b.pose.r, j))
+			if norm3(axis) > 1e-9 {
+				axes = append(axes, normalize3(axis))
+			}
+		}
+	}
+	offset := sub3(b.pose.p, a.pose.p)
+	for _, axis := range axes {
+		if math.Abs(dot3(offset, axis)) > boxExtent(a, axis)+boxExtent(b, axis) {
+			return false
+		}
+	}
+	return true
+}
+
+// boxExtent is half of the length of a box projected onto an axis.
+func boxExtent(box placedShape, axis [3]float64) float64 {
+	extent := 0.0
+	for i := 0; i < 3; i++ {
+		extent = extent + box.half[i]*math.Abs(dot3(column3(box.pose.r, i), axis))
+	}
+	return extent
+}
+
+func sub3(a, b [3]float64) [3]float64 {
+	return [3]float64{a[0] - b[0], a[1] - b[1], a[2] - b[2]}
+}
+
+func column3(a [3][3]float64, i int) [3]float64 {
+	return [3]float64{a[0][i], a[1][i], a[2][i]}
+}
+
+func clamp(value, min, max float64) float64 {
+	return math.Max(min, math.Min(max, value))
+}
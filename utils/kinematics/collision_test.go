@@ -0,0 +1,117 @@
+package kinematics
+
+import (
+	"errors"
+	"math"
+	"testing"
+)
+
+func TestCheckSelfCollision(t *testing.T) {
+	// Home and the calibration position are clear
+	for _, thetas := range []StepperTheta{{}, AR3JointLimits.Min} {
+		if err := AR3CollisionModel.CheckSelfCollision(thetas); err != nil {
+			t.Errorf("Expected %v to be clear. Got %s", thetas, err)
+		}
+	}
+
+	// Folding the forearm back onto the upper arm, or reaching the wrist down
+	// into the base, collides.
+	for _, thetas := range []StepperTheta{{J3: math.Pi}, {-0.97, -0.11, 2.44, 0.12, 1.13, -2}} {
+		if err := AR3CollisionModel.CheckSelfCollision(thetas); !errors.Is(err, ErrSelfCollision) {
+			t.Errorf("Expected %v to collide. Got %v", thetas, err)
+		}
+	}
+}
+
+func TestCheckSelfCollisionAlong(t *testing.T) {
+	// A post in front of the arm, and a bar that J1 swings through it
+	model := CollisionModel{
+		DhParameters: AR3DhParameters,
+		Links: []Geometry{
+			{Name: "post", Frame: 0, Shape: Box, Pose: XyzWxyz{X: 300, Z: 170}, Size: [3]float64{20, 20, 20}},
+			CapsuleBetween("bar", 1, [3]float64{0, 0, 0}, [3]float64{400, 0, 0}, 5),
+		},
+	}
+	from := StepperTheta{J1: -0.5}
+	to := StepperTheta{J1: 0.5}
+	if model.CheckSelfCollision(from) != nil || model.CheckSelfCollision(to) != nil {
+		t.Fatalf("Expected both ends of the move to be clear")
+	}
+	if err := model.CheckSelfCollisionAlong([]StepperTheta{from, to}, 0.01); !errors.Is(err, ErrSelfCollision) {
+		t.Errorf("Expected the move to collide. Got %v", err)
+	}
+	if err := model.CheckSelfCollisionAlong([]StepperTheta{from, {J1: -0.2}, from}, 0.01); err != nil {
+		t.Errorf("Expected the move to be clear. Got %s", err)
+	}
+	if err := model.CheckSelfCollisionAlong([]StepperTheta{from, to}, 0); err == nil {
+		t.Errorf("Expected a step of 0 to fail")
+	}
+
+	// Allowed pairs are not checked
+	model.Allowed = [][2]string{{"bar", "post"}}
+	if err := model.CheckSelfCollisionAlong([]StepperTheta{from, to}, 0.01); err != nil {
+		t.Errorf("Expected allowed links not to collide. Got %s", err)
+	}
+}
+
+func TestCheckCollision(t *testing.T) {
+	// A bench 150mm below the base of the arm, and a vial rack in front of it
+	// below the elbow
+	bench := Geometry{Name: "bench", Shape: Box, Pose: XyzWxyz{Z: -200}, Size: [3]float64{2000, 2000, 100}}
+	rack := Geometry{Name: "rack", Shape: Cylinder, Pose: XyzWxyz{X: 370}, Radius: 50, Length: 80}
+	obstacles := []Geometry{bench, rack}
+
+	// Home reaches over the rack, and pointing the forearm down reaches into it
+	if err := AR3CollisionModel.CheckCollision(StepperTheta{}, obstacles); err != nil {
+		t.Errorf("Expected home to be clear. Got %s", err)
+	}
+	err := AR3CollisionModel.CheckCollision(StepperTheta{J3: math.Pi / 2}, obstacles)
+	if !errors.Is(err, ErrCollision) {
+		t.Errorf("Expected to hit the rack. Got %v", err)
+	}
+
+	// Swinging around the rack clears it only on the way there
+	from := StepperTheta{J1: -1.5, J3: math.Pi / 2}
+	if err := AR3CollisionModel.CheckCollisionAlong([]StepperTheta{from, {J1: -0.8, J3: math.Pi / 2}}, obstacles, 0.01); err != nil {
+		t.Errorf("Expected the move to be clear. Got %s", err)
+	}
+	if err := AR3CollisionModel.CheckCollisionAlong([]StepperTheta{from, {J1: 1.5, J3: math.Pi / 2}}, obstacles, 0.01); !errors.Is(err, ErrCollision) {
+		t.Errorf("Expected the move to hit the rack. Got %v", err)
+	}
+}
+
+func TestIntersects(t *testing.T) {
+	place := func(geometry Geometry) placedShape {
+		return placeGeometry(geometry, identityTransform())
+	}
+	box := place(Geometry{Shape: Box, Size: [3]float64{20, 20, 20}})
+	turned := PoseFromAngleAxis(math.Pi/4, [3]float64{0, 0, 1})
+	turned.X = 24
+	testCases := []struct {
+		a, b     placedShape
+		expected bool
+	}{
+		// Crossing capsules
+		{place(CapsuleBetween("", 0, [3]float64{-10, 0, 0}, [3]float64{10, 0, 0}, 1)), place(CapsuleBetween("", 0, [3]float64{0, -10, 1.5}, [3]float64{0, 10, 1.5}, 1)), true},
+		{place(CapsuleBetween("", 0, [3]float64{-10, 0, 0}, [3]float64{10, 0, 0}, 1)), place(CapsuleBetween("", 0, [3]float64{0, -10, 2.5}, [3]float64{0, 10, 2.5}, 1)), false},
+		// A capsule passing over the corner of a box
+		{place(CapsuleBetween("", 0, [3]float64{0, 20, 12.5}, [3]float64{20, 0, 12.5}, 2)), box, false},
+		{place(CapsuleBetween("", 0, [3]float64{0, 20, 11}, [3]float64{20, 0, 11}, 2)), box, true},
+		// A box turned 45 degrees reaches further than its side
+		{place(Geometry{Shape: Box, Pose: turned, Size: [3]float64{20, 20, 20}}), box, true},
+		{place(Geometry{Shape: Box, Pose: XyzWxyz{X: 24}, Size: [3]float64{20, 20, 20}}), box, false},
+		// A sphere near the corner of a box
+		{place(Geometry{Shape: Sphere, Pose: XyzWxyz{X: 12, Y: 12, Z: 12}, Radius: 3}), box, false},
+		{place(Geometry{Shape: Sphere, Pose: XyzWxyz{X: 12, Y: 12, Z: 12}, Radius: 4}), box, true},
+		// A capsule passing by the rim of a cylinder, and a box on its bounding box
+		{place(CapsuleBetween("", 0, [3]float64{-20, 8, 25}, [3]float64{20, 8, 25}, 2)), place(Geometry{Shape: Cylinder, Radius: 10, Length: 20}), false},
+		{place(CapsuleBetween("", 0, [3]float64{-20, 8, 21}, [3]float64{20, 8, 21}, 2)), place(Geometry{Shape: Cylinder, Radius: 10, Length: 20}), true},
+		{place(CapsuleBetween("", 0, [3]float64{9, 9, -20}, [3]float64{9, 9, 20}, 1)), place(Geometry{Shape: Cylinder, Radius: 10, Length: 20}), false},
+		{place(Geometry{Shape: Box, Pose: XyzWxyz{X: 9, Y: 9, Z: 10}, Size: [3]float64{2, 2, 2}}), place(Geometry{Shape: Cylinder, Radius: 10, Length: 20}), true},
+	}
+	for i, testCase := range testCases {
+		if intersects(testCase.a, testCase.b) != testCase.expected || intersects(testCase.b, testCase.a) != testCase.expected {
+			t.Errorf("Test case %d: expected %t", i, testCase.expected)
+		}
+	}
+}
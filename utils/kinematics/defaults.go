@@ -13,3 +13,35 @@ var AR3DhParameters DhParameters = DhParameters{
 	AValues:      [...]float64{64.2, 305, 0, 0, 0, 0},
 	DValues:      [...]float64{169.77, 0, 0, -222.63, 0, -36.25},
 }
+
+// AR3JointLimits are the AR3 joint ranges published by Annin Robotics, in
+// radians.
+var AR3JointLimits JointLimits = JointLimits{
+	Min: StepperTheta{-170 * math.Pi / 180, -129.6 * math.Pi / 180, 1 * math.Pi / 180, -164.5 * math.Pi / 180, -104.15 * math.Pi / 180, -148.1 * math.Pi / 180},
+	Max: StepperTheta{170 * math.Pi / 180, 0, 143.7 * math.Pi / 180, 164.5 * math.Pi / 180, 104.15 * math.Pi / 180, 148.1 * math.Pi / 180},
+}
+
+// AR3CollisionModel is rough collision geometry of the AR3, with every link
+// slightly larger than the real arm. Frame 1 has its y axis pointing down
+// along J1 and frame 3 has its z axis pointing from the elbow to the wrist,
+// which is why some of the links run along negative axes. Tools are not
+// included, and can be added as links on frame 6.
+var AR3CollisionModel CollisionModel = CollisionModel{
+	DhParameters: AR3DhParameters,
+	Links: []Geometry{
+		{Name: "base", Frame: 0, Shape: Box, Pose: XyzWxyz{Z: 50, Qw: 1}, Size: [3]float64{240, 240, 100}},
+		CapsuleBetween("shoulder", 1, [3]float64{-64.2, 70, 0}, [3]float64{0, 0, 0}, 60),
+		CapsuleBetween("upper arm", 2, [3]float64{-305, 0, 0}, [3]float64{0, 0, 0}, 45),
+		CapsuleBetween("forearm", 3, [3]float64{0, 0, 0}, [3]float64{0, 0, -222.63}, 40),
+		CapsuleBetween("wrist", 5, [3]float64{0, 0, 0}, [3]float64{0, 0, -36.25}, 35),
+	},
+	Allowed: [][2]string{{"base", "shoulder"}, {"shoulder", "upper arm"}, {"upper arm", "forearm"}, {"forearm", "wrist"}},
+}
+
+// AR3TrackChain is an AR3 mounted on a linear track along the y axis. The
+// first joint is the track position in millimeters, followed by the six
+// joints of the AR3.
+var AR3TrackChain Chain = append(Chain{
+	{Type: Fixed, Alpha: -(math.Pi / 2)},
+	{Type: Prismatic, Alpha: math.Pi / 2},
+}, AR3DhParameters.Chain()...)
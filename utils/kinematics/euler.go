@@ -0,0 +1,144 @@
+package kinematics
+
+import (
+	"errors"
+	"math"
+)
+
+// ErrInvalidEulerOrder is returned when an EulerConvention does not have an
+// order of three different axes.
+var ErrInvalidEulerOrder = errors.New("Euler order must be three different axes out of X, Y and Z, like ZYX")
+
+// EulerConvention describes how three Euler angles make up a rotation. Order
+// is the axis of each angle, like "ZYX". Intrinsic rotations (the default)
+// rotate around the axes of the frame as it moves, so that an intrinsic "ZYX"
+// rotation is Rz(a) * Ry(b) * Rx(c). Extrinsic rotations rotate around the
+// fixed axes of the original frame, so that an extrinsic "XYZ" rotation is
+// Rz(c) * Ry(b) * Rx(a). Rotations about the same axis twice (proper Euler
+// angles, like ZYZ) are not supported.
+type EulerConvention struct {
+	Order     string
+	Extrinsic bool
+}
+
+// RPY is the roll, pitch, yaw convention. Roll is around the fixed x axis,
+// then pitch around the fixed y axis, then yaw around the fixed z axis. This is
+// the same rotation as ZYX intrinsic with the angles reversed.
+var RPY = EulerConvention{Order: "XYZ", Extrinsic: true}
+
+// ABC is the A, B, C convention used by ARCS and most industrial arms. A is
+// around the z axis, then B around the new y axis, then C around the new x
+// axis.
+var ABC = EulerConvention{Order: "ZYX"}
+
+// XyzAbc is a position in millimeters with a rotation in radians using the
+// ABC convention. It is the x,y,z,a,b,c form of an XyzWxyz.
+type XyzAbc struct {
+	X float64
+	Y float64
+	Z float64
+	A float64
+	B float64
+	C float64
+}
+
+// XyzWxyz converts an XyzAbc into an XyzWxyz.
+func (p XyzAbc) XyzWxyz() XyzWxyz {
+	pose, _ := PoseFromEuler(p.X, p.Y, p.Z, [3]float64{p.A, p.B, p.C}, ABC)
+	return pose
+}
+
+// XyzAbc converts an XyzWxyz into an XyzAbc. At gimbal lock (B of +/-pi/2),
+// C is 0.
+func (pose XyzWxyz) XyzAbc() XyzAbc {
+	angles, _ := pose.Euler(ABC)
+	return XyzAbc{X: pose.X, Y: pose.Y, Z: pose.Z, A: angles[0], B: angles[1], C: angles[2]}
+}
+
+// axes returns the axis indexes of an EulerConvention in intrinsic order,
+// along with +1 if they are in cyclic order (like XYZ) or -1 if they are not
+// (like ZYX).
+func (convention EulerConvention) axes() ([3]int, float64, error) {
+	var axes [3]int
+	if len(convention.Order) != 3 {
+		return axes, 0, ErrInvalidEulerOrder
+	}
+	for i, axis := range convention.Order {
+		switch axis {
+		case 'X', 'x':
+			axes[i] = 0
+		case 'Y', 'y':
+			axes[i] = 1
+		case 'Z', 'z':
+			axes[i] = 2
+		default:
+			return axes, 0, ErrInvalidEulerOrder
+		}
+	}
+	if axes[0] == axes[1] || axes[1] == axes[2] || axes[0] == axes[2] {
+		return axes, 0, ErrInvalidEulerOrder
+	}
+	if convention.Extrinsic {
+		axes[0], axes[2] = axes[2], axes[0]
+	}
+	parity := 1.0
+	if (axes[1]-axes[0]+3)%3 != 1 {
+		parity = -1
+	}
+	return axes, parity, nil
+}
+
+// PoseFromEuler returns a pose at x, y, z rotated by Euler angles. The angles
+// are in radians, in the order of the axes of the convention.
+func PoseFromEuler(x, y, z float64, angles [3]float64, convention EulerConvention) (XyzWxyz, error) {
+	axes, _, err := convention.axes()
+	if err != nil {
+		return XyzWxyz{}, err
+	}
+	if convention.Extrinsic {
+		angles[0], angles[2] = angles[2], angles[0]
+	}
+	pose := IdentityPose
+	for i, axis := range axes {
+		var direction [3]float64
+		direction[axis] = 1
+		pose = pose.Mul(PoseFromAngleAxis(angles[i], direction))
+	}
+	pose.X, pose.Y, pose.Z = x, y, z
+	return pose, nil
+}
+
+// Euler returns the rotation of a pose as Euler angles in radians, in the order
+// of the axes of the convention. The first and last angles are between -pi and
+// pi, and the middle angle is between -pi/2 and pi/2.
+//
+// When the middle angle is +/-pi/2 (gimbal lock), the first and last axes line
+// up and only their sum or difference is defined. In that case the angle of
+// the rotation applied first (the last intrinsic angle, or the first extrinsic
+// angle) is 0.
+func (pose XyzWxyz) Euler(convention EulerConvention) ([3]float64, error) {
+	axes, parity, err := convention.axes()
+	if err != nil {
+		return [3]float64{}, err
+	}
+	q := pose.Normalize()
+	r := quaternionToRotation(q.Qw, q.Qx, q.Qy, q.Qz)
+	i, j, k := axes[0], axes[1], axes[2]
+
+	var angles [3]float64
+	sinMiddle := math.Max(-1, math.Min(1, parity*r[i][k]))
+	cosMiddle := math.Hypot(r[i][i], r[i][j])
+	angles[1] = math.Atan2(sinMiddle, cosMiddle)
+	if cosMiddle > 1e-9 {
+		angles[0] = math.Atan2(-parity*r[j][k], r[k][k])
+		angles[2] = math.Atan2(-parity*r[i][j], r[i][i])
+	} else {
+		// Gimbal lock, so put all of the rotation into the first angle
+		angles[0] = math.Atan2(parity*r[k][j], r[j][j])
+		angles[2] = 0
+	}
+	if convention.Extrinsic {
+		angles[0], angles[2] = angles[2], angles[0]
+	}
+	return angles, nil
+}
@@ -0,0 +1,148 @@
+package kinematics
+
+import (
+	"math"
+	"testing"
+)
+
+func rotationY(angle float64) [3][3]float64 {
+	c, s := math.Cos(angle), math.Sin(angle)
+	return [3][3]float64{{c, 0, s}, {0, 1, 0}, {-s, 0, c}}
+}
+
+// eulerMatrix builds the rotation matrix of intrinsic Euler angles directly
+// from rotation matrices.
+func eulerMatrix(order string, angles [3]float64) [3][3]float64 {
+	r := identity3()
+	for i, axis := range order {
+		switch axis {
+		case 'X':
+			r = mulMat3(r, rotationX(angles[i]))
+		case 'Y':
+			r = mulMat3(r, rotationY(angles[i]))
+		case 'Z':
+			r = mulMat3(r, rotationZ(angles[i]))
+		}
+	}
+	return r
+}
+
+func matricesEqual(a, b [3][3]float64, tolerance float64) bool {
+	for i := 0; i < 3; i++ {
+		if !vectorsEqual(a[i], b[i], tolerance) {
+			return false
+		}
+	}
+	return true
+}
+
+var eulerOrders = []string{"XYZ", "XZY", "YXZ", "YZX", "ZXY", "ZYX"}
+
+func TestPoseFromEuler(t *testing.T) {
+	angles := [3]float64{0.3, -0.7, 1.9}
+	for _, order := range eulerOrders {
+		pose, err := PoseFromEuler(1, 2, 3, angles, EulerConvention{Order: order})
+		if err != nil {
+			t.Fatalf("PoseFromEuler %s failed with error: %s", order, err)
+		}
+		if !matricesEqual(quaternionToRotation(pose.Qw, pose.Qx, pose.Qy, pose.Qz), eulerMatrix(order, angles), 1e-12) {
+			t.Errorf("Intrinsic %s does not match rotation matrices", order)
+		}
+		if pose.X != 1 || pose.Y != 2 || pose.Z != 3 {
+			t.Errorf("Position should be kept. Got %v", pose)
+		}
+
+		// Extrinsic rotations are intrinsic rotations in reverse order
+		reversed := string([]byte{order[2], order[1], order[0]})
+		extrinsic, _ := PoseFromEuler(1, 2, 3, angles, EulerConvention{Order: order, Extrinsic: true})
+		if !matricesEqual(quaternionToRotation(extrinsic.Qw, extrinsic.Qx, extrinsic.Qy, extrinsic.Qz), eulerMatrix(reversed, [3]float64{angles[2], angles[1], angles[0]}), 1e-12) {
+			t.Errorf("Extrinsic %s does not match rotation matrices", order)
+		}
+	}
+
+	// Known values
+	testCases := []struct {
+		name       string
+		angles     [3]float64
+		convention EulerConvention
+		expected   XyzWxyz
+	}{
+		{"yaw", [3]float64{0, 0, math.Pi / 2}, RPY, rotationZ90},
+		{"roll", [3]float64{math.Pi, 0, 0}, RPY, XyzWxyz{Qx: 1}},
+		{"a", [3]float64{math.Pi / 2, 0, 0}, ABC, rotationZ90},
+		{"b", [3]float64{0, math.Pi, 0}, ABC, XyzWxyz{Qy: 1}},
+		// 90 degrees around x, then around the new y, is 120 degrees around (1, 1, -1)
+		{"intrinsic xy", [3]float64{math.Pi / 2, math.Pi / 2, 0}, EulerConvention{Order: "XYZ"}, XyzWxyz{Qw: 0.5, Qx: 0.5, Qy: 0.5, Qz: 0.5}},
+	}
+	for _, testCase := range testCases {
+		pose, _ := PoseFromEuler(0, 0, 0, testCase.angles, testCase.convention)
+		if !posesEqual(pose, testCase.expected, 1e-12) {
+			t.Errorf("%s: expected %v. Got %v", testCase.name, testCase.expected, pose)
+		}
+	}
+
+	// RPY and ABC are the same rotation with the angles reversed
+	rpy, _ := PoseFromEuler(0, 0, 0, [3]float64{0.1, 0.2, 0.3}, RPY)
+	abc, _ := PoseFromEuler(0, 0, 0, [3]float64{0.3, 0.2, 0.1}, ABC)
+	if !posesEqual(rpy, abc, 1e-12) {
+		t.Errorf("RPY should match ABC reversed. Got %v and %v", rpy, abc)
+	}
+
+	for _, order := range []string{"ZYZ", "XY", "XYW", ""} {
+		_, err := PoseFromEuler(0, 0, 0, angles, EulerConvention{Order: order})
+		if err != ErrInvalidEulerOrder {
+			t.Errorf("Order %q should be invalid", order)
+		}
+	}
+}
+
+func TestXyzWxyz_Euler(t *testing.T) {
+	// Every convention round trips, including middle angles near +/-pi/2
+	for _, order := range eulerOrders {
+		for _, extrinsic := range []bool{false, true} {
+			convention := EulerConvention{Order: order, Extrinsic: extrinsic}
+			for _, angles := range [][3]float64{{0.3, -0.7, 1.9}, {-2.5, 1.5, 0.4}, {3, -1.55, -3}, {0, 0, 0}} {
+				pose, _ := PoseFromEuler(0, 0, 0, angles, convention)
+				result, err := pose.Euler(convention)
+				if err != nil {
+					t.Fatalf("Euler %v failed with error: %s", convention, err)
+				}
+				if !vectorsEqual(result, angles, 1e-9) {
+					t.Errorf("%v: expected %v. Got %v", convention, angles, result)
+				}
+			}
+		}
+	}
+
+	// At gimbal lock, the first applied angle is 0 and the rotation is kept
+	pose, _ := PoseFromEuler(0, 0, 0, [3]float64{0.3, math.Pi / 2, 0.2}, ABC)
+	angles, _ := pose.Euler(ABC)
+	if angles[2] != 0 || math.Abs(angles[1]-math.Pi/2) > 1e-9 {
+		t.Errorf("Expected B of pi/2 and C of 0 at gimbal lock. Got %v", angles)
+	}
+	locked, _ := PoseFromEuler(0, 0, 0, angles, ABC)
+	if !posesEqual(locked, pose, 1e-9) {
+		t.Errorf("Gimbal lock angles %v should be the same rotation", angles)
+	}
+	pose, _ = PoseFromEuler(0, 0, 0, [3]float64{0.2, -math.Pi / 2, 0.3}, RPY)
+	angles, _ = pose.Euler(RPY)
+	if angles[0] != 0 || math.Abs(angles[1]+math.Pi/2) > 1e-9 {
+		t.Errorf("Expected roll of 0 and pitch of -pi/2 at gimbal lock. Got %v", angles)
+	}
+	locked, _ = PoseFromEuler(0, 0, 0, angles, RPY)
+	if !posesEqual(locked, pose, 1e-9) {
+		t.Errorf("Gimbal lock angles %v should be the same rotation", angles)
+	}
+}
+
+func TestXyzAbc(t *testing.T) {
+	abc := XyzAbc{X: 100, Y: -50, Z: 300, A: 0.4, B: -0.3, C: 2.9}
+	pose := abc.XyzWxyz()
+	if !matricesEqual(quaternionToRotation(pose.Qw, pose.Qx, pose.Qy, pose.Qz), eulerMatrix("ZYX", [3]float64{0.4, -0.3, 2.9}), 1e-12) {
+		t.Errorf("XyzAbc should be intrinsic ZYX. Got %v", pose)
+	}
+	result := pose.XyzAbc()
+	if result.X != abc.X || result.Y != abc.Y || result.Z != abc.Z || !vectorsEqual([3]float64{result.A, result.B, result.C}, [3]float64{abc.A, abc.B, abc.C}, 1e-9) {
+		t.Errorf("XyzAbc should round trip %v. Got %v", abc, result)
+	}
+}
@@ -3,6 +3,7 @@ package kinematics_test
 import (
 	"fmt"
 	"github.com/koeng101/armos/utils/kinematics"
+	"math"
 )
 
 func ExampleForwardKinematics() {
@@ -18,5 +19,34 @@ func ExampleInverseKinematics() {
 	angles, _ := kinematics.InverseKinematics(coordinates, kinematics.AR3DhParameters)
 
 	fmt.Println(angles)
-	// Output: {1.8462740950010432 0.3416721655970939 -2.313720459511564 -1.7765008677785283 2.2218097507147707 1.2318789996199948}
+	// Output: {1.8297201286870788 0.37150515466837514 -2.3636297217206366 -1.4469514433884594 2.0035309510178836 1.6102818089142548}
+}
+
+func ExampleAnalyticInverseKinematics() {
+	// This position can be reached with the shoulder facing towards or away
+	// from it, the elbow up or down, and the wrist flipped or not.
+	angles := kinematics.StepperTheta{0.3, -1.2, 1.5, 0.4, 0.6, -0.2}
+	coordinates := kinematics.ForwardKinematics(angles, kinematics.AR3DhParameters)
+	solutions, _ := kinematics.AnalyticInverseKinematics(coordinates, kinematics.AR3DhParameters)
+
+	fmt.Println(len(solutions))
+	// Output: 8
+}
+
+func ExampleChain_ForwardKinematics() {
+	// Move the AR3 250mm along its track, with all joints at 0.
+	coordinates := kinematics.AR3TrackChain.ForwardKinematics([]float64{250, 0, 0, 0, 0, 0, 0})
+
+	fmt.Printf("%.2f %.2f %.2f\n", coordinates.X, coordinates.Y, coordinates.Z)
+	// Output: 628.08 250.00 169.77
+}
+
+func ExampleXyzWxyz_Mul() {
+	// A gripper 100mm along x of the flange, with the flange rotated 90
+	// degrees around z, is 100mm along y of the base.
+	flange := kinematics.PoseFromAngleAxis(math.Pi/2, [3]float64{0, 0, 1})
+	gripper := flange.Mul(kinematics.XyzWxyz{X: 100, Qw: 1})
+
+	fmt.Printf("%.2f %.2f %.2f\n", gripper.X, gripper.Y, gripper.Z)
+	// Output: 0.00 100.00 0.00
 }
@@ -0,0 +1,50 @@
+package kinematics
+
+// Frames are the base and tool frame offsets of an arm. Base is the position of
+// the base of the arm in the world, and Tool is the position of the tool center
+// point (TCP) relative to the flange of the arm. A rotation of all zeros is
+// treated as no rotation, so the zero value of Frames has no offsets and poses
+// are those of the flange relative to the base of the arm.
+type Frames struct {
+	Base XyzWxyz
+	Tool XyzWxyz
+}
+
+// ToolPose converts a flange pose relative to the base of the arm into a tool
+// pose in the world.
+func (frames Frames) ToolPose(flange XyzWxyz) XyzWxyz {
+	return frames.Base.Mul(flange).Mul(frames.Tool)
+}
+
+// FlangePose converts a tool pose in the world into a flange pose relative to
+// the base of the arm, for use with inverse kinematics.
+func (frames Frames) FlangePose(tool XyzWxyz) XyzWxyz {
+	return frames.Base.Inverse().Mul(tool).Mul(frames.Tool.Inverse())
+}
+
+// ForwardKinematics calculates the tool pose in the world given joint angles.
+func (frames Frames) ForwardKinematics(thetas StepperTheta, dhParameters DhParameters) XyzWxyz {
+	return frames.ToolPose(ForwardKinematics(thetas, dhParameters))
+}
+
+// InverseKinematics calculates the joint angles nearest to the current joint
+// angles that move the tool to a pose in the world. It works like
+// NearestInverseKinematics.
+func (frames Frames) InverseKinematics(desiredTool XyzWxyz, dhParameters DhParameters, current StepperTheta, options NearestOptions) (StepperTheta, error) {
+	return NearestInverseKinematics(frames.FlangePose(desiredTool), dhParameters, current, options)
+}
+
+// FlangeTwist converts a twist of the tool in the world into a twist of the
+// flange relative to the base of the arm, for use with JointVelocities. The
+// flange pose is needed because rotating the tool also moves the flange.
+func (frames Frames) FlangeTwist(twist Twist, flange XyzWxyz) Twist {
+	base := frames.Base.Inverse()
+	linear := base.RotateVector(twist.Linear)
+	angular := base.RotateVector(twist.Angular)
+	tool := flange.RotateVector([3]float64{frames.Tool.X, frames.Tool.Y, frames.Tool.Z})
+	lever := cross3(angular, tool)
+	return Twist{
+		Linear:  [3]float64{linear[0] - lever[0], linear[1] - lever[1], linear[2] - lever[2]},
+		Angular: angular,
+	}
+}
@@ -0,0 +1,60 @@
+package kinematics
+
+import (
+	"math"
+	"testing"
+)
+
+var testFrames = Frames{
+	Base: XyzWxyz{X: 100, Y: -50, Z: 20, Qw: math.Cos(0.25), Qz: math.Sin(0.25)},
+	Tool: XyzWxyz{X: 10, Z: 120, Qw: 1},
+}
+
+func TestFrames(t *testing.T) {
+	thetas := StepperTheta{0.3, -1.2, 1.5, 0.4, 0.6, -0.2}
+	flange := ForwardKinematics(thetas, AR3DhParameters)
+
+	// With no offsets, the tool is the flange.
+	if !poseMatches(Frames{}.ToolPose(flange), flange) {
+		t.Errorf("Zero Frames should not move the pose")
+	}
+
+	// The tool should be 120mm out along the z axis of the flange.
+	tool := testFrames.ToolPose(flange)
+	local := Frames{Tool: testFrames.Tool}.ToolPose(flange)
+	r := quaternionToRotation(flange.Qw, flange.Qx, flange.Qy, flange.Qz)
+	if math.Abs(local.X-(flange.X+10*r[0][0]+120*r[0][2])) > 1e-9 || math.Abs(local.Z-(flange.Z+10*r[2][0]+120*r[2][2])) > 1e-9 {
+		t.Errorf("Tool offset not applied along the flange. Got %v from %v", local, flange)
+	}
+
+	// FlangePose undoes ToolPose
+	if !poseMatches(testFrames.FlangePose(tool), flange) {
+		t.Errorf("FlangePose(ToolPose(flange)) should be the flange. Got %v, expected %v", testFrames.FlangePose(tool), flange)
+	}
+
+	// Inverse kinematics reaches the tool pose
+	solution, err := testFrames.InverseKinematics(tool, AR3DhParameters, StepperTheta{0.25, -1.1, 1.4, 0.4, 0.6, -0.2}, NearestOptions{})
+	if err != nil {
+		t.Fatalf("Inverse kinematics with frames failed with error: %s", err)
+	}
+	if !poseMatches(testFrames.ForwardKinematics(solution, AR3DhParameters), tool) {
+		t.Errorf("Solution %v does not reach tool pose %v", solution, tool)
+	}
+}
+
+func TestFrames_FlangeTwist(t *testing.T) {
+	// Rotating about the tool center point should keep the tool in place.
+	thetas := StepperTheta{0.3, -1.2, 1.5, 0.4, 0.6, -0.2}
+	flange := ForwardKinematics(thetas, AR3DhParameters)
+	twist := testFrames.FlangeTwist(Twist{Angular: [3]float64{0, 0.1, 0}}, flange)
+	options := VelocityOptions{Damping: 0.0001}
+	rates := JointVelocities(twist, thetas, AR3DhParameters, options)
+	dt := 0.01
+	moved := StepperTheta{thetas.J1 + rates[0]*dt, thetas.J2 + rates[1]*dt, thetas.J3 + rates[2]*dt, thetas.J4 + rates[3]*dt, thetas.J5 + rates[4]*dt, thetas.J6 + rates[5]*dt}
+	before := testFrames.ForwardKinematics(thetas, AR3DhParameters)
+	after := testFrames.ForwardKinematics(moved, AR3DhParameters)
+	distance := math.Sqrt(math.Pow(after.X-before.X, 2) + math.Pow(after.Y-before.Y, 2) + math.Pow(after.Z-before.Z, 2))
+	if distance > 0.01 {
+		t.Errorf("Tool center point moved %fmm while rotating about it", distance)
+	}
+}
@@ -0,0 +1,62 @@
+package kinematics
+
+import (
+	"gonum.org/v1/gonum/mat"
+	"math"
+)
+
+// SingularityThreshold is the condition number above which joint angles are
+// considered near a singularity. Well conditioned AR3 poses have a condition
+// number below 10, while poses within a degree of a singularity have a
+// condition number of several hundred.
+var SingularityThreshold = 100.0
+
+// Jacobian calculates the geometric Jacobian of an arm at the given joint
+// angles. It is a 6x6 matrix that maps joint velocities in radians per second
+// onto the linear (rows 0 to 2, in millimeters per second) and angular (rows 3
+// to 5, in radians per second) velocity of the end effector, relative to the
+// base of the arm.
+func Jacobian(thetas StepperTheta, dhParameters DhParameters) *mat.Dense {
+	return dhParameters.Chain().Jacobian(thetas.toFloat())
+}
+
+// Manipulability calculates the Yoshikawa manipulability of an arm at the
+// given joint angles, which is the volume of the velocity ellipsoid of the end
+// effector. It is 0 at a singularity.
+func Manipulability(thetas StepperTheta, dhParameters DhParameters) float64 {
+	return math.Abs(mat.Det(Jacobian(thetas, dhParameters)))
+}
+
+// ConditionNumber calculates the condition number of the Jacobian of an arm at
+// the given joint angles. It is 1 for a perfectly conditioned pose and goes to
+// infinity at a singularity. The linear rows of the Jacobian are divided by the
+// length of the arm first, so that millimeters and radians are comparable.
+func ConditionNumber(thetas StepperTheta, dhParameters DhParameters) float64 {
+	jacobian := Jacobian(thetas, dhParameters)
+	length := armLength(dhParameters)
+	for i := 0; i < 3; i++ {
+		for j := 0; j < 6; j++ {
+			jacobian.Set(i, j, jacobian.At(i, j)/length)
+		}
+	}
+	return mat.Cond(jacobian, 2)
+}
+
+// IsSingular checks if joint angles are near a singularity, meaning that their
+// condition number is above SingularityThreshold.
+func IsSingular(thetas StepperTheta, dhParameters DhParameters) bool {
+	return ConditionNumber(thetas, dhParameters) > SingularityThreshold
+}
+
+// armLength is the sum of the link lengths and offsets of an arm, used as a
+// characteristic length to scale the Jacobian.
+func armLength(dhParameters DhParameters) float64 {
+	length := 0.0
+	for i := 0; i < 6; i++ {
+		length = length + math.Abs(dhParameters.AValues[i]) + math.Abs(dhParameters.DValues[i])
+	}
+	if length == 0 {
+		return 1
+	}
+	return length
+}
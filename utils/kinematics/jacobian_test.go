@@ -0,0 +1,51 @@
+package kinematics
+
+import (
+	"math"
+	"testing"
+)
+
+func TestJacobian(t *testing.T) {
+	// Compare the linear rows of the Jacobian against finite differences of
+	// the forward kinematics.
+	thetas := StepperTheta{0.3, -1.2, 1.5, 0.4, 0.6, -0.2}
+	jacobian := Jacobian(thetas, AR3DhParameters)
+	start := ForwardKinematics(thetas, AR3DhParameters)
+	h := 1e-7
+	for i := 0; i < 6; i++ {
+		moved := thetas.toFloat()
+		moved[i] = moved[i] + h
+		end := ForwardKinematics(StepperTheta{moved[0], moved[1], moved[2], moved[3], moved[4], moved[5]}, AR3DhParameters)
+		for row, difference := range []float64{(end.X - start.X) / h, (end.Y - start.Y) / h, (end.Z - start.Z) / h} {
+			if math.Abs(jacobian.At(row, i)-difference) > 1e-3 {
+				t.Errorf("Jacobian at (%d, %d) is %f. Expected %f", row, i, jacobian.At(row, i), difference)
+			}
+		}
+	}
+
+	// Joint 1 rotates about the z axis of the base
+	if jacobian.At(3, 0) != 0 || jacobian.At(4, 0) != 0 || jacobian.At(5, 0) != 1 {
+		t.Errorf("Expected joint 1 to rotate about the z axis")
+	}
+}
+
+func TestIsSingular(t *testing.T) {
+	thetas := StepperTheta{0.3, -1.2, 1.5, 0.4, 0.6, -0.2}
+	if IsSingular(thetas, AR3DhParameters) {
+		t.Errorf("Expected %v not to be singular. Condition number %f", thetas, ConditionNumber(thetas, AR3DhParameters))
+	}
+
+	// Wrist singularity and elbow singularity
+	singularities := []StepperTheta{
+		{0.3, -1.2, 1.5, 0.4, 0, -0.2},
+		{0.3, -1.2, 0, 0.4, 0.6, -0.2},
+	}
+	for _, singularity := range singularities {
+		if !IsSingular(singularity, AR3DhParameters) {
+			t.Errorf("Expected %v to be singular. Condition number %f", singularity, ConditionNumber(singularity, AR3DhParameters))
+		}
+		if Manipulability(singularity, AR3DhParameters) > 1e-6 {
+			t.Errorf("Expected %v to have no manipulability. Got %f", singularity, Manipulability(singularity, AR3DhParameters))
+		}
+	}
+}
@@ -9,15 +9,58 @@ angles that move the end effector to that coordinate.
 ForwardKinematics (joint angles	-> xyzwxyz)
 
 InverseKinematics (xyzwxyz	-> joint angles)
+
+InverseKinematicsFromSeed (xyzwxyz + nearby joint angles	-> joint angles)
+
+AnalyticInverseKinematics (xyzwxyz	-> every set of joint angles)
+
+InverseKinematicsWithinLimits (xyzwxyz + joint limits	-> joint angles within limits)
+
+NearestInverseKinematics (xyzwxyz + current joint angles	-> nearest joint angles)
+
+InverseKinematicsWithOptions (xyzwxyz + seed + solver options	-> joint angles + diagnostics)
+
+Jacobian (joint angles	-> joint to end effector velocity matrix)
+
+JointVelocities (end effector twist + joint angles	-> joint velocities)
+
+PlanCartesianPath (xyzwxyz waypoints + start joint angles	-> joint angles along straight lines)
+
+BuildWorkspace (joint limits	-> reachability and dexterity map)
+
+CollisionModel.CheckSelfCollision (joint angles	-> whether links of the arm collide)
+
+CollisionModel.CheckCollision (joint angles + obstacles	-> whether the arm hits an obstacle)
+
+ParseURDF (URDF file	-> Chain, DhParameters, joint limits and collision geometry)
+
+Calibrate (joint angles + measured tool positions	-> fitted DhParameters)
+
+InverseKinematics is a numeric solver that works for any DhParameters, but only
+returns one of the possible solutions. Arms with a spherical wrist, like the
+AR3, can instead use AnalyticInverseKinematics, which is exact and returns
+every solution.
+
+The functions above work on 6 joint arms described by DhParameters. Arms with
+any number of revolute and prismatic joints, like an AR3 on a linear track or
+a SCARA, are described by a Chain, which has its own ForwardKinematics,
+InverseKinematics and Jacobian. DhParameters can be converted into a Chain.
+
+Arms documented with modified (Craig) DH parameters can be described with a
+ModifiedChain, and arms documented with screw axes can be described with a
+POEModel (product of exponentials). Chain, ModifiedChain and POEModel can all be
+converted into each other.
+
+XyzWxyz poses can be composed with Mul, inverted with Inverse, interpolated
+with Slerp, and converted to and from 4x4 matrices and angle-axis rotations.
+They can also be converted to and from Euler angles, like the x,y,z,a,b,c form
+XyzAbc used by ARCS.
 */
 package kinematics
 
 import (
-	"errors"
 	"gonum.org/v1/gonum/mat"
-	"gonum.org/v1/gonum/optimize"
 	"math"
-	"math/rand"
 )
 
 // DhParameters stand for "Denavit-Hartenberg Parameters". These parameters
@@ -56,131 +99,51 @@ type XyzWxyz struct {
 }
 
 // ForwardKinematics calculates the end effector XyzWxyz coordinates given
-// joint angles and robotic arm parameters.
+// joint angles and robotic arm parameters. It does not allocate.
 func ForwardKinematics(thetas StepperTheta, dhParameters DhParameters) XyzWxyz {
-	// First, setup variables. We use 4 variables - theta, alpha, a and d to calculate a matrix
-	// which is then multiplied to an accumulator matrix.
-	thetaArray := []float64{thetas.J1, thetas.J2, thetas.J3, thetas.J4, thetas.J5, thetas.J6}
-	var theta float64
-	var alpha float64
-	var a float64
-	var d float64
-	// Setup accumulator matrix - an identity matrix.
-	accumulatortMat := mat.NewDense(4, 4, []float64{1, 0, 0, 0,
-		0, 1, 0, 0,
-		0, 0, 1, 0,
-		0, 0, 0, 1,
-	})
-	// Iterate through each joint and built a new
-	// matrix, multiplying it against the accumulator.
-	for jointIdx := 0; jointIdx < 6; jointIdx++ {
-		theta = thetaArray[jointIdx]
-		theta = theta + dhParameters.ThetaOffsets[jointIdx]
-		alpha = dhParameters.AlphaValues[jointIdx]
-		a = dhParameters.AValues[jointIdx]
-		d = dhParameters.DValues[jointIdx]
-		tMat := mat.NewDense(4, 4, []float64{
-			// First row
-			math.Cos(theta),
-			-math.Sin(theta) * math.Cos(alpha),
-			math.Sin(theta) * math.Sin(alpha),
-			a * math.Cos(theta),
-			// Second row
-			math.Sin(theta),
-			math.Cos(theta) * math.Cos(alpha),
-			-math.Cos(theta) * math.Sin(alpha),
-			a * math.Sin(theta),
-			// Third row
-			0,
-			math.Sin(alpha),
-			math.Cos(alpha),
-			d,
-			// Forth row
-			0,
-			0,
-			0,
-			1,
-		})
-		// Multiply tMat against accumulatortMat
-		x := mat.NewDense(4, 4, nil)
-		x.Mul(accumulatortMat, tMat)
-		accumulatortMat = x
+	joints := [6]float64{thetas.J1, thetas.J2, thetas.J3, thetas.J4, thetas.J5, thetas.J6}
+	frame := identityFrame4()
+	for i, joint := range joints {
+		frame.mulJoint(&dhParameters, i, joint)
 	}
-
-	// Now that we have the final accumulatorMatrix, lets figure out the euler angles.
-	var output XyzWxyz
-	output.X = accumulatortMat.At(0, 3)
-	output.Y = accumulatortMat.At(1, 3)
-	output.Z = accumulatortMat.At(2, 3)
-	output.Qw, output.Qx, output.Qy, output.Qz = matrixToQuaterian(accumulatortMat)
-	return output
+	return frame.pose()
 }
 
 // InverseKinematics calculates joint angles to achieve an XyzWxyz end effector
 // position given the desired XyzWxyz coordinates and the robotic arm
 // parameters.
 func InverseKinematics(desiredEndEffector XyzWxyz, dhParameters DhParameters) (StepperTheta, error) {
-	thetasInit := StepperTheta{0, 0, 0, 0, 0, 0}
-	// Initialize an objective function for the optimization problem
-	objectiveFunction := func(s []float64) float64 {
-		stepperThetaTest := StepperTheta{s[0], s[1], s[2], s[3], s[4], s[5]}
-		currentEndEffector := ForwardKinematics(stepperThetaTest, dhParameters)
-
-		// Get XYZ offsets
-		xOffset := desiredEndEffector.X - currentEndEffector.X
-		yOffset := desiredEndEffector.Y - currentEndEffector.Y
-		zOffset := desiredEndEffector.Z - currentEndEffector.Z
-
-		// Get rotational offsets. Essentially, do this in Golang (from python): np.arccos(np.clip(2*(np.dot(target_quat, source_quat)**2) - 1, -1, 1))
-		dotOffset := (desiredEndEffector.Qw * currentEndEffector.Qw) + (desiredEndEffector.Qx * currentEndEffector.Qx) + (desiredEndEffector.Qy * currentEndEffector.Qy) + (desiredEndEffector.Qz * currentEndEffector.Qz)
-		dotOffset = (2*(dotOffset*dotOffset) - 1)
-		if dotOffset > 1 {
-			dotOffset = 1
-		}
-		rotationalOffset := math.Acos(dotOffset)
-
-		// Get the error vector
-		errorVector := ((xOffset * xOffset) + (yOffset * yOffset) + (zOffset * zOffset) + (rotationalOffset * rotationalOffset)) * 0.25
-
-		return errorVector
-	}
-	// Setup problem and method for solving
-	problem := optimize.Problem{Func: objectiveFunction}
+	return InverseKinematicsFromSeed(desiredEndEffector, dhParameters, StepperTheta{0, 0, 0, 0, 0, 0})
+}
 
-	// Solve
-	result, err := optimize.Minimize(problem, thetasInit.toFloat(), nil, nil)
+// InverseKinematicsFromSeed calculates joint angles like InverseKinematics,
+// but starts the optimization from the given seed instead of from zero. Seeding
+// with the current joint angles makes it likely that the solution is close to
+// the current joint angles, which is useful for consecutive small moves.
+func InverseKinematicsFromSeed(desiredEndEffector XyzWxyz, dhParameters DhParameters, seed StepperTheta) (StepperTheta, error) {
+	result, err := InverseKinematicsWithOptions(desiredEndEffector, dhParameters, seed, DefaultIKOptions)
 	if err != nil {
 		return StepperTheta{}, err
 	}
-	f := result.Location.F
-
-	// If the results aren't up to spec, queue up another theta seed and test again.
-	// We arbitrarily choose 10e-6 because that is small enough that the errors do not matter.
-	for i := 0; f > 0.000001; i++ {
-		// Get a random seed
-		randTheta := func() float64 {
-			return 360 * rand.Float64()
-		}
-		randomSeed := StepperTheta{randTheta(), randTheta(), randTheta(), randTheta(), randTheta(), randTheta()}
-
-		// Solve
-		result, err := optimize.Minimize(problem, randomSeed.toFloat(), nil, nil)
-		if err != nil {
-			return StepperTheta{}, err
-		}
-		f = result.Location.F
-		if i == 100 {
-			return StepperTheta{}, errors.New("Failed to iterate 100 times to succes")
-		}
-	}
-	r := result.Location.X
-	return StepperTheta{r[0], r[1], r[2], r[3], r[4], r[5]}, nil
+	return result.Theta, nil
 }
 
 // matrixToQuaterian converts a rotation matrix to a quaterian. This code has
 // been tested in all cases vs the python implementation with scipy rotation
 // and works properly.
-func matrixToQuaterian(accumulatortMat *mat.Dense) (float64, float64, float64, float64) {
+func matrixToQuaterian(accumulatortMat mat.Matrix) (float64, float64, float64, float64) {
+	var r [3][3]float64
+	for i := range r {
+		for j := range r[i] {
+			r[i][j] = accumulatortMat.At(i, j)
+		}
+	}
+	return rotationToQuaternion(r)
+}
+
+// rotationToQuaternion converts a rotation matrix to a quaternion, returned as
+// qw, qx, qy, qz.
+func rotationToQuaternion(r [3][3]float64) (float64, float64, float64, float64) {
 	// http://www.euclideanspace.com/maths/geometry/rotations/conversions/matrixToQuaternion/
 	var qw float64
 	var qx float64
@@ -188,31 +151,31 @@ func matrixToQuaterian(accumulatortMat *mat.Dense) (float64, float64, float64, f
 	var qz float64
 	var tr float64
 	var s float64
-	tr = accumulatortMat.At(0, 0) + accumulatortMat.At(1, 1) + accumulatortMat.At(2, 2)
+	tr = r[0][0] + r[1][1] + r[2][2]
 	switch {
 	case tr > 0:
 		s = math.Sqrt(tr+1.0) * 2
 		qw = 0.25 * s
-		qx = (accumulatortMat.At(2, 1) - accumulatortMat.At(1, 2)) / s
-		qy = (accumulatortMat.At(0, 2) - accumulatortMat.At(2, 0)) / s
-		qz = (accumulatortMat.At(1, 0) - accumulatortMat.At(0, 1)) / s
-	case accumulatortMat.At(0, 0) > accumulatortMat.At(1, 1) && accumulatortMat.At(0, 0) > accumulatortMat.At(2, 2):
-		s = math.Sqrt(1.0+accumulatortMat.At(0, 0)-accumulatortMat.At(1, 1)-accumulatortMat.At(2, 2)) * 2
-		qw = (accumulatortMat.At(2, 1) - accumulatortMat.At(1, 2)) / s
+		qx = (r[2][1] - r[1][2]) / s
+		qy = (r[0][2] - r[2][0]) / s
+		qz = (r[1][0] - r[0][1]) / s
+	case r[0][0] > r[1][1] && r[0][0] > r[2][2]:
+		s = math.Sqrt(1.0+r[0][0]-r[1][1]-r[2][2]) * 2
+		qw = (r[2][1] - r[1][2]) / s
 		qx = 0.25 * s
-		qy = (accumulatortMat.At(0, 1) + accumulatortMat.At(1, 0)) / s
-		qz = (accumulatortMat.At(0, 2) + accumulatortMat.At(2, 0)) / s
-	case accumulatortMat.At(1, 1) > accumulatortMat.At(2, 2):
-		s = math.Sqrt(1.0+accumulatortMat.At(1, 1)-accumulatortMat.At(0, 0)-accumulatortMat.At(2, 2)) * 2
-		qw = (accumulatortMat.At(0, 2) - accumulatortMat.At(2, 0)) / s
-		qx = (accumulatortMat.At(0, 1) + accumulatortMat.At(1, 0)) / s
+		qy = (r[0][1] + r[1][0]) / s
+		qz = (r[0][2] + r[2][0]) / s
+	case r[1][1] > r[2][2]:
+		s = math.Sqrt(1.0+r[1][1]-r[0][0]-r[2][2]) * 2
+		qw = (r[0][2] - r[2][0]) / s
+		qx = (r[0][1] + r[1][0]) / s
 		qy = 0.25 * s
-		qz = (accumulatortMat.At(2, 1) + accumulatortMat.At(1, 2)) / s
+		qz = (r[2][1] + r[1][2]) / s
 	default:
-		s = math.Sqrt(1.0+accumulatortMat.At(2, 2)-accumulatortMat.At(0, 0)-accumulatortMat.At(1, 1)) * 2
-		qw = (accumulatortMat.At(0, 1) - accumulatortMat.At(1, 0))
-		qx = (accumulatortMat.At(0, 2) + accumulatortMat.At(2, 0)) / s
-		qy = (accumulatortMat.At(2, 1) + accumulatortMat.At(1, 2)) / s
+		s = math.Sqrt(1.0+r[2][2]-r[0][0]-r[1][1]) * 2
+		qw = (r[1][0] - r[0][1]) / s
+		qx = (r[0][2] + r[2][0]) / s
+		qy = (r[2][1] + r[1][2]) / s
 		qz = 0.25 * s
 	}
 	return qw, qx, qy, qz
@@ -128,3 +128,21 @@ func BenchmarkInverseKinematics(b *testing.B) {
 		}
 	}
 }
+
+func TestForwardKinematics_Allocations(t *testing.T) {
+	thetas := StepperTheta{0.3, -1.2, 1.5, 0.4, 0.6, -0.2}
+	allocations := testing.AllocsPerRun(100, func() {
+		_ = ForwardKinematics(thetas, AR3DhParameters)
+	})
+	if allocations != 0 {
+		t.Errorf("Expected ForwardKinematics to not allocate. Got %f allocations", allocations)
+	}
+}
+
+func BenchmarkForwardKinematics(b *testing.B) {
+	thetas := StepperTheta{0.3, -1.2, 1.5, 0.4, 0.6, -0.2}
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		_ = ForwardKinematics(thetas, AR3DhParameters)
+	}
+}
@@ -0,0 +1,122 @@
+package kinematics
+
+import (
+	"errors"
+	"fmt"
+	"math"
+)
+
+// ErrNoSolutionWithinLimits is returned when a pose can be reached, but not
+// without moving a joint outside of its limits.
+var ErrNoSolutionWithinLimits = errors.New("No inverse kinematics solution within joint limits")
+
+// JointLimits are the minimum and maximum angle of each joint, in radians.
+type JointLimits struct {
+	Min StepperTheta
+	Max StepperTheta
+}
+
+// Contains checks if all joint angles are within the limits.
+func (limits JointLimits) Contains(thetas StepperTheta) bool {
+	minimums := limits.Min.toFloat()
+	maximums := limits.Max.toFloat()
+	for i, theta := range thetas.toFloat() {
+		if theta < minimums[i] || theta > maximums[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// Wrap adds or removes full turns from each joint to bring it within the
+// limits. If there is more than one way to do so, the angle closest to the
+// middle of the joint's range is used. It returns false if a joint cannot be
+// brought within its limits.
+func (limits JointLimits) Wrap(thetas StepperTheta) (StepperTheta, bool) {
+	minimums := limits.Min.toFloat()
+	maximums := limits.Max.toFloat()
+	wrapped := thetas.toFloat()
+	for i, theta := range wrapped {
+		middle := (minimums[i] + maximums[i]) / 2
+		theta = theta - 2*math.Pi*math.Round((theta-middle)/(2*math.Pi))
+		if theta < minimums[i] || theta > maximums[i] {
+			return thetas, false
+		}
+		wrapped[i] = theta
+	}
+	return StepperTheta{wrapped[0], wrapped[1], wrapped[2], wrapped[3], wrapped[4], wrapped[5]}, true
+}
+
+// sample returns random joint angles within the limits.
+func (limits JointLimits) sample(randFloat func() float64) StepperTheta {
+	minimums, maximums := limits.Min.toFloat(), limits.Max.toFloat()
+	var thetas StepperTheta
+	for i, theta := range []*float64{&thetas.J1, &thetas.J2, &thetas.J3, &thetas.J4, &thetas.J5, &thetas.J6} {
+		*theta = minimums[i] + (maximums[i]-minimums[i])*randFloat()
+	}
+	return thetas
+}
+
+// InverseKinematicsWithinLimits calculates joint angles that reach an XyzWxyz
+// end effector position without moving any joint outside of its limits.
+//
+// Arms with a closed-form solution return every solution within the limits.
+// Other arms use the numeric solver with DefaultIKOptions, seeded with random
+// angles within the limits, and return the first solution found within the
+// limits. If there is no solution within the limits, ErrNoSolutionWithinLimits
+// is returned.
+func InverseKinematicsWithinLimits(desiredEndEffector XyzWxyz, dhParameters DhParameters, limits JointLimits) ([]StepperTheta, error) {
+	return InverseKinematicsWithinLimitsWithOptions(desiredEndEffector, dhParameters, limits, DefaultIKOptions)
+}
+
+// InverseKinematicsWithinLimitsWithOptions calculates joint angles like
+// InverseKinematicsWithinLimits, but with configurable options for the numeric
+// solver. The options are not used by arms with a closed-form solution.
+func InverseKinematicsWithinLimitsWithOptions(desiredEndEffector XyzWxyz, dhParameters DhParameters, limits JointLimits, options IKOptions) ([]StepperTheta, error) {
+	minimums := limits.Min.toFloat()
+	maximums := limits.Max.toFloat()
+	var seed StepperTheta
+	for i, theta := range []*float64{&seed.J1, &seed.J2, &seed.J3, &seed.J4, &seed.J5, &seed.J6} {
+		*theta = (minimums[i] + maximums[i]) / 2
+	}
+	return inverseKinematicsWithinLimitsFromSeed(desiredEndEffector, dhParameters, limits, seed, options)
+}
+
+// inverseKinematicsWithinLimitsFromSeed calculates joint angles like
+// InverseKinematicsWithinLimitsWithOptions, but starts the numeric solver
+// from a seed instead of the middle of the limits.
+func inverseKinematicsWithinLimitsFromSeed(desiredEndEffector XyzWxyz, dhParameters DhParameters, limits JointLimits, seed StepperTheta, options IKOptions) ([]StepperTheta, error) {
+	solutions, err := AnalyticInverseKinematics(desiredEndEffector, dhParameters)
+	switch {
+	case err == nil:
+		var withinLimits []StepperTheta
+		for _, solution := range solutions {
+			wrapped, ok := limits.Wrap(solution)
+			if ok {
+				withinLimits = append(withinLimits, wrapped)
+			}
+		}
+		if len(withinLimits) == 0 {
+			return nil, ErrNoSolutionWithinLimits
+		}
+		return withinLimits, nil
+	case err != ErrNoAnalyticSolution:
+		return nil, err
+	}
+
+	// Fall back to the numeric solver, with the limits as the range of each
+	// link.
+	chain := dhParameters.Chain()
+	minimums := limits.Min.toFloat()
+	maximums := limits.Max.toFloat()
+	for i := range chain {
+		chain[i].Min, chain[i].Max = minimums[i], maximums[i]
+	}
+	seedAngles := seed.toFloat()
+	result, err := chain.InverseKinematics(desiredEndEffector, seedAngles[:], options)
+	if err != nil {
+		return nil, fmt.Errorf("%w. %s", ErrNoSolutionWithinLimits, err)
+	}
+	r := result.Joints
+	return []StepperTheta{{r[0], r[1], r[2], r[3], r[4], r[5]}}, nil
+}
@@ -0,0 +1,102 @@
+package kinematics
+
+import (
+	"errors"
+	"math"
+	"math/rand"
+	"testing"
+)
+
+func TestJointLimits_Wrap(t *testing.T) {
+	limits := JointLimits{
+		Min: StepperTheta{-math.Pi, -math.Pi, 0, -math.Pi, -math.Pi, -math.Pi},
+		Max: StepperTheta{math.Pi, math.Pi, 2 * math.Pi, math.Pi, math.Pi, math.Pi},
+	}
+	wrapped, ok := limits.Wrap(StepperTheta{0, 0, -1, 0, 0, 0})
+	if !ok {
+		t.Fatalf("Failed to wrap joint angles into limits")
+	}
+	if math.Abs(wrapped.J3-(2*math.Pi-1)) > 1e-9 {
+		t.Errorf("Expected J3 to be wrapped to %f. Got %f", 2*math.Pi-1, wrapped.J3)
+	}
+	if !limits.Contains(wrapped) {
+		t.Errorf("Wrapped joint angles %v are not within limits", wrapped)
+	}
+
+	limits.Max.J1 = 0.5
+	limits.Min.J1 = -0.5
+	_, ok = limits.Wrap(StepperTheta{1, 0, 1, 0, 0, 0})
+	if ok {
+		t.Errorf("J1 should not have been wrapped into limits")
+	}
+}
+
+func TestInverseKinematicsWithinLimits(t *testing.T) {
+	thetas := StepperTheta{0.3, -1.2, 1.5, 0.4, 0.6, -0.2}
+	desiredEndEffector := ForwardKinematics(thetas, AR3DhParameters)
+	all, err := AnalyticInverseKinematics(desiredEndEffector, AR3DhParameters)
+	if err != nil {
+		t.Fatalf("Analytic inverse kinematics failed with error: %s", err)
+	}
+	solutions, err := InverseKinematicsWithinLimits(desiredEndEffector, AR3DhParameters, AR3JointLimits)
+	if err != nil {
+		t.Fatalf("Inverse kinematics within limits failed with error: %s", err)
+	}
+	if len(solutions) == 0 || len(solutions) >= len(all) {
+		t.Errorf("Expected joint limits to remove some of %d solutions. Got %d", len(all), len(solutions))
+	}
+	for _, solution := range solutions {
+		if !AR3JointLimits.Contains(solution) {
+			t.Errorf("Solution %v is not within limits", solution)
+		}
+		if !poseMatches(ForwardKinematics(solution, AR3DhParameters), desiredEndEffector) {
+			t.Errorf("Solution %v does not reach %v", solution, desiredEndEffector)
+		}
+	}
+}
+
+func TestInverseKinematicsWithinLimits_NoSolution(t *testing.T) {
+	// J2 of the AR3 only moves between -129.6 and 0 degrees, so a pose
+	// reached by leaning the other way has no solution within limits.
+	thetas := StepperTheta{0, 1.2, 0.2, 0, 0.5, 0}
+	desiredEndEffector := ForwardKinematics(thetas, AR3DhParameters)
+	_, err := InverseKinematicsWithinLimits(desiredEndEffector, AR3DhParameters, AR3JointLimits)
+	if !errors.Is(err, ErrNoSolutionWithinLimits) {
+		t.Errorf("Expected ErrNoSolutionWithinLimits. Got %v", err)
+	}
+}
+
+func TestInverseKinematicsWithinLimits_Numeric(t *testing.T) {
+	dhParameters := AR3DhParameters
+	dhParameters.AValues[4] = 10
+	thetas := StepperTheta{0.3, -1.2, 1.5, 0.4, 0.6, -0.2}
+	desiredEndEffector := ForwardKinematics(thetas, dhParameters)
+	solutions, err := InverseKinematicsWithinLimits(desiredEndEffector, dhParameters, AR3JointLimits)
+	if err != nil {
+		t.Fatalf("Inverse kinematics within limits failed with error: %s", err)
+	}
+	if !AR3JointLimits.Contains(solutions[0]) {
+		t.Errorf("Solution %v is not within limits", solutions[0])
+	}
+
+	// The numeric solver takes options, and is deterministic with a Rand
+	options := DefaultIKOptions
+	options.Rand = rand.NewSource(1)
+	first, err := InverseKinematicsWithinLimitsWithOptions(desiredEndEffector, dhParameters, AR3JointLimits, options)
+	if err != nil {
+		t.Fatalf("Inverse kinematics within limits failed with error: %s", err)
+	}
+	options.Rand = rand.NewSource(1)
+	second, _ := InverseKinematicsWithinLimitsWithOptions(desiredEndEffector, dhParameters, AR3JointLimits, options)
+	if first[0] != second[0] || !AR3JointLimits.Contains(first[0]) {
+		t.Errorf("Expected the same solution within limits twice. Got %v and %v", first, second)
+	}
+
+	// A pose outside of the limits fails within the restarts of the options
+	desiredEndEffector = ForwardKinematics(StepperTheta{0, 1.2, 0.2, 0, 0.5, 0}, dhParameters)
+	options.MaxRestarts = 5
+	_, err = InverseKinematicsWithinLimitsWithOptions(desiredEndEffector, dhParameters, AR3JointLimits, options)
+	if !errors.Is(err, ErrNoSolutionWithinLimits) {
+		t.Errorf("Expected ErrNoSolutionWithinLimits. Got %v", err)
+	}
+}
@@ -0,0 +1,327 @@
+package kinematics
+
+import (
+	"math"
+)
+
+// ModifiedLink is a single link of a ModifiedChain described by modified (Craig)
+// DH parameters. Unlike standard DH parameters, Alpha and A are the twist and
+// length of the previous link (alpha i-1 and a i-1), and are applied before the
+// joint moves. Type, Min and Max are the same as in Link.
+type ModifiedLink struct {
+	Type  JointType
+	Alpha float64
+	A     float64
+	Theta float64
+	D     float64
+	Min   float64
+	Max   float64
+}
+
+// ModifiedChain is a serial kinematic chain described by modified DH
+// parameters. Joint positions are passed the same way as for a Chain.
+type ModifiedChain []ModifiedLink
+
+// Screw is a joint axis of a POEModel in the base frame of the arm. Revolute
+// joints have a unit Omega along their axis and V = -Omega x q for a point q on
+// their axis. Prismatic joints have a zero Omega and a unit V along their axis.
+type Screw struct {
+	Omega [3]float64
+	V     [3]float64
+}
+
+// POEModel describes a serial arm with the product of exponentials formula.
+// Screws are the joint axes in the base frame with all joints at 0, and Home is
+// the end effector position with all joints at 0.
+type POEModel struct {
+	Screws []Screw
+	Home   XyzWxyz
+}
+
+// transform is a rigid transform with a rotation and a translation.
+type transform struct {
+	r [3][3]float64
+	p [3]float64
+}
+
+func identityTransform() transform {
+	return transform{r: identity3()}
+}
+
+func (a transform) mul(b transform) transform {
+	p := mulVec3(a.r, b.p)
+	return transform{r: mulMat3(a.r, b.r), p: [3]float64{p[0] + a.p[0], p[1] + a.p[1], p[2] + a.p[2]}}
+}
+
+// xyzWxyz converts a transform into an XyzWxyz.
+func (a transform) xyzWxyz() XyzWxyz {
+	var pose XyzWxyz
+	pose.X, pose.Y, pose.Z = a.p[0], a.p[1], a.p[2]
+	pose.Qw, pose.Qx, pose.Qy, pose.Qz = rotationToQuaternion(a.r)
+	return pose
+}
+
+// transformOf converts an XyzWxyz into a transform.
+func transformOf(pose XyzWxyz) transform {
+	return transform{r: quaternionToRotation(pose.Qw, pose.Qx, pose.Qy, pose.Qz), p: [3]float64{pose.X, pose.Y, pose.Z}}
+}
+
+// standardTransform is the transform of a standard DH link.
+func standardTransform(theta, alpha, a, d float64) transform {
+	return transform{r: dhRotation(theta, alpha), p: [3]float64{a * math.Cos(theta), a * math.Sin(theta), d}}
+}
+
+// modifiedTransform is the transform of a modified DH link.
+func modifiedTransform(alpha, a, theta, d float64) transform {
+	return transform{r: mulMat3(rotationX(alpha), rotationZ(theta)), p: [3]float64{a, -math.Sin(alpha) * d, math.Cos(alpha) * d}}
+}
+
+// DOF returns the number of moving joints of a ModifiedChain.
+func (chain ModifiedChain) DOF() int {
+	dof := 0
+	for _, link := range chain {
+		if link.Type != Fixed {
+			dof++
+		}
+	}
+	return dof
+}
+
+// ForwardKinematics calculates the end effector XyzWxyz coordinates of a
+// ModifiedChain given its joint positions. ForwardKinematics panics with
+// ErrJointCount if len(joints) is not chain.DOF().
+func (chain ModifiedChain) ForwardKinematics(joints []float64) XyzWxyz {
+	if len(joints) != chain.DOF() {
+		panic(ErrJointCount)
+	}
+	accumulator := identityTransform()
+	joint := 0
+	for _, link := range chain {
+		theta, d := link.Theta, link.D
+		switch link.Type {
+		case Revolute:
+			theta = theta + joints[joint]
+			joint++
+		case Prismatic:
+			d = d + joints[joint]
+			joint++
+		}
+		accumulator = accumulator.mul(modifiedTransform(link.Alpha, link.A, theta, d))
+	}
+	return accumulator.xyzWxyz()
+}
+
+// Modified converts a Chain into modified DH parameters. Standard DH links end
+// with a twist and length that modified DH links start with, so the twist and
+// length of the last link become an extra Fixed link at the end.
+func (chain Chain) Modified() ModifiedChain {
+	var modified ModifiedChain
+	var alpha, a float64
+	for _, link := range chain {
+		modified = append(modified, ModifiedLink{Type: link.Type, Alpha: alpha, A: a, Theta: link.Theta, D: link.D, Min: link.Min, Max: link.Max})
+		alpha, a = link.Alpha, link.A
+	}
+	if alpha != 0 || a != 0 {
+		modified = append(modified, ModifiedLink{Type: Fixed, Alpha: alpha, A: a})
+	}
+	return modified
+}
+
+// Chain converts a ModifiedChain into standard DH parameters. The twist and
+// length of the first link become an extra Fixed link at the start.
+func (chain ModifiedChain) Chain() Chain {
+	var standard Chain
+	if len(chain) > 0 && (chain[0].Alpha != 0 || chain[0].A != 0) {
+		standard = append(standard, Link{Type: Fixed, Alpha: chain[0].Alpha, A: chain[0].A})
+	}
+	for i, link := range chain {
+		var alpha, a float64
+		if i+1 < len(chain) {
+			alpha, a = chain[i+1].Alpha, chain[i+1].A
+		}
+		standard = append(standard, Link{Type: link.Type, Theta: link.Theta, Alpha: alpha, A: a, D: link.D, Min: link.Min, Max: link.Max})
+	}
+	return standard
+}
+
+// POE converts a Chain into a product of exponentials model.
+func (chain Chain) POE() POEModel {
+	var model POEModel
+	frame := identityTransform()
+	for _, link := range chain {
+		z := [3]float64{frame.r[0][2], frame.r[1][2], frame.r[2][2]}
+		switch link.Type {
+		case Revolute:
+			model.Screws = append(model.Screws, Screw{Omega: z, V: cross3(frame.p, z)})
+		case Prismatic:
+			model.Screws = append(model.Screws, Screw{V: z})
+		}
+		frame = frame.mul(standardTransform(link.Theta, link.Alpha, link.A, link.D))
+	}
+	model.Home = frame.xyzWxyz()
+	return model
+}
+
+// POE converts a ModifiedChain into a product of exponentials model.
+func (chain ModifiedChain) POE() POEModel {
+	return chain.Chain().POE()
+}
+
+// ForwardKinematics calculates the end effector XyzWxyz coordinates of a
+// POEModel given its joint positions. ForwardKinematics panics with
+// ErrJointCount if there is not one joint position per screw.
+func (model POEModel) ForwardKinematics(joints []float64) XyzWxyz {
+	if len(joints) != len(model.Screws) {
+		panic(ErrJointCount)
+	}
+	accumulator := identityTransform()
+	for i, screw := range model.Screws {
+		accumulator = accumulator.mul(screw.exp(joints[i]))
+	}
+	return accumulator.mul(transformOf(model.Home)).xyzWxyz()
+}
+
+// exp is the matrix exponential of a screw moved by q.
+func (screw Screw) exp(q float64) transform {
+	w, v := screw.Omega, screw.V
+	if w == [3]float64{} {
+		return transform{r: identity3(), p: [3]float64{v[0] * q, v[1] * q, v[2] * q}}
+	}
+	// Rodrigues' formula
+	skew := [3][3]float64{{0, -w[2], w[1]}, {w[2], 0, -w[0]}, {-w[1], w[0], 0}}
+	skew2 := mulMat3(skew, skew)
+	s, c := math.Sin(q), math.Cos(q)
+	var r, g [3][3]float64
+	for i := 0; i < 3; i++ {
+		for j := 0; j < 3; j++ {
+			r[i][j] = s*skew[i][j] + (1-c)*skew2[i][j]
+			g[i][j] = (1-c)*skew[i][j] + (q-s)*skew2[i][j]
+		}
+		r[i][i] = r[i][i] + 1
+		g[i][i] = g[i][i] + q
+	}
+	return transform{r: r, p: mulVec3(g, v)}
+}
+
+// Chain converts a POEModel into standard DH parameters. Each link is placed
+// on the common normal between consecutive joint axes. Fixed links are added
+// at the start to move the base onto the first joint axis, and at the end to
+// move the last frame onto Home. Prismatic joints only have a direction, so
+// their axis is placed through the previous frame.
+func (model POEModel) Chain() Chain {
+	var chain Chain
+	frame := identityTransform()
+	home := transformOf(model.Home)
+	for i := 0; i <= len(model.Screws); i++ {
+		// Find the line of the next axis. After the last joint, the next
+		// axis is the z axis of Home.
+		var point, direction [3]float64
+		switch {
+		case i == len(model.Screws):
+			point = home.p
+			direction = [3]float64{home.r[0][2], home.r[1][2], home.r[2][2]}
+		case model.Screws[i].Omega == [3]float64{}:
+			point = frame.p
+			direction = model.Screws[i].V
+		default:
+			direction = model.Screws[i].Omega
+			point = cross3(direction, model.Screws[i].V)
+		}
+
+		// Move onto the next axis. Before the first joint, this is a Fixed
+		// link, otherwise it is the link of the previous joint.
+		previousType := Fixed
+		if i > 0 {
+			previousType = typeOf(model.Screws[i-1])
+		}
+		link, next := commonNormal(frame, point, direction)
+		link.Type = previousType
+		if previousType != Fixed || !link.isIdentity() {
+			chain = append(chain, link)
+		}
+		frame = next
+	}
+
+	// Rotate and slide along the last axis onto Home.
+	x := [3]float64{frame.r[0][0], frame.r[1][0], frame.r[2][0]}
+	homeX := [3]float64{home.r[0][0], home.r[1][0], home.r[2][0]}
+	z := [3]float64{frame.r[0][2], frame.r[1][2], frame.r[2][2]}
+	last := Link{
+		Type:  Fixed,
+		Theta: math.Atan2(dot3(cross3(x, homeX), z), dot3(x, homeX)),
+		D:     dot3([3]float64{home.p[0] - frame.p[0], home.p[1] - frame.p[1], home.p[2] - frame.p[2]}, z),
+	}
+	if !last.isIdentity() {
+		chain = append(chain, last)
+	}
+	return chain
+}
+
+// typeOf returns the joint type of a screw.
+func typeOf(screw Screw) JointType {
+	if screw.Omega == [3]float64{} {
+		return Prismatic
+	}
+	return Revolute
+}
+
+// isIdentity checks if a link does not move its frame.
+func (link Link) isIdentity() bool {
+	return math.Abs(wrapAngle(link.Theta)) < analyticTolerance && math.Abs(link.D) < analyticTolerance && math.Abs(link.A) < analyticTolerance && math.Abs(wrapAngle(link.Alpha)) < analyticTolerance
+}
+
+// commonNormal finds the standard DH link that moves a frame onto a line,
+// following the common normal between the z axis of the frame and the line.
+// It returns the link and the frame at the end of it.
+func commonNormal(frame transform, point, direction [3]float64) (Link, transform) {
+	z := [3]float64{frame.r[0][2], frame.r[1][2], frame.r[2][2]}
+	x := [3]float64{frame.r[0][0], frame.r[1][0], frame.r[2][0]}
+	w := normalize3(direction)
+	offset := [3]float64{point[0] - frame.p[0], point[1] - frame.p[1], point[2] - frame.p[2]}
+
+	var normal [3]float64
+	var t float64 // distance along z to the foot of the common normal
+	c := cross3(z, w)
+	if norm3(c) > analyticTolerance {
+		normal = normalize3(c)
+		// Closest point on the z axis to the line
+		t = dot3(cross3(offset, w), c) / dot3(c, c)
+	} else {
+		// Parallel axes have a common normal at any height, so keep the
+		// foot at the frame origin.
+		along := dot3(offset, z)
+		perpendicular := [3]float64{offset[0] - along*z[0], offset[1] - along*z[1], offset[2] - along*z[2]}
+		if norm3(perpendicular) > analyticTolerance {
+			normal = normalize3(perpendicular)
+		} else {
+			normal = x
+		}
+	}
+	foot := [3]float64{frame.p[0] + t*z[0], frame.p[1] + t*z[1], frame.p[2] + t*z[2]}
+	a := dot3([3]float64{point[0] - foot[0], point[1] - foot[1], point[2] - foot[2]}, normal)
+
+	link := Link{
+		Theta: math.Atan2(dot3(cross3(x, normal), z), dot3(x, normal)),
+		D:     t,
+		A:     a,
+		Alpha: math.Atan2(dot3(cross3(z, w), normal), dot3(z, w)),
+	}
+	return link, frame.mul(standardTransform(link.Theta, link.Alpha, link.A, link.D))
+}
+
+func cross3(a, b [3]float64) [3]float64 {
+	return [3]float64{a[1]*b[2] - a[2]*b[1], a[2]*b[0] - a[0]*b[2], a[0]*b[1] - a[1]*b[0]}
+}
+
+func dot3(a, b [3]float64) float64 {
+	return a[0]*b[0] + a[1]*b[1] + a[2]*b[2]
+}
+
+func norm3(a [3]float64) float64 {
+	return math.Sqrt(dot3(a, a))
+}
+
+func normalize3(a [3]float64) [3]float64 {
+	n := norm3(a)
+	return [3]float64{a[0] / n, a[1] / n, a[2] / n}
+}
@@ -0,0 +1,53 @@
+package kinematics
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+func TestModels(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	chains := map[string]Chain{
+		"AR3":          AR3DhParameters.Chain(),
+		"AR3 on track": AR3TrackChain,
+		"SCARA":        scaraChain,
+		"planar":       planarChain,
+	}
+	for name, chain := range chains {
+		modified := chain.Modified()
+		poe := chain.POE()
+		fromModified := modified.Chain()
+		fromPOE := poe.Chain()
+		for i := 0; i < 50; i++ {
+			joints := make([]float64, chain.DOF())
+			for j := range joints {
+				joints[j] = math.Pi * (2*r.Float64() - 1)
+			}
+			expected := chain.ForwardKinematics(joints)
+			poses := map[string]XyzWxyz{
+				"modified DH":                modified.ForwardKinematics(joints),
+				"product of exponentials":    poe.ForwardKinematics(joints),
+				"modified DH to standard DH": fromModified.ForwardKinematics(joints),
+				"POE to standard DH":         fromPOE.ForwardKinematics(joints),
+			}
+			for model, pose := range poses {
+				if !poseMatches(pose, expected) {
+					t.Errorf("%s %s does not match standard DH at %v. Got %v, expected %v", name, model, joints, pose, expected)
+				}
+			}
+		}
+	}
+}
+
+func TestModels_POEChain(t *testing.T) {
+	// Converting the AR3 to POE and back only needs the 6 joints, because
+	// its base is already on the first joint axis.
+	chain := AR3DhParameters.Chain().POE().Chain()
+	if chain.DOF() != 6 {
+		t.Errorf("Expected 6 joints. Got %d", chain.DOF())
+	}
+	if len(chain) > 7 {
+		t.Errorf("Expected at most one extra Fixed link. Got %d links", len(chain))
+	}
+}
@@ -0,0 +1,169 @@
+package kinematics
+
+import (
+	"errors"
+	"math"
+)
+
+// ErrConfigurationChange is returned by NearestInverseKinematics when a pose
+// can only be reached by changing the configuration of the arm, and
+// configuration changes are not allowed.
+var ErrConfigurationChange = errors.New("Pose cannot be reached without changing arm configuration")
+
+// Configuration describes which of the up to 8 inverse kinematics solutions of
+// a 6R arm with a spherical wrist a set of joint angles is. Each field is
+// either 1 or -1.
+//
+// Shoulder is 1 when the wrist center is in front of joint 1 and -1 when the
+// arm reaches over its back. Elbow is 1 for elbow up and -1 for elbow down.
+// Wrist is the sign of joint 5, which flips when the wrist passes through a
+// singularity.
+type Configuration struct {
+	Shoulder int
+	Elbow    int
+	Wrist    int
+}
+
+// ConfigurationOf returns the configuration of a set of joint angles.
+func ConfigurationOf(thetas StepperTheta, dhParameters DhParameters) Configuration {
+	q := thetas.toFloat()
+	for i := range q {
+		q[i] = q[i] + dhParameters.ThetaOffsets[i]
+	}
+	alpha := dhParameters.AlphaValues
+	d := dhParameters.DValues
+
+	// Wrist center, found by backing off from the end effector
+	pose := ForwardKinematics(thetas, dhParameters)
+	r06 := quaternionToRotation(pose.Qw, pose.Qx, pose.Qy, pose.Qz)
+	wristAxis := mulVec3(r06, [3]float64{0, math.Sin(alpha[5]), math.Cos(alpha[5])})
+	px := pose.X - d[5]*wristAxis[0]
+	py := pose.Y - d[5]*wristAxis[1]
+
+	phi := math.Atan2(d[3]*math.Sin(alpha[2]), dhParameters.AValues[2])
+	return Configuration{
+		Shoulder: sign(px*math.Cos(q[0]) + py*math.Sin(q[0])),
+		Elbow:    sign(math.Sin(q[2] - phi)),
+		Wrist:    sign(math.Sin(q[4])),
+	}
+}
+
+// NearestOptions define how NearestInverseKinematics chooses a solution.
+// Weights scale the motion of each joint, so that moving a heavily weighted
+// joint is avoided. Zero weights are treated as 1. If Limits is not nil, only
+// solutions within the limits are returned. If KeepConfiguration is true,
+// solutions with a different Configuration than the current joint angles are
+// not returned.
+type NearestOptions struct {
+	Weights           [6]float64
+	Limits            *JointLimits
+	KeepConfiguration bool
+}
+
+// NearestInverseKinematics calculates the joint angles that reach an XyzWxyz
+// end effector position with the least weighted joint motion from the current
+// joint angles. Full turns are added or removed from each joint to keep it as
+// close as possible to its current angle.
+//
+// Arms with a closed-form solution choose between every solution from
+// AnalyticInverseKinematics. Other arms use the numeric solver seeded with the
+// current joint angles: InverseKinematicsWithinLimitsWithOptions if Limits is
+// set, so that the solver keeps looking until it finds a solution within them,
+// and InverseKinematicsFromSeed otherwise.
+func NearestInverseKinematics(desiredEndEffector XyzWxyz, dhParameters DhParameters, current StepperTheta, options NearestOptions) (StepperTheta, error) {
+	solutions, err := AnalyticInverseKinematics(desiredEndEffector, dhParameters)
+	if err == ErrNoAnalyticSolution {
+		if options.Limits != nil {
+			solutions, err = inverseKinematicsWithinLimitsFromSeed(desiredEndEffector, dhParameters, *options.Limits, current, DefaultIKOptions)
+		} else {
+			var solution StepperTheta
+			solution, err = InverseKinematicsFromSeed(desiredEndEffector, dhParameters, current)
+			solutions = []StepperTheta{solution}
+		}
+	}
+	if err != nil {
+		return StepperTheta{}, err
+	}
+
+	var configuration Configuration
+	if options.KeepConfiguration {
+		configuration = ConfigurationOf(current, dhParameters)
+	}
+	var nearest StepperTheta
+	nearestDistance := math.Inf(1)
+	var outsideLimits, configurationChanges int
+	for _, solution := range solutions {
+		solution, ok := unwrapNear(solution, current, options.Limits)
+		if !ok {
+			outsideLimits++
+			continue
+		}
+		if options.KeepConfiguration && ConfigurationOf(solution, dhParameters) != configuration {
+			configurationChanges++
+			continue
+		}
+		distance := weightedDistance(solution, current, options.Weights)
+		if distance < nearestDistance {
+			nearest = solution
+			nearestDistance = distance
+		}
+	}
+	switch {
+	case !math.IsInf(nearestDistance, 1):
+		return nearest, nil
+	case configurationChanges > 0:
+		return StepperTheta{}, ErrConfigurationChange
+	default:
+		return StepperTheta{}, ErrNoSolutionWithinLimits
+	}
+}
+
+// unwrapNear adds or removes full turns from each joint so that it is as close
+// as possible to the reference while staying within limits. It returns false
+// if a joint cannot be brought within its limits.
+func unwrapNear(thetas, reference StepperTheta, limits *JointLimits) (StepperTheta, bool) {
+	angles := thetas.toFloat()
+	references := reference.toFloat()
+	for i, angle := range angles {
+		angle = angle - 2*math.Pi*math.Round((angle-references[i])/(2*math.Pi))
+		if limits != nil {
+			minimum := limits.Min.toFloat()[i]
+			maximum := limits.Max.toFloat()[i]
+			switch {
+			case angle < minimum:
+				angle = angle + 2*math.Pi
+			case angle > maximum:
+				angle = angle - 2*math.Pi
+			}
+			if angle < minimum || angle > maximum {
+				return thetas, false
+			}
+		}
+		angles[i] = angle
+	}
+	return StepperTheta{angles[0], angles[1], angles[2], angles[3], angles[4], angles[5]}, true
+}
+
+// weightedDistance returns the weighted sum of absolute joint motion between
+// two sets of joint angles.
+func weightedDistance(a, b StepperTheta, weights [6]float64) float64 {
+	anglesA := a.toFloat()
+	anglesB := b.toFloat()
+	distance := 0.0
+	for i := range anglesA {
+		weight := weights[i]
+		if weight == 0 {
+			weight = 1
+		}
+		distance = distance + weight*math.Abs(anglesA[i]-anglesB[i])
+	}
+	return distance
+}
+
+// sign returns -1 for negative numbers and 1 otherwise.
+func sign(x float64) int {
+	if x < 0 {
+		return -1
+	}
+	return 1
+}
@@ -0,0 +1,111 @@
+package kinematics
+
+import (
+	"errors"
+	"math"
+	"testing"
+)
+
+func TestNearestInverseKinematics(t *testing.T) {
+	current := StepperTheta{0.3, -1.2, 1.5, 0.4, 0.6, -0.2}
+	desiredEndEffector := ForwardKinematics(StepperTheta{0.31, -1.19, 1.49, 0.42, 0.58, -0.21}, AR3DhParameters)
+	solution, err := NearestInverseKinematics(desiredEndEffector, AR3DhParameters, current, NearestOptions{})
+	if err != nil {
+		t.Fatalf("Nearest inverse kinematics failed with error: %s", err)
+	}
+	if maxDifference(solution, current) > 0.05 {
+		t.Errorf("Expected a solution near %v. Got %v", current, solution)
+	}
+	if ConfigurationOf(solution, AR3DhParameters) != ConfigurationOf(current, AR3DhParameters) {
+		t.Errorf("Nearest solution should not change configuration")
+	}
+}
+
+func TestNearestInverseKinematics_Unwrap(t *testing.T) {
+	// Joint 6 past pi should stay past pi instead of jumping a full turn.
+	current := StepperTheta{0.3, -1.2, 1.5, 0.4, 0.6, 3.1}
+	desiredEndEffector := ForwardKinematics(StepperTheta{0.3, -1.2, 1.5, 0.4, 0.6, 3.2}, AR3DhParameters)
+	solution, err := NearestInverseKinematics(desiredEndEffector, AR3DhParameters, current, NearestOptions{})
+	if err != nil {
+		t.Fatalf("Nearest inverse kinematics failed with error: %s", err)
+	}
+	if math.Abs(solution.J6-3.2) > 1e-6 {
+		t.Errorf("Expected J6 to be 3.2. Got %f", solution.J6)
+	}
+}
+
+func TestNearestInverseKinematics_KeepConfiguration(t *testing.T) {
+	// The arm reaching over its back can't stretch as far as the arm reaching
+	// forwards, so this pose can only be reached by changing configuration.
+	current := StepperTheta{-2.84, -3.07, 0.21, -2.92, 1.49, 0.12}
+	if ConfigurationOf(current, AR3DhParameters).Shoulder != -1 {
+		t.Fatalf("Expected the current joint angles to reach over the back of the arm")
+	}
+	desiredEndEffector := ForwardKinematics(StepperTheta{0.3, -0.8, 1.3, 0.4, 0.6, -0.2}, AR3DhParameters)
+	_, err := NearestInverseKinematics(desiredEndEffector, AR3DhParameters, current, NearestOptions{KeepConfiguration: true})
+	if !errors.Is(err, ErrConfigurationChange) {
+		t.Errorf("Expected ErrConfigurationChange. Got %v", err)
+	}
+	solution, err := NearestInverseKinematics(desiredEndEffector, AR3DhParameters, current, NearestOptions{})
+	if err != nil {
+		t.Fatalf("Nearest inverse kinematics should allow configuration changes by default. Got %s", err)
+	}
+	if ConfigurationOf(solution, AR3DhParameters).Shoulder != 1 {
+		t.Errorf("Expected the solution to reach forwards. Got %v", solution)
+	}
+}
+
+func TestNearestInverseKinematics_Weights(t *testing.T) {
+	// Near a wrist singularity, the pose can be reached either by rotating
+	// joints 4 and 6 a long way, or by flipping joint 5 and rotating them a
+	// little. Weighting joint 5 heavily should avoid the flip.
+	current := StepperTheta{0.3, -1.2, 1.5, 0.4, 0.05, -0.2}
+	desiredEndEffector := ForwardKinematics(StepperTheta{0.3, -1.2, 1.5, 2.4, 0.05, -2.2}, AR3DhParameters)
+	solution, err := NearestInverseKinematics(desiredEndEffector, AR3DhParameters, current, NearestOptions{})
+	if err != nil {
+		t.Fatalf("Nearest inverse kinematics failed with error: %s", err)
+	}
+	if math.Abs(solution.J5+0.05) > 1e-6 {
+		t.Errorf("Expected the wrist to flip to J5 = -0.05. Got %f", solution.J5)
+	}
+	weights := [6]float64{1, 1, 1, 1, 100, 1}
+	solution, err = NearestInverseKinematics(desiredEndEffector, AR3DhParameters, current, NearestOptions{Weights: weights})
+	if err != nil {
+		t.Fatalf("Nearest inverse kinematics failed with error: %s", err)
+	}
+	if math.Abs(solution.J5-0.05) > 1e-6 {
+		t.Errorf("Expected J5 to stay at 0.05. Got %f", solution.J5)
+	}
+}
+
+func maxDifference(a, b StepperTheta) float64 {
+	difference := 0.0
+	anglesA := a.toFloat()
+	for i, angle := range b.toFloat() {
+		difference = math.Max(difference, math.Abs(anglesA[i]-angle))
+	}
+	return difference
+}
+
+func TestNearestInverseKinematics_NumericLimits(t *testing.T) {
+	// Offsetting the wrist removes the closed-form solution. Starting from the
+	// flipped wrist, the numeric solver has to search for a solution with J5
+	// within limits.
+	dhParameters := AR3DhParameters
+	dhParameters.AValues[4] = 10
+	target := StepperTheta{0.3, -1.2, 1.5, 0.4, 0.6, -0.2}
+	current := StepperTheta{0.3, -1.2, 1.5, 0.4 - math.Pi, -0.6, -0.2 + math.Pi}
+	desiredEndEffector := ForwardKinematics(target, dhParameters)
+	limits := AR3JointLimits
+	limits.Min.J5 = 0
+	solution, err := NearestInverseKinematics(desiredEndEffector, dhParameters, current, NearestOptions{Limits: &limits})
+	if err != nil {
+		t.Fatalf("Nearest inverse kinematics failed with error: %s", err)
+	}
+	if !limits.Contains(solution) {
+		t.Errorf("Solution %v is not within limits", solution)
+	}
+	if !poseMatches(ForwardKinematics(solution, dhParameters), desiredEndEffector) {
+		t.Errorf("Solution %v does not reach %v", solution, desiredEndEffector)
+	}
+}
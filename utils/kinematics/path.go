@@ -0,0 +1,164 @@
+package kinematics
+
+import (
+	"errors"
+	"fmt"
+	"math"
+)
+
+// ErrJointJump is returned when a joint moves further than
+// PathOptions.MaxJointStep between two samples of a straight line path. This
+// happens near a singularity or when the arm would have to change
+// configuration to stay on the line.
+var ErrJointJump = errors.New("Joint jumps between samples")
+
+// ErrUnreachable is returned when a sample of a straight line path cannot be
+// reached.
+var ErrUnreachable = errors.New("Pose cannot be reached")
+
+// PathOptions define how a straight line path is sampled and checked.
+// SegmentLength is the maximum distance between samples in millimeters, and
+// SegmentRotation is the maximum rotation between samples in radians.
+// MaxJointStep is the largest change of any joint, in radians, allowed between
+// two samples. Frames are the base and tool offsets of the arm, and Nearest is
+// passed on to NearestInverseKinematics at each sample.
+type PathOptions struct {
+	SegmentLength   float64
+	SegmentRotation float64
+	MaxJointStep    float64
+	Frames          Frames
+	Nearest         NearestOptions
+}
+
+// DefaultPathOptions are good defaults for straight line paths. The arm may not
+// change configuration along the path.
+var DefaultPathOptions = PathOptions{SegmentLength: 5, SegmentRotation: 5 * math.Pi / 180, MaxJointStep: 10 * math.Pi / 180, Nearest: NearestOptions{KeepConfiguration: true}}
+
+// MaxPathSamples is the most samples PlanCartesianPath will solve for a
+// single path.
+const MaxPathSamples = 10000
+
+// PathSample is a single sample of a straight line path. Step is the largest
+// change of any joint from the previous sample. If the sample cannot be
+// reached, Err is set and Theta is the joint angles of the previous sample.
+type PathSample struct {
+	Pose  XyzWxyz
+	Theta StepperTheta
+	Step  float64
+	Err   error
+}
+
+// PathSegment is the straight line between two waypoints of a path.
+// MaxJointStep is the largest change of any joint between two samples of the
+// segment, and MaxStepJoint is the index (0 for J1) of that joint. Err is the
+// first error of any sample in the segment.
+type PathSegment struct {
+	Samples      []PathSample
+	MaxJointStep float64
+	MaxStepJoint int
+	Err          error
+}
+
+// CartesianPath is a planned path of straight lines between waypoints.
+type CartesianPath struct {
+	Segments []PathSegment
+}
+
+// Err returns the first error of any segment of the path, or nil if the whole
+// path can be followed.
+func (path CartesianPath) Err() error {
+	for i, segment := range path.Segments {
+		if segment.Err != nil {
+			return fmt.Errorf("Segment %d of %d: %w", i+1, len(path.Segments), segment.Err)
+		}
+	}
+	return nil
+}
+
+// Thetas returns the joint angles of every sample of the path, in order.
+func (path CartesianPath) Thetas() []StepperTheta {
+	var thetas []StepperTheta
+	for _, segment := range path.Segments {
+		for _, sample := range segment.Samples {
+			thetas = append(thetas, sample.Theta)
+		}
+	}
+	return thetas
+}
+
+// PlanCartesianPath plans straight line moves of the tool from the pose at the
+// start joint angles through each waypoint, which are tool poses in the world.
+// Position is interpolated linearly and rotation with Slerp. Each sample is
+// solved with NearestInverseKinematics, seeded with the previous sample.
+//
+// The whole path is always planned, so that callers can see every unreachable
+// sample and joint jump before moving. The returned error is path.Err().
+// SegmentLength and SegmentRotation must be positive, and the path may not
+// need more than MaxPathSamples samples. Otherwise an error is returned before
+// anything is planned.
+func PlanCartesianPath(waypoints []XyzWxyz, dhParameters DhParameters, start StepperTheta, options PathOptions) (CartesianPath, error) {
+	if options.SegmentLength <= 0 || options.SegmentRotation <= 0 {
+		return CartesianPath{}, fmt.Errorf("Path SegmentLength and SegmentRotation must be positive. Got %f and %f", options.SegmentLength, options.SegmentRotation)
+	}
+
+	// Figure out how many samples we need for each segment
+	origin := options.Frames.ForwardKinematics(start, dhParameters)
+	sampleCounts := make([]int, len(waypoints))
+	total := 0.0
+	from := origin
+	for i, to := range waypoints {
+		samples := math.Max(math.Max(math.Ceil(from.Distance(to)/options.SegmentLength), math.Ceil(from.Angle(to)/options.SegmentRotation)), 1)
+		total = total + samples
+		// Written this way round so that NaN poses are rejected too
+		if !(total <= MaxPathSamples) {
+			return CartesianPath{}, fmt.Errorf("Path needs more than %d samples. Use longer segments or fewer waypoints", MaxPathSamples)
+		}
+		sampleCounts[i] = int(samples)
+		from = to
+	}
+
+	var path CartesianPath
+	from = origin
+	previous := start
+	for waypoint, to := range waypoints {
+		samples := sampleCounts[waypoint]
+		var segment PathSegment
+		for i := 1; i <= samples; i++ {
+			sample := PathSample{Pose: Slerp(from, to, float64(i)/float64(samples)), Theta: previous}
+			solution, err := options.Frames.InverseKinematics(sample.Pose, dhParameters, previous, options.Nearest)
+			if err != nil {
+				sample.Err = fmt.Errorf("%w at sample %d of %d: %s", ErrUnreachable, i, samples, err)
+			} else {
+				var joint int
+				sample.Theta = solution
+				sample.Step, joint = maxJointStep(solution, previous)
+				if sample.Step > segment.MaxJointStep {
+					segment.MaxJointStep, segment.MaxStepJoint = sample.Step, joint
+				}
+				if sample.Step > options.MaxJointStep {
+					sample.Err = fmt.Errorf("%w at sample %d of %d. J%d moves by %f radians", ErrJointJump, i, samples, joint+1, sample.Step)
+				}
+				previous = solution
+			}
+			if segment.Err == nil {
+				segment.Err = sample.Err
+			}
+			segment.Samples = append(segment.Samples, sample)
+		}
+		path.Segments = append(path.Segments, segment)
+		from = to
+	}
+	return path, path.Err()
+}
+
+// maxJointStep returns the largest change of any joint between two sets of
+// joint angles, and the index of that joint.
+func maxJointStep(a, b StepperTheta) (float64, int) {
+	step, joint := 0.0, 0
+	for i, d := range []float64{a.J1 - b.J1, a.J2 - b.J2, a.J3 - b.J3, a.J4 - b.J4, a.J5 - b.J5, a.J6 - b.J6} {
+		if math.Abs(d) > step {
+			step, joint = math.Abs(d), i
+		}
+	}
+	return step, joint
+}
@@ -0,0 +1,117 @@
+package kinematics
+
+import (
+	"errors"
+	"math"
+	"testing"
+)
+
+func TestPlanCartesianPath(t *testing.T) {
+	// Approach 50mm down, twist 20 degrees around the flange, then retract.
+	start := StepperTheta{0.3, -1.2, 1.5, 0.4, 0.6, -0.2}
+	above := ForwardKinematics(start, AR3DhParameters)
+	below := above
+	below.Z = below.Z - 50
+	twisted := below.Mul(PoseFromAngleAxis(20*math.Pi/180, [3]float64{0, 0, 1}))
+	retracted := twisted
+	retracted.Z = retracted.Z + 50
+
+	path, err := PlanCartesianPath([]XyzWxyz{below, twisted, retracted}, AR3DhParameters, start, DefaultPathOptions)
+	if err != nil {
+		t.Fatalf("PlanCartesianPath failed with error: %s", err)
+	}
+	if len(path.Segments) != 3 {
+		t.Fatalf("Expected 3 segments. Got %d", len(path.Segments))
+	}
+
+	// 50mm at 5mm per sample, and 20 degrees at 5 degrees per sample
+	for i, expected := range []int{10, 4, 10} {
+		if len(path.Segments[i].Samples) != expected {
+			t.Errorf("Expected %d samples in segment %d. Got %d", expected, i, len(path.Segments[i].Samples))
+		}
+	}
+	for i, segment := range path.Segments {
+		if segment.MaxJointStep <= 0 || segment.MaxJointStep > DefaultPathOptions.MaxJointStep {
+			t.Errorf("Unexpected largest joint step %f in segment %d", segment.MaxJointStep, i)
+		}
+		last := segment.Samples[len(segment.Samples)-1]
+		if !poseMatches(ForwardKinematics(last.Theta, AR3DhParameters), last.Pose) {
+			t.Errorf("Segment %d does not reach its waypoint", i)
+		}
+	}
+
+	// Every sample is on the straight line
+	for _, sample := range path.Segments[0].Samples {
+		reached := ForwardKinematics(sample.Theta, AR3DhParameters)
+		if math.Abs(reached.X-above.X) > 1e-6 || math.Abs(reached.Y-above.Y) > 1e-6 {
+			t.Errorf("Sample %v is off of the line", reached)
+		}
+	}
+	if len(path.Thetas()) != 24 {
+		t.Errorf("Expected 24 samples. Got %d", len(path.Thetas()))
+	}
+}
+
+func TestPlanCartesianPath_Errors(t *testing.T) {
+	start := StepperTheta{0.3, -1.2, 1.5, 0.4, 0.6, -0.2}
+	pose := ForwardKinematics(start, AR3DhParameters)
+	far := pose
+	far.X = far.X + 2000
+	near := pose
+	near.Z = near.Z + 20
+
+	// An unreachable section is reported, and the rest of the path is still
+	// planned.
+	path, err := PlanCartesianPath([]XyzWxyz{far, near}, AR3DhParameters, start, DefaultPathOptions)
+	if !errors.Is(err, ErrUnreachable) {
+		t.Errorf("Expected ErrUnreachable. Got %v", err)
+	}
+	if len(path.Segments) != 2 || !errors.Is(path.Segments[0].Err, ErrUnreachable) {
+		t.Fatalf("Expected the first of 2 segments to be unreachable")
+	}
+	unreachable := path.Segments[0].Samples[len(path.Segments[0].Samples)-1]
+	if unreachable.Err == nil || unreachable.Theta == (StepperTheta{}) {
+		t.Errorf("Unreachable samples should have an error and keep the previous joint angles")
+	}
+
+	// Joint jumps are reported along with the joint that jumped
+	options := DefaultPathOptions
+	options.MaxJointStep = 0.001
+	path, err = PlanCartesianPath([]XyzWxyz{near}, AR3DhParameters, start, options)
+	if !errors.Is(err, ErrJointJump) {
+		t.Errorf("Expected ErrJointJump. Got %v", err)
+	}
+	segment := path.Segments[0]
+	steps := make([]float64, 6)
+	previous := start
+	for _, sample := range segment.Samples {
+		for j, d := range []float64{sample.Theta.J1 - previous.J1, sample.Theta.J2 - previous.J2, sample.Theta.J3 - previous.J3, sample.Theta.J4 - previous.J4, sample.Theta.J5 - previous.J5, sample.Theta.J6 - previous.J6} {
+			steps[j] = math.Max(steps[j], math.Abs(d))
+		}
+		previous = sample.Theta
+	}
+	if steps[segment.MaxStepJoint] != segment.MaxJointStep {
+		t.Errorf("J%d should have the largest step of %f. Got steps %v", segment.MaxStepJoint+1, segment.MaxJointStep, steps)
+	}
+}
+
+func TestPlanCartesianPath_InvalidOptions(t *testing.T) {
+	start := StepperTheta{0.3, -1.2, 1.5, 0.4, 0.6, -0.2}
+	pose := ForwardKinematics(start, AR3DhParameters)
+	pose.Z = pose.Z + 20
+
+	for _, lengths := range [][2]float64{{0, 0.1}, {-5, 0.1}, {5, 0}, {5, -0.1}} {
+		options := DefaultPathOptions
+		options.SegmentLength, options.SegmentRotation = lengths[0], lengths[1]
+		if path, err := PlanCartesianPath([]XyzWxyz{pose}, AR3DhParameters, start, options); err == nil || len(path.Segments) != 0 {
+			t.Errorf("Expected SegmentLength %f and SegmentRotation %f to be rejected", lengths[0], lengths[1])
+		}
+	}
+
+	// Paths needing too many samples are rejected before planning
+	options := DefaultPathOptions
+	options.SegmentLength = 1e-9
+	if path, err := PlanCartesianPath([]XyzWxyz{pose}, AR3DhParameters, start, options); err == nil || len(path.Segments) != 0 {
+		t.Errorf("Expected a path with too many samples to be rejected")
+	}
+}
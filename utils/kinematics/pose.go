@@ -0,0 +1,155 @@
+package kinematics
+
+import (
+	"gonum.org/v1/gonum/mat"
+	"math"
+)
+
+// IdentityPose is the XyzWxyz with no translation and no rotation.
+var IdentityPose = XyzWxyz{Qw: 1}
+
+// Normalize scales the quaternion of a pose to unit length. A quaternion of all
+// zeros is treated as no rotation, so poses written without a rotation are
+// valid.
+func (pose XyzWxyz) Normalize() XyzWxyz {
+	norm := math.Sqrt(pose.Qw*pose.Qw + pose.Qx*pose.Qx + pose.Qy*pose.Qy + pose.Qz*pose.Qz)
+	if norm == 0 {
+		pose.Qw = 1
+		return pose
+	}
+	pose.Qw, pose.Qx, pose.Qy, pose.Qz = pose.Qw/norm, pose.Qx/norm, pose.Qy/norm, pose.Qz/norm
+	return pose
+}
+
+// Mul composes two poses. If pose is the position of frame B in frame A and
+// other is the position of frame C in frame B, the result is the position of
+// frame C in frame A.
+func (pose XyzWxyz) Mul(other XyzWxyz) XyzWxyz {
+	a, b := pose.Normalize(), other.Normalize()
+	p := a.RotateVector([3]float64{b.X, b.Y, b.Z})
+	return XyzWxyz{
+		X:  a.X + p[0],
+		Y:  a.Y + p[1],
+		Z:  a.Z + p[2],
+		Qw: a.Qw*b.Qw - a.Qx*b.Qx - a.Qy*b.Qy - a.Qz*b.Qz,
+		Qx: a.Qw*b.Qx + a.Qx*b.Qw + a.Qy*b.Qz - a.Qz*b.Qy,
+		Qy: a.Qw*b.Qy - a.Qx*b.Qz + a.Qy*b.Qw + a.Qz*b.Qx,
+		Qz: a.Qw*b.Qz + a.Qx*b.Qy - a.Qy*b.Qx + a.Qz*b.Qw,
+	}
+}
+
+// Inverse returns the inverse of a pose, so that pose.Mul(pose.Inverse()) is
+// IdentityPose.
+func (pose XyzWxyz) Inverse() XyzWxyz {
+	inverse := pose.Normalize()
+	inverse.Qx, inverse.Qy, inverse.Qz = -inverse.Qx, -inverse.Qy, -inverse.Qz
+	inverse.X, inverse.Y, inverse.Z = 0, 0, 0
+	p := inverse.RotateVector([3]float64{pose.X, pose.Y, pose.Z})
+	inverse.X, inverse.Y, inverse.Z = -p[0], -p[1], -p[2]
+	return inverse
+}
+
+// RotateVector rotates a vector by the rotation of a pose. The translation of
+// the pose is ignored.
+func (pose XyzWxyz) RotateVector(v [3]float64) [3]float64 {
+	q := pose.Normalize()
+	return mulVec3(quaternionToRotation(q.Qw, q.Qx, q.Qy, q.Qz), v)
+}
+
+// TransformPoint moves a point in the frame of a pose into the frame the pose
+// is relative to.
+func (pose XyzWxyz) TransformPoint(point [3]float64) [3]float64 {
+	p := pose.RotateVector(point)
+	return [3]float64{p[0] + pose.X, p[1] + pose.Y, p[2] + pose.Z}
+}
+
+// Matrix converts a pose into a 4x4 homogeneous transformation matrix.
+func (pose XyzWxyz) Matrix() *mat.Dense {
+	q := pose.Normalize()
+	r := quaternionToRotation(q.Qw, q.Qx, q.Qy, q.Qz)
+	return mat.NewDense(4, 4, []float64{
+		r[0][0], r[0][1], r[0][2], pose.X,
+		r[1][0], r[1][1], r[1][2], pose.Y,
+		r[2][0], r[2][1], r[2][2], pose.Z,
+		0, 0, 0, 1,
+	})
+}
+
+// PoseFromMatrix converts a 4x4 homogeneous transformation matrix into a pose.
+// The upper left 3x3 of the matrix must be a rotation matrix.
+func PoseFromMatrix(m mat.Matrix) XyzWxyz {
+	var pose XyzWxyz
+	pose.X, pose.Y, pose.Z = m.At(0, 3), m.At(1, 3), m.At(2, 3)
+	pose.Qw, pose.Qx, pose.Qy, pose.Qz = matrixToQuaterian(m)
+	return pose
+}
+
+// PoseFromAngleAxis returns a pose with no translation that rotates by angle
+// radians around axis. The axis does not need to be unit length.
+func PoseFromAngleAxis(angle float64, axis [3]float64) XyzWxyz {
+	norm := norm3(axis)
+	if norm == 0 {
+		return IdentityPose
+	}
+	s := math.Sin(angle/2) / norm
+	return XyzWxyz{Qw: math.Cos(angle / 2), Qx: axis[0] * s, Qy: axis[1] * s, Qz: axis[2] * s}
+}
+
+// AngleAxis returns the rotation of a pose as an angle between 0 and pi
+// radians around a unit axis. A pose with no rotation returns an angle of 0
+// around the x axis.
+func (pose XyzWxyz) AngleAxis() (float64, [3]float64) {
+	q := pose.Normalize()
+	if q.Qw < 0 {
+		q.Qw, q.Qx, q.Qy, q.Qz = -q.Qw, -q.Qx, -q.Qy, -q.Qz
+	}
+	s := math.Sqrt(q.Qx*q.Qx + q.Qy*q.Qy + q.Qz*q.Qz)
+	if s == 0 {
+		return 0, [3]float64{1, 0, 0}
+	}
+	return 2 * math.Atan2(s, q.Qw), [3]float64{q.Qx / s, q.Qy / s, q.Qz / s}
+}
+
+// Distance returns the straight line distance between the positions of two
+// poses.
+func (pose XyzWxyz) Distance(other XyzWxyz) float64 {
+	return math.Sqrt(math.Pow(other.X-pose.X, 2) + math.Pow(other.Y-pose.Y, 2) + math.Pow(other.Z-pose.Z, 2))
+}
+
+// Angle returns the angle, between 0 and pi radians, of the smallest rotation
+// between the rotations of two poses. Quaternions q and -q are the same
+// rotation.
+func (pose XyzWxyz) Angle(other XyzWxyz) float64 {
+	a, b := pose.Normalize(), other.Normalize()
+	dot := math.Abs(a.Qw*b.Qw + a.Qx*b.Qx + a.Qy*b.Qy + a.Qz*b.Qz)
+	return 2 * math.Acos(math.Min(1, dot))
+}
+
+// Slerp interpolates between two poses. The position is interpolated linearly
+// and the rotation is spherically interpolated along the shortest path. t is 0
+// at from and 1 at to.
+func Slerp(from, to XyzWxyz, t float64) XyzWxyz {
+	from, to = from.Normalize(), to.Normalize()
+	var pose XyzWxyz
+	pose.X = from.X + (to.X-from.X)*t
+	pose.Y = from.Y + (to.Y-from.Y)*t
+	pose.Z = from.Z + (to.Z-from.Z)*t
+
+	// Take the shortest path between the two rotations
+	dot := from.Qw*to.Qw + from.Qx*to.Qx + from.Qy*to.Qy + from.Qz*to.Qz
+	if dot < 0 {
+		dot = -dot
+		to.Qw, to.Qx, to.Qy, to.Qz = -to.Qw, -to.Qx, -to.Qy, -to.Qz
+	}
+	fromWeight, toWeight := 1-t, t
+	if dot < 0.9995 {
+		angle := math.Acos(dot)
+		fromWeight = math.Sin((1-t)*angle) / math.Sin(angle)
+		toWeight = math.Sin(t*angle) / math.Sin(angle)
+	}
+	pose.Qw = fromWeight*from.Qw + toWeight*to.Qw
+	pose.Qx = fromWeight*from.Qx + toWeight*to.Qx
+	pose.Qy = fromWeight*from.Qy + toWeight*to.Qy
+	pose.Qz = fromWeight*from.Qz + toWeight*to.Qz
+	return pose.Normalize()
+}
@@ -0,0 +1,224 @@
+package kinematics
+
+import (
+	"gonum.org/v1/gonum/mat"
+	"math"
+	"testing"
+)
+
+// rotationZ90 is a rotation of 90 degrees around the z axis.
+var rotationZ90 = XyzWxyz{Qw: math.Sqrt2 / 2, Qz: math.Sqrt2 / 2}
+
+func posesEqual(a, b XyzWxyz, tolerance float64) bool {
+	return a.Distance(b) < tolerance && a.Angle(b) < tolerance
+}
+
+func vectorsEqual(a, b [3]float64, tolerance float64) bool {
+	return math.Abs(a[0]-b[0]) < tolerance && math.Abs(a[1]-b[1]) < tolerance && math.Abs(a[2]-b[2]) < tolerance
+}
+
+func TestXyzWxyz_Normalize(t *testing.T) {
+	testCases := []struct {
+		pose     XyzWxyz
+		expected XyzWxyz
+	}{
+		{XyzWxyz{X: 1}, XyzWxyz{X: 1, Qw: 1}},
+		{XyzWxyz{Qw: 2}, XyzWxyz{Qw: 1}},
+		{XyzWxyz{Qw: 3, Qz: 4}, XyzWxyz{Qw: 0.6, Qz: 0.8}},
+		{XyzWxyz{Qw: -1, Qx: 1, Qy: 1, Qz: 1}, XyzWxyz{Qw: -0.5, Qx: 0.5, Qy: 0.5, Qz: 0.5}},
+	}
+	for _, testCase := range testCases {
+		normalized := testCase.pose.Normalize()
+		if normalized != testCase.expected {
+			t.Errorf("Normalize(%v) should be %v. Got %v", testCase.pose, testCase.expected, normalized)
+		}
+	}
+}
+
+func TestXyzWxyz_Mul(t *testing.T) {
+	testCases := []struct {
+		name     string
+		a        XyzWxyz
+		b        XyzWxyz
+		expected XyzWxyz
+	}{
+		{"translations add", XyzWxyz{X: 1, Y: 2, Z: 3, Qw: 1}, XyzWxyz{X: 4, Y: 5, Z: 6, Qw: 1}, XyzWxyz{X: 5, Y: 7, Z: 9, Qw: 1}},
+		{"translation is rotated", XyzWxyz{X: 10, Qw: rotationZ90.Qw, Qz: rotationZ90.Qz}, XyzWxyz{X: 1, Qw: 1}, XyzWxyz{X: 10, Y: 1, Qw: rotationZ90.Qw, Qz: rotationZ90.Qz}},
+		{"rotations add", rotationZ90, rotationZ90, XyzWxyz{Qz: 1}},
+		{"identity", XyzWxyz{X: 1, Y: 2, Z: 3, Qw: 0.5, Qx: 0.5, Qy: 0.5, Qz: 0.5}, IdentityPose, XyzWxyz{X: 1, Y: 2, Z: 3, Qw: 0.5, Qx: 0.5, Qy: 0.5, Qz: 0.5}},
+		{"zero rotation is identity", XyzWxyz{X: 1}, XyzWxyz{Y: 1}, XyzWxyz{X: 1, Y: 1, Qw: 1}},
+		// 90 degrees around x, then 90 degrees around y, is 120 degrees around (1, 1, -1)
+		{"x then y", XyzWxyz{Qw: math.Sqrt2 / 2, Qx: math.Sqrt2 / 2}, XyzWxyz{Qw: math.Sqrt2 / 2, Qy: math.Sqrt2 / 2}, XyzWxyz{Qw: 0.5, Qx: 0.5, Qy: 0.5, Qz: 0.5}},
+	}
+	for _, testCase := range testCases {
+		composed := testCase.a.Mul(testCase.b)
+		if !posesEqual(composed, testCase.expected, 1e-9) {
+			t.Errorf("%s: expected %v. Got %v", testCase.name, testCase.expected, composed)
+		}
+	}
+
+	// Mul matches multiplying 4x4 matrices
+	a := XyzWxyz{X: 100, Y: -20, Z: 30, Qw: 0.9, Qx: 0.1, Qy: -0.3, Qz: 0.2}
+	b := XyzWxyz{X: -5, Y: 40, Z: 12, Qw: 0.2, Qx: 0.7, Qy: 0.1, Qz: -0.6}
+	var product mat.Dense
+	product.Mul(a.Matrix(), b.Matrix())
+	if !posesEqual(a.Mul(b), PoseFromMatrix(&product), 1e-9) {
+		t.Errorf("Mul should match matrix multiplication. Got %v, expected %v", a.Mul(b), PoseFromMatrix(&product))
+	}
+}
+
+func TestXyzWxyz_Inverse(t *testing.T) {
+	poses := []XyzWxyz{
+		IdentityPose,
+		{X: 1, Y: 2, Z: 3, Qw: 1},
+		{X: 10, Qw: rotationZ90.Qw, Qz: rotationZ90.Qz},
+		{X: 100, Y: -20, Z: 30, Qw: 0.9, Qx: 0.1, Qy: -0.3, Qz: 0.2},
+	}
+	for _, pose := range poses {
+		if !posesEqual(pose.Mul(pose.Inverse()), IdentityPose, 1e-9) {
+			t.Errorf("pose.Mul(pose.Inverse()) should be identity for %v. Got %v", pose, pose.Mul(pose.Inverse()))
+		}
+		if !posesEqual(pose.Inverse().Mul(pose), IdentityPose, 1e-9) {
+			t.Errorf("pose.Inverse().Mul(pose) should be identity for %v. Got %v", pose, pose.Inverse().Mul(pose))
+		}
+	}
+
+	// The inverse of 10mm along x then 90 degrees around z is -90 degrees
+	// around z, then 10mm along -y.
+	inverse := XyzWxyz{X: 10, Qw: rotationZ90.Qw, Qz: rotationZ90.Qz}.Inverse()
+	expected := XyzWxyz{Y: 10, Qw: rotationZ90.Qw, Qz: -rotationZ90.Qz}
+	if !posesEqual(inverse, expected, 1e-9) {
+		t.Errorf("Expected inverse %v. Got %v", expected, inverse)
+	}
+}
+
+func TestXyzWxyz_TransformPoint(t *testing.T) {
+	pose := XyzWxyz{X: 10, Y: 20, Z: 30, Qw: rotationZ90.Qw, Qz: rotationZ90.Qz}
+	if v := pose.RotateVector([3]float64{1, 0, 0}); !vectorsEqual(v, [3]float64{0, 1, 0}, 1e-12) {
+		t.Errorf("Rotating x by 90 degrees around z should be y. Got %v", v)
+	}
+	if p := pose.TransformPoint([3]float64{1, 2, 3}); !vectorsEqual(p, [3]float64{8, 21, 33}, 1e-12) {
+		t.Errorf("Expected (8, 21, 33). Got %v", p)
+	}
+	if p := pose.Inverse().TransformPoint(pose.TransformPoint([3]float64{1, 2, 3})); !vectorsEqual(p, [3]float64{1, 2, 3}, 1e-12) {
+		t.Errorf("Inverse should undo TransformPoint. Got %v", p)
+	}
+}
+
+func TestXyzWxyz_Matrix(t *testing.T) {
+	// Known rotation matrices, including 180 degree rotations around each axis,
+	// which use each branch of matrixToQuaterian.
+	testCases := []struct {
+		pose   XyzWxyz
+		matrix []float64
+	}{
+		{XyzWxyz{X: 1, Y: 2, Z: 3, Qw: 1}, []float64{1, 0, 0, 1, 0, 1, 0, 2, 0, 0, 1, 3, 0, 0, 0, 1}},
+		{rotationZ90, []float64{0, -1, 0, 0, 1, 0, 0, 0, 0, 0, 1, 0, 0, 0, 0, 1}},
+		{XyzWxyz{Qx: 1}, []float64{1, 0, 0, 0, 0, -1, 0, 0, 0, 0, -1, 0, 0, 0, 0, 1}},
+		{XyzWxyz{Qy: 1}, []float64{-1, 0, 0, 0, 0, 1, 0, 0, 0, 0, -1, 0, 0, 0, 0, 1}},
+		{XyzWxyz{Qz: 1}, []float64{-1, 0, 0, 0, 0, -1, 0, 0, 0, 0, 1, 0, 0, 0, 0, 1}},
+		{XyzWxyz{Qw: 0.5, Qx: 0.5, Qy: 0.5, Qz: 0.5}, []float64{0, 0, 1, 0, 1, 0, 0, 0, 0, 1, 0, 0, 0, 0, 0, 1}},
+	}
+	for _, testCase := range testCases {
+		expected := mat.NewDense(4, 4, testCase.matrix)
+		if !mat.EqualApprox(testCase.pose.Matrix(), expected, 1e-12) {
+			t.Errorf("Matrix of %v should be %v. Got %v", testCase.pose, mat.Formatted(expected), mat.Formatted(testCase.pose.Matrix()))
+		}
+		if !posesEqual(PoseFromMatrix(expected), testCase.pose, 1e-9) {
+			t.Errorf("PoseFromMatrix should be %v. Got %v", testCase.pose, PoseFromMatrix(expected))
+		}
+	}
+
+	// Rotations near 180 degrees around a tilted axis round trip through the
+	// last branch of matrixToQuaterian.
+	pose := PoseFromAngleAxis(3.1, [3]float64{0.1, 0.2, 1})
+	pose.X, pose.Y, pose.Z = -4, 5, 6
+	if !posesEqual(PoseFromMatrix(pose.Matrix()), pose, 1e-9) {
+		t.Errorf("Matrix should round trip %v. Got %v", pose, PoseFromMatrix(pose.Matrix()))
+	}
+}
+
+func TestXyzWxyz_AngleAxis(t *testing.T) {
+	testCases := []struct {
+		angle float64
+		axis  [3]float64
+		pose  XyzWxyz
+	}{
+		{math.Pi / 2, [3]float64{0, 0, 1}, rotationZ90},
+		{math.Pi, [3]float64{1, 0, 0}, XyzWxyz{Qx: 1}},
+		{2 * math.Pi / 3, [3]float64{1 / math.Sqrt(3), 1 / math.Sqrt(3), 1 / math.Sqrt(3)}, XyzWxyz{Qw: 0.5, Qx: 0.5, Qy: 0.5, Qz: 0.5}},
+		{0, [3]float64{1, 0, 0}, IdentityPose},
+	}
+	for _, testCase := range testCases {
+		pose := PoseFromAngleAxis(testCase.angle, testCase.axis)
+		if !posesEqual(pose, testCase.pose, 1e-9) {
+			t.Errorf("PoseFromAngleAxis(%f, %v) should be %v. Got %v", testCase.angle, testCase.axis, testCase.pose, pose)
+		}
+		angle, axis := testCase.pose.AngleAxis()
+		if math.Abs(angle-testCase.angle) > 1e-9 || !vectorsEqual(axis, testCase.axis, 1e-9) {
+			t.Errorf("AngleAxis of %v should be %f around %v. Got %f around %v", testCase.pose, testCase.angle, testCase.axis, angle, axis)
+		}
+	}
+
+	// Axes do not need to be unit length, and -q is the same rotation as q.
+	if !posesEqual(PoseFromAngleAxis(math.Pi/2, [3]float64{0, 0, 5}), rotationZ90, 1e-9) {
+		t.Errorf("Axis should be normalized")
+	}
+	angle, axis := XyzWxyz{Qw: -rotationZ90.Qw, Qz: -rotationZ90.Qz}.AngleAxis()
+	if math.Abs(angle-math.Pi/2) > 1e-9 || !vectorsEqual(axis, [3]float64{0, 0, 1}, 1e-9) {
+		t.Errorf("Negated quaternion should be 90 degrees around z. Got %f around %v", angle, axis)
+	}
+}
+
+func TestXyzWxyz_Distance(t *testing.T) {
+	a := XyzWxyz{X: 1, Y: 2, Z: 3, Qw: 1}
+	b := XyzWxyz{X: 4, Y: 6, Z: 3, Qw: rotationZ90.Qw, Qz: rotationZ90.Qz}
+	if a.Distance(b) != 5 {
+		t.Errorf("Expected a distance of 5. Got %f", a.Distance(b))
+	}
+	if math.Abs(a.Angle(b)-math.Pi/2) > 1e-12 {
+		t.Errorf("Expected an angle of pi/2. Got %f", a.Angle(b))
+	}
+	if a.Angle(XyzWxyz{Qw: -1}) != 0 {
+		t.Errorf("q and -q should be the same rotation. Got %f", a.Angle(XyzWxyz{Qw: -1}))
+	}
+	if math.Abs(a.Angle(XyzWxyz{Qx: 1})-math.Pi) > 1e-12 {
+		t.Errorf("Expected an angle of pi. Got %f", a.Angle(XyzWxyz{Qx: 1}))
+	}
+}
+
+func TestSlerp(t *testing.T) {
+	from := XyzWxyz{X: 0, Y: 0, Z: 0, Qw: 1}
+	to := XyzWxyz{X: 10, Y: -20, Z: 30, Qw: rotationZ90.Qw, Qz: rotationZ90.Qz}
+
+	if !posesEqual(Slerp(from, to, 0), from, 1e-12) || !posesEqual(Slerp(from, to, 1), to, 1e-12) {
+		t.Errorf("Slerp should start at from and end at to")
+	}
+	halfway := Slerp(from, to, 0.5)
+	expected := PoseFromAngleAxis(math.Pi/4, [3]float64{0, 0, 1})
+	expected.X, expected.Y, expected.Z = 5, -10, 15
+	if !posesEqual(halfway, expected, 1e-12) {
+		t.Errorf("Expected %v halfway. Got %v", expected, halfway)
+	}
+
+	// -q is the same rotation as q, so the path should not go the long way.
+	negated := to
+	negated.Qw, negated.Qz = -negated.Qw, -negated.Qz
+	if !posesEqual(Slerp(from, negated, 0.5), expected, 1e-12) {
+		t.Errorf("Slerp should take the shortest path. Got %v", Slerp(from, negated, 0.5))
+	}
+
+	// Rotation speed is constant along the path.
+	for _, step := range []float64{0.1, 0.3, 0.7, 0.9} {
+		angle := Slerp(from, to, step).Angle(from)
+		if math.Abs(angle-step*math.Pi/2) > 1e-12 {
+			t.Errorf("Expected an angle of %f at %f. Got %f", step*math.Pi/2, step, angle)
+		}
+	}
+
+	// Nearly identical rotations fall back to linear interpolation.
+	close := XyzWxyz{Qw: math.Cos(0.001), Qx: math.Sin(0.001)}
+	if math.Abs(Slerp(from, close, 0.5).Angle(from)-0.001) > 1e-9 {
+		t.Errorf("Expected an angle of 0.001. Got %f", Slerp(from, close, 0.5).Angle(from))
+	}
+}
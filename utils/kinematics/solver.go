@@ -0,0 +1,64 @@
+package kinematics
+
+import (
+	"errors"
+	"gonum.org/v1/gonum/optimize"
+	"math/rand"
+	"time"
+)
+
+// ErrNoConvergence is returned when the numeric inverse kinematics solver
+// cannot find joint angles within tolerance of the desired end effector.
+var ErrNoConvergence = errors.New("Inverse kinematics failed to converge")
+
+// IKOptions configure the numeric inverse kinematics solver.
+//
+// Rand is the source of random seeds used when the solver restarts. If Rand is
+// nil, the global math/rand source is used. Setting Rand makes the solver
+// deterministic. Tolerance is the largest residual accepted as a solution.
+// MaxRestarts is the number of random restarts tried after the first attempt
+// from the seed. TimeBudget limits the total time spent solving, and is
+// unlimited if 0. Method is the gonum optimizer used, and is chosen by gonum
+// if nil. If PositionOnly is true, the rotation of the end effector is
+// ignored, which is useful for arms with fewer than 6 joints.
+type IKOptions struct {
+	Rand         rand.Source
+	Tolerance    float64
+	MaxRestarts  int
+	TimeBudget   time.Duration
+	Method       optimize.Method
+	PositionOnly bool
+}
+
+// DefaultIKOptions are the options used by InverseKinematics and
+// InverseKinematicsFromSeed.
+var DefaultIKOptions = IKOptions{Tolerance: 0.000001, MaxRestarts: 100}
+
+// IKResult is the result of the numeric inverse kinematics solver, along with
+// diagnostics of how well it solved.
+//
+// Residual is the value of the objective function at Theta. Restarts is the
+// number of random restarts used. PositionError is the distance between the
+// reached and desired end effector position, and OrientationError is the angle
+// between the reached and desired end effector rotation in radians.
+type IKResult struct {
+	Theta            StepperTheta
+	Residual         float64
+	Restarts         int
+	PositionError    float64
+	OrientationError float64
+}
+
+// InverseKinematicsWithOptions calculates joint angles like
+// InverseKinematicsFromSeed, but with configurable options. It always returns
+// the best result found, so that the diagnostics are available even when the
+// solver fails.
+func InverseKinematicsWithOptions(desiredEndEffector XyzWxyz, dhParameters DhParameters, seed StepperTheta, options IKOptions) (IKResult, error) {
+	result, err := dhParameters.Chain().InverseKinematics(desiredEndEffector, seed.toFloat(), options)
+	ikResult := IKResult{Residual: result.Residual, Restarts: result.Restarts, PositionError: result.PositionError, OrientationError: result.OrientationError}
+	if len(result.Joints) == 6 {
+		r := result.Joints
+		ikResult.Theta = StepperTheta{r[0], r[1], r[2], r[3], r[4], r[5]}
+	}
+	return ikResult, err
+}
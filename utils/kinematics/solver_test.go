@@ -0,0 +1,75 @@
+package kinematics
+
+import (
+	"errors"
+	"gonum.org/v1/gonum/optimize"
+	"math/rand"
+	"testing"
+	"time"
+)
+
+func TestInverseKinematicsWithOptions_Deterministic(t *testing.T) {
+	desiredEndEffector := ForwardKinematics(StepperTheta{1.2, -2.1, 0.4, 2.6, -1.9, 0.7}, AR3DhParameters)
+	options := DefaultIKOptions
+	options.Rand = rand.NewSource(42)
+	first, err := InverseKinematicsWithOptions(desiredEndEffector, AR3DhParameters, StepperTheta{}, options)
+	if err != nil {
+		t.Fatalf("Inverse kinematics failed with error: %s", err)
+	}
+	options.Rand = rand.NewSource(42)
+	second, err := InverseKinematicsWithOptions(desiredEndEffector, AR3DhParameters, StepperTheta{}, options)
+	if err != nil {
+		t.Fatalf("Inverse kinematics failed with error: %s", err)
+	}
+	if first != second {
+		t.Errorf("Same random source gave different results: %v and %v", first, second)
+	}
+	if first.Residual > options.Tolerance {
+		t.Errorf("Residual %f is above tolerance", first.Residual)
+	}
+	if first.PositionError > 0.01 || first.OrientationError > 0.01 {
+		t.Errorf("Expected small errors. Got position error %f and orientation error %f", first.PositionError, first.OrientationError)
+	}
+}
+
+func TestInverseKinematicsWithOptions_Method(t *testing.T) {
+	thetas := StepperTheta{0.3, -1.2, 1.5, 0.4, 0.6, -0.2}
+	desiredEndEffector := ForwardKinematics(thetas, AR3DhParameters)
+	options := DefaultIKOptions
+	options.Method = &optimize.NelderMead{}
+	result, err := InverseKinematicsWithOptions(desiredEndEffector, AR3DhParameters, StepperTheta{0.3, -1.1, 1.4, 0.4, 0.6, -0.2}, options)
+	if err != nil {
+		t.Fatalf("Inverse kinematics failed with error: %s", err)
+	}
+	if result.Restarts != 0 {
+		t.Errorf("Expected no restarts from a nearby seed. Got %d", result.Restarts)
+	}
+}
+
+func TestInverseKinematicsWithOptions_OutOfReach(t *testing.T) {
+	desiredEndEffector := XyzWxyz{X: 5000, Qw: 1}
+	options := DefaultIKOptions
+	options.Rand = rand.NewSource(1)
+	options.MaxRestarts = 2
+	result, err := InverseKinematicsWithOptions(desiredEndEffector, AR3DhParameters, StepperTheta{}, options)
+	if !errors.Is(err, ErrNoConvergence) {
+		t.Errorf("Expected ErrNoConvergence. Got %v", err)
+	}
+	if result.Restarts != 2 {
+		t.Errorf("Expected 2 restarts. Got %d", result.Restarts)
+	}
+	if result.PositionError < 1000 {
+		t.Errorf("Expected a large position error. Got %f", result.PositionError)
+	}
+
+	options.MaxRestarts = 1000000
+	options.TimeBudget = 50 * time.Millisecond
+	start := time.Now()
+	_, err = InverseKinematicsWithOptions(desiredEndEffector, AR3DhParameters, StepperTheta{}, options)
+	if !errors.Is(err, ErrNoConvergence) {
+		t.Errorf("Expected ErrNoConvergence. Got %v", err)
+	}
+	if time.Since(start) > time.Second {
+		t.Errorf("Time budget of %s was not respected", options.TimeBudget)
+	}
+}
@@ -0,0 +1,396 @@
+package kinematics
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// ErrInvalidURDF is returned when a URDF file does not describe a serial arm.
+var ErrInvalidURDF = errors.New("Invalid URDF")
+
+// ErrNotSixJoint is returned when a URDFModel cannot be described by
+// DhParameters.
+var ErrNotSixJoint = errors.New("Arm is not 6 revolute joints described by DhParameters")
+
+// urdfScale converts URDF meters into millimeters.
+const urdfScale = 1000
+
+// URDFModel is a serial arm read from a URDF file, with lengths converted from
+// meters into millimeters.
+//
+// Chain is the arm in standard DH parameters, with the limits of each joint in
+// Min and Max. Joints are the names of the moving joints, in the order of the
+// joint positions of Chain. Links are the box, cylinder and sphere collision
+// geometry of each link of the arm, named after the link and attached to the
+// frames of Chain like in a CollisionModel. Allowed are the pairs of links that
+// meet at a joint.
+type URDFModel struct {
+	Name    string
+	Joints  []string
+	Chain   Chain
+	Links   []Geometry
+	Allowed [][2]string
+}
+
+// urdfRobot is the robot element of a URDF file.
+type urdfRobot struct {
+	Name   string      `xml:"name,attr"`
+	Links  []urdfLink  `xml:"link"`
+	Joints []urdfJoint `xml:"joint"`
+}
+
+type urdfLink struct {
+	Name       string          `xml:"name,attr"`
+	Collisions []urdfCollision `xml:"collision"`
+}
+
+type urdfCollision struct {
+	Origin   urdfOrigin `xml:"origin"`
+	Geometry struct {
+		Box *struct {
+			Size string `xml:"size,attr"`
+		} `xml:"box"`
+		Cylinder *struct {
+			Radius float64 `xml:"radius,attr"`
+			Length float64 `xml:"length,attr"`
+		} `xml:"cylinder"`
+		Sphere *struct {
+			Radius float64 `xml:"radius,attr"`
+		} `xml:"sphere"`
+	} `xml:"geometry"`
+}
+
+type urdfOrigin struct {
+	Xyz string `xml:"xyz,attr"`
+	Rpy string `xml:"rpy,attr"`
+}
+
+type urdfJoint struct {
+	Name   string `xml:"name,attr"`
+	Type   string `xml:"type,attr"`
+	Parent struct {
+		Link string `xml:"link,attr"`
+	} `xml:"parent"`
+	Child struct {
+		Link string `xml:"link,attr"`
+	} `xml:"child"`
+	Origin urdfOrigin `xml:"origin"`
+	Axis   *struct {
+		Xyz string `xml:"xyz,attr"`
+	} `xml:"axis"`
+	Limit *struct {
+		Lower float64 `xml:"lower,attr"`
+		Upper float64 `xml:"upper,attr"`
+	} `xml:"limit"`
+}
+
+// ParseURDF reads a serial arm from a URDF file. The arm runs from the root
+// link to the tip link, whose frame becomes the end effector. If tip is empty,
+// the branch with the most moving joints is followed wherever the tree of links
+// branches, so a tip should be given for arms with gripper fingers. Revolute,
+// continuous, prismatic and fixed joints are supported, and mesh collision
+// geometry is skipped.
+func ParseURDF(r io.Reader, tip string) (URDFModel, error) {
+	var robot urdfRobot
+	if err := xml.NewDecoder(r).Decode(&robot); err != nil {
+		return URDFModel{}, fmt.Errorf("%w: %s", ErrInvalidURDF, err)
+	}
+
+	// Index the tree of links
+	links := make(map[string]urdfLink)
+	for _, link := range robot.Links {
+		links[link.Name] = link
+	}
+	children := make(map[string][]urdfJoint)
+	parents := make(map[string]string)
+	for _, joint := range robot.Joints {
+		if _, ok := links[joint.Parent.Link]; !ok {
+			return URDFModel{}, fmt.Errorf("%w: joint %s has unknown parent link %s", ErrInvalidURDF, joint.Name, joint.Parent.Link)
+		}
+		if _, ok := links[joint.Child.Link]; !ok {
+			return URDFModel{}, fmt.Errorf("%w: joint %s has unknown child link %s", ErrInvalidURDF, joint.Name, joint.Child.Link)
+		}
+		if parent, ok := parents[joint.Child.Link]; ok {
+			return URDFModel{}, fmt.Errorf("%w: link %s is a child of both %s and %s", ErrInvalidURDF, joint.Child.Link, parent, joint.Parent.Link)
+		}
+		parents[joint.Child.Link] = joint.Parent.Link
+		children[joint.Parent.Link] = append(children[joint.Parent.Link], joint)
+	}
+	var roots []string
+	for _, link := range robot.Links {
+		if _, ok := parents[link.Name]; !ok {
+			roots = append(roots, link.Name)
+		}
+	}
+	if len(roots) != 1 {
+		return URDFModel{}, fmt.Errorf("%w: expected 1 root link. Got %d", ErrInvalidURDF, len(roots))
+	}
+
+	// Find the joints from the root link to the tip
+	var path []urdfJoint
+	if tip == "" {
+		for link := roots[0]; len(children[link]) > 0; link = path[len(path)-1].Child.Link {
+			joint := children[link][0]
+			for _, other := range children[link][1:] {
+				if movingDepth(other, children) > movingDepth(joint, children) {
+					joint = other
+				}
+			}
+			path = append(path, joint)
+		}
+	} else {
+		if _, ok := links[tip]; !ok {
+			return URDFModel{}, fmt.Errorf("%w: unknown tip link %s", ErrInvalidURDF, tip)
+		}
+		for link := tip; link != roots[0]; link = parents[link] {
+			for _, joint := range children[parents[link]] {
+				if joint.Child.Link == link {
+					path = append([]urdfJoint{joint}, path...)
+				}
+			}
+		}
+	}
+
+	// Walk from the root link to the tip, collecting the screw of each moving
+	// joint and the frame of each link with all joints at 0.
+	type placedLink struct {
+		link   urdfLink
+		frame  transform
+		moving int
+	}
+	model := URDFModel{Name: robot.Name}
+	var poe POEModel
+	var limits [][2]float64
+	frame := identityTransform()
+	placed := []placedLink{{link: links[roots[0]], frame: frame}}
+	for _, joint := range path {
+		origin, err := parseOrigin(joint.Origin)
+		if err != nil {
+			return URDFModel{}, fmt.Errorf("%w: origin of joint %s: %s", ErrInvalidURDF, joint.Name, err)
+		}
+		frame = frame.mul(transformOf(origin))
+
+		// Axes are in the frame of the joint and default to x
+		axis := [3]float64{1, 0, 0}
+		if joint.Axis != nil {
+			axis, err = parseVector(joint.Axis.Xyz)
+			if err != nil {
+				return URDFModel{}, fmt.Errorf("%w: axis of joint %s: %s", ErrInvalidURDF, joint.Name, err)
+			}
+		}
+		if norm3(axis) == 0 && joint.Type != "fixed" {
+			return URDFModel{}, fmt.Errorf("%w: joint %s has no axis", ErrInvalidURDF, joint.Name)
+		}
+		var direction [3]float64
+		if joint.Type != "fixed" {
+			direction = normalize3(mulVec3(frame.r, axis))
+		}
+
+		var lower, upper float64
+		if joint.Limit != nil {
+			lower, upper = joint.Limit.Lower, joint.Limit.Upper
+		}
+		switch joint.Type {
+		case "revolute":
+			poe.Screws = append(poe.Screws, Screw{Omega: direction, V: cross3(frame.p, direction)})
+			limits = append(limits, [2]float64{lower, upper})
+		case "continuous":
+			poe.Screws = append(poe.Screws, Screw{Omega: direction, V: cross3(frame.p, direction)})
+			limits = append(limits, [2]float64{})
+		case "prismatic":
+			poe.Screws = append(poe.Screws, Screw{V: direction})
+			limits = append(limits, [2]float64{lower * urdfScale, upper * urdfScale})
+		case "fixed":
+		default:
+			return URDFModel{}, fmt.Errorf("%w: joint %s has unsupported type %s", ErrInvalidURDF, joint.Name, joint.Type)
+		}
+		if joint.Type != "fixed" {
+			model.Joints = append(model.Joints, joint.Name)
+		}
+
+		model.Allowed = append(model.Allowed, [2]string{joint.Parent.Link, joint.Child.Link})
+		placed = append(placed, placedLink{link: links[joint.Child.Link], frame: frame, moving: len(poe.Screws)})
+	}
+	poe.Home = frame.xyzWxyz()
+
+	// Convert into standard DH parameters, keeping the joint limits
+	model.Chain = poe.Chain()
+	joint := 0
+	for i := range model.Chain {
+		if model.Chain[i].Type != Fixed {
+			model.Chain[i].Min, model.Chain[i].Max = limits[joint][0], limits[joint][1]
+			joint++
+		}
+	}
+
+	// Attach collision geometry to the DH frames. Frame i is the frame after
+	// joint i and any fixed links that follow it, which moves rigidly with
+	// every link between joint i and joint i+1.
+	frames := make([]transform, len(poe.Screws)+1)
+	frames[0] = identityTransform()
+	accumulator := identityTransform()
+	moving := 0
+	for _, link := range model.Chain {
+		accumulator = accumulator.mul(standardTransform(link.Theta, link.Alpha, link.A, link.D))
+		if link.Type != Fixed {
+			moving++
+		}
+		if moving > 0 {
+			frames[moving] = accumulator
+		}
+	}
+	for _, p := range placed {
+		for _, collision := range p.link.Collisions {
+			geometry, ok, err := parseGeometry(collision)
+			if err != nil {
+				return URDFModel{}, fmt.Errorf("%w: collision of link %s: %s", ErrInvalidURDF, p.link.Name, err)
+			}
+			if !ok {
+				continue
+			}
+			geometry.Name = p.link.Name
+			geometry.Frame = p.moving
+			geometry.Pose = frames[p.moving].xyzWxyz().Inverse().Mul(p.frame.xyzWxyz()).Mul(geometry.Pose)
+			model.Links = append(model.Links, geometry)
+		}
+	}
+	return model, nil
+}
+
+// DhParameters converts a URDFModel of 6 revolute joints into DhParameters.
+// The last joint may be followed by a rotation and offset along its axis,
+// which becomes part of the last joint. Any other fixed offset, like a base
+// that is not on the axis of the first joint, returns ErrNotSixJoint.
+func (model URDFModel) DhParameters() (DhParameters, error) {
+	chain := model.Chain
+	var last Link
+	if n := len(chain); n == 7 && chain[6].Type == Fixed && chain[5].Type != Fixed && math.Abs(chain[5].A) < analyticTolerance && math.Abs(wrapAngle(chain[5].Alpha)) < analyticTolerance && math.Abs(chain[6].A) < analyticTolerance && math.Abs(wrapAngle(chain[6].Alpha)) < analyticTolerance {
+		chain, last = chain[:6], chain[6]
+	}
+	if len(chain) != 6 {
+		return DhParameters{}, fmt.Errorf("%w. Got %d links", ErrNotSixJoint, len(chain))
+	}
+	var dh DhParameters
+	for i, link := range chain {
+		if link.Type != Revolute {
+			return DhParameters{}, fmt.Errorf("%w. Link %d is not revolute", ErrNotSixJoint, i+1)
+		}
+		dh.ThetaOffsets[i], dh.AlphaValues[i], dh.AValues[i], dh.DValues[i] = link.Theta, link.Alpha, link.A, link.D
+	}
+	dh.ThetaOffsets[5] = dh.ThetaOffsets[5] + last.Theta
+	dh.DValues[5] = dh.DValues[5] + last.D
+	return dh, nil
+}
+
+// JointLimits returns the limits of a URDFModel of 6 joints. Continuous joints
+// are limited to one turn in either direction.
+func (model URDFModel) JointLimits() (JointLimits, error) {
+	var minimums, maximums []float64
+	for _, link := range model.Chain {
+		if link.Type == Fixed {
+			continue
+		}
+		if link.Max > link.Min {
+			minimums, maximums = append(minimums, link.Min), append(maximums, link.Max)
+		} else {
+			minimums, maximums = append(minimums, -2*math.Pi), append(maximums, 2*math.Pi)
+		}
+	}
+	if len(minimums) != 6 {
+		return JointLimits{}, fmt.Errorf("%w. Got %d joints", ErrNotSixJoint, len(minimums))
+	}
+	return JointLimits{
+		Min: StepperTheta{minimums[0], minimums[1], minimums[2], minimums[3], minimums[4], minimums[5]},
+		Max: StepperTheta{maximums[0], maximums[1], maximums[2], maximums[3], maximums[4], maximums[5]},
+	}, nil
+}
+
+// CollisionModel returns the collision geometry of a URDFModel of 6 revolute
+// joints.
+func (model URDFModel) CollisionModel() (CollisionModel, error) {
+	dh, err := model.DhParameters()
+	if err != nil {
+		return CollisionModel{}, err
+	}
+	return CollisionModel{DhParameters: dh, Links: model.Links, Allowed: model.Allowed}, nil
+}
+
+// movingDepth returns the largest number of moving joints from a joint to any
+// tip of the tree, including the joint itself.
+func movingDepth(joint urdfJoint, children map[string][]urdfJoint) int {
+	depth := 0
+	for _, child := range children[joint.Child.Link] {
+		if d := movingDepth(child, children); d > depth {
+			depth = d
+		}
+	}
+	if joint.Type != "fixed" {
+		depth++
+	}
+	return depth
+}
+
+// parseGeometry converts URDF collision geometry into a Geometry. It returns
+// false for geometry that is not a box, cylinder or sphere.
+func parseGeometry(collision urdfCollision) (Geometry, bool, error) {
+	pose, err := parseOrigin(collision.Origin)
+	if err != nil {
+		return Geometry{}, false, err
+	}
+	shape := collision.Geometry
+	switch {
+	case shape.Box != nil:
+		size, err := parseVector(shape.Box.Size)
+		if err != nil {
+			return Geometry{}, false, err
+		}
+		return Geometry{Shape: Box, Pose: pose, Size: [3]float64{size[0] * urdfScale, size[1] * urdfScale, size[2] * urdfScale}}, true, nil
+	case shape.Cylinder != nil:
+		// URDF cylinders are centered on their origin, while a Cylinder
+		// starts at it.
+		length := shape.Cylinder.Length * urdfScale
+		pose = pose.Mul(XyzWxyz{Z: -length / 2, Qw: 1})
+		return Geometry{Shape: Cylinder, Pose: pose, Radius: shape.Cylinder.Radius * urdfScale, Length: length}, true, nil
+	case shape.Sphere != nil:
+		return Geometry{Shape: Sphere, Pose: pose, Radius: shape.Sphere.Radius * urdfScale}, true, nil
+	}
+	return Geometry{}, false, nil
+}
+
+// parseOrigin converts a URDF origin into a pose in millimeters.
+func parseOrigin(origin urdfOrigin) (XyzWxyz, error) {
+	xyz, err := parseVector(origin.Xyz)
+	if err != nil {
+		return XyzWxyz{}, err
+	}
+	rpy, err := parseVector(origin.Rpy)
+	if err != nil {
+		return XyzWxyz{}, err
+	}
+	return PoseFromEuler(xyz[0]*urdfScale, xyz[1]*urdfScale, xyz[2]*urdfScale, rpy, RPY)
+}
+
+// parseVector parses 3 space separated numbers. An empty string is zero.
+func parseVector(s string) ([3]float64, error) {
+	var vector [3]float64
+	fields := strings.Fields(s)
+	if len(fields) == 0 {
+		return vector, nil
+	}
+	if len(fields) != 3 {
+		return vector, fmt.Errorf("expected 3 numbers. Got %q", s)
+	}
+	for i, field := range fields {
+		value, err := strconv.ParseFloat(field, 64)
+		if err != nil {
+			return vector, err
+		}
+		vector[i] = value
+	}
+	return vector, nil
+}
@@ -0,0 +1,214 @@
+package kinematics
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+// ar3URDF writes the AR3 as a URDF, with a link frame after each joint like in
+// the URDF files of most arms. There is a sphere around the wrist center on
+// link_5 and a cylinder along the upper arm on link_2.
+func ar3URDF() string {
+	var b strings.Builder
+	b.WriteString(`<?xml version="1.0"?>
+<robot name="ar3">
+  <link name="base_link">
+    <collision>
+      <origin xyz="0 0 0.05"/>
+      <geometry><box size="0.24 0.24 0.1"/></geometry>
+    </collision>
+  </link>
+`)
+	origin := identityTransform()
+	parent := "base_link"
+	for i := 0; i < 6; i++ {
+		child := fmt.Sprintf("link_%d", i+1)
+		fmt.Fprintf(&b, "  <link name=%q>\n", child)
+		switch child {
+		case "link_2":
+			// The upper arm runs from J2 along x of link_2 to J3
+			b.WriteString(`    <collision>
+      <origin xyz="0.1525 0 0" rpy="0 1.5707963267948966 0"/>
+      <geometry><cylinder radius="0.045" length="0.305"/></geometry>
+    </collision>
+`)
+		case "link_5":
+			b.WriteString(`    <collision>
+      <geometry><sphere radius="0.035"/></geometry>
+    </collision>
+    <collision>
+      <geometry><mesh filename="package://ar3/meshes/link_5.stl"/></geometry>
+    </collision>
+`)
+		}
+		b.WriteString("  </link>\n")
+		writeURDFJoint(&b, fmt.Sprintf("joint_%d", i+1), "revolute", parent, child, origin, AR3JointLimits.Min.toFloat()[i], AR3JointLimits.Max.toFloat()[i])
+		origin = standardTransform(AR3DhParameters.ThetaOffsets[i], AR3DhParameters.AlphaValues[i], AR3DhParameters.AValues[i], AR3DhParameters.DValues[i])
+		parent = child
+	}
+	b.WriteString("  <link name=\"flange\"/>\n")
+	writeURDFJoint(&b, "joint_flange", "fixed", parent, "flange", origin, 0, 0)
+	b.WriteString("</robot>\n")
+	return b.String()
+}
+
+func writeURDFJoint(b *strings.Builder, name, jointType, parent, child string, origin transform, lower, upper float64) {
+	rpy, _ := origin.xyzWxyz().Euler(RPY)
+	fmt.Fprintf(b, "  <joint name=%q type=%q>\n    <parent link=%q/>\n    <child link=%q/>\n", name, jointType, parent, child)
+	fmt.Fprintf(b, "    <origin xyz=\"%.17g %.17g %.17g\" rpy=\"%.17g %.17g %.17g\"/>\n", origin.p[0]/1000, origin.p[1]/1000, origin.p[2]/1000, rpy[0], rpy[1], rpy[2])
+	if jointType != "fixed" {
+		fmt.Fprintf(b, "    <axis xyz=\"0 0 1\"/>\n    <limit lower=\"%.17g\" upper=\"%.17g\" effort=\"0\" velocity=\"0\"/>\n", lower, upper)
+	}
+	b.WriteString("  </joint>\n")
+}
+
+func TestParseURDF(t *testing.T) {
+	model, err := ParseURDF(strings.NewReader(ar3URDF()), "")
+	if err != nil {
+		t.Fatalf("ParseURDF failed with error: %s", err)
+	}
+	if model.Name != "ar3" || len(model.Joints) != 6 || model.Joints[5] != "joint_6" {
+		t.Errorf("Expected 6 joints of the ar3. Got %s %v", model.Name, model.Joints)
+	}
+	dh, err := model.DhParameters()
+	if err != nil {
+		t.Fatalf("DhParameters failed with error: %s", err)
+	}
+	limits, err := model.JointLimits()
+	if err != nil {
+		t.Fatalf("JointLimits failed with error: %s", err)
+	}
+	if limits.Min.toFloat()[1] != AR3JointLimits.Min.J2 || limits.Max.toFloat()[5] != AR3JointLimits.Max.J6 {
+		t.Errorf("Expected the limits of the AR3. Got %v", limits)
+	}
+
+	// The DH parameters found from the URDF are not the same as the
+	// AR3DhParameters, but they describe the same arm.
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < 20; i++ {
+		thetas := StepperTheta{r.Float64()*4 - 2, r.Float64()*4 - 2, r.Float64()*4 - 2, r.Float64()*4 - 2, r.Float64()*4 - 2, r.Float64()*4 - 2}
+		expected := ForwardKinematics(thetas, AR3DhParameters)
+		got := ForwardKinematics(thetas, dh)
+		if expected.Distance(got) > 1e-6 || expected.Angle(got) > 1e-6 {
+			t.Errorf("Expected %v at %v. Got %v", expected, thetas, got)
+		}
+	}
+}
+
+func TestParseURDF_Collision(t *testing.T) {
+	model, err := ParseURDF(strings.NewReader(ar3URDF()), "")
+	if err != nil {
+		t.Fatalf("ParseURDF failed with error: %s", err)
+	}
+	if len(model.Links) != 3 {
+		t.Fatalf("Expected a base, upper arm and wrist without the mesh. Got %v", model.Links)
+	}
+	collision, err := model.CollisionModel()
+	if err != nil {
+		t.Fatalf("CollisionModel failed with error: %s", err)
+	}
+	if len(collision.Allowed) != 7 || collision.Allowed[0] != [2]string{"base_link", "link_1"} {
+		t.Errorf("Expected every joint to allow its links to touch. Got %v", collision.Allowed)
+	}
+
+	// The wrist center is 36.25mm behind the flange, and the upper arm runs
+	// between the shoulder and the elbow.
+	thetas := StepperTheta{0.3, -1.2, 1.5, 0.4, 0.6, -0.2}
+	shapes := collision.place(thetas)
+	base, upperArm, wrist := shapes[0], shapes[1], shapes[2]
+	if base.shape != Box || math.Abs(base.pose.p[2]-50) > 1e-9 || math.Abs(base.half[0]-120) > 1e-9 {
+		t.Errorf("Expected the base box 50mm up. Got %v", base)
+	}
+	flange := ForwardKinematics(thetas, AR3DhParameters)
+	z := flange.RotateVector([3]float64{0, 0, 1})
+	center := [3]float64{flange.X + 36.25*z[0], flange.Y + 36.25*z[1], flange.Z + 36.25*z[2]}
+	if norm3(sub3(wrist.pose.p, center)) > 1e-6 || wrist.radius != 35 {
+		t.Errorf("Expected the wrist sphere at %v. Got %v", center, wrist)
+	}
+	chain := AR3DhParameters.Chain()
+	shoulder := chain[:1].ForwardKinematics([]float64{thetas.J1})
+	elbow := chain[:2].ForwardKinematics([]float64{thetas.J1, thetas.J2})
+	start, end := upperArm.boundingBox().pose.p, [3]float64{}
+	for i := range end {
+		end[i] = (shoulder.TransformPoint([3]float64{})[i] + elbow.TransformPoint([3]float64{})[i]) / 2
+	}
+	if upperArm.shape != Cylinder || upperArm.length != 305 || norm3(sub3(start, end)) > 1e-6 {
+		t.Errorf("Expected the upper arm centered at %v. Got %v", end, start)
+	}
+	if err := collision.CheckSelfCollision(StepperTheta{}); err != nil {
+		t.Errorf("Expected home to be clear. Got %s", err)
+	}
+}
+
+func TestParseURDF_Tip(t *testing.T) {
+	// A planar arm with a prismatic gripper finger and a tool point
+	urdf := `<robot name="planar">
+  <link name="base"/>
+  <link name="upper"/>
+  <link name="lower"/>
+  <link name="tool"/>
+  <link name="finger"/>
+  <joint name="shoulder" type="continuous">
+    <parent link="base"/><child link="upper"/>
+    <axis xyz="0 0 1"/>
+  </joint>
+  <joint name="elbow" type="revolute">
+    <parent link="upper"/><child link="lower"/>
+    <origin xyz="0.2 0 0"/>
+    <axis xyz="0 0 1"/>
+    <limit lower="-1" upper="1"/>
+  </joint>
+  <joint name="tool" type="fixed">
+    <parent link="lower"/><child link="tool"/>
+    <origin xyz="0.1 0 0"/>
+  </joint>
+  <joint name="finger" type="prismatic">
+    <parent link="lower"/><child link="finger"/>
+    <axis xyz="0 1 0"/>
+    <limit lower="0" upper="0.02"/>
+  </joint>
+</robot>`
+
+	// Without a tip, the finger is followed
+	model, err := ParseURDF(strings.NewReader(urdf), "")
+	if err != nil {
+		t.Fatalf("ParseURDF failed with error: %s", err)
+	}
+	if len(model.Joints) != 3 || model.Joints[2] != "finger" {
+		t.Errorf("Expected to follow the finger. Got %v", model.Joints)
+	}
+
+	model, err = ParseURDF(strings.NewReader(urdf), "tool")
+	if err != nil {
+		t.Fatalf("ParseURDF failed with error: %s", err)
+	}
+	if len(model.Joints) != 2 || model.Chain.DOF() != 2 {
+		t.Fatalf("Expected the shoulder and elbow. Got %v", model.Joints)
+	}
+	pose := model.Chain.ForwardKinematics([]float64{math.Pi / 2, -math.Pi / 2})
+	if math.Abs(pose.X-100) > 1e-9 || math.Abs(pose.Y-200) > 1e-9 {
+		t.Errorf("Expected the tool at (100, 200). Got %v", pose)
+	}
+	if _, err := model.DhParameters(); !errors.Is(err, ErrNotSixJoint) {
+		t.Errorf("Expected a planar arm to not have DhParameters. Got %v", err)
+	}
+
+	// Invalid files
+	for _, invalid := range []string{
+		`<robot`,
+		`<robot><link name="a"/><link name="b"/></robot>`,
+		`<robot><link name="a"/><link name="b"/><joint name="j" type="floating"><parent link="a"/><child link="b"/></joint></robot>`,
+		`<robot><link name="a"/><joint name="j" type="fixed"><parent link="a"/><child link="b"/></joint></robot>`,
+	} {
+		if _, err := ParseURDF(strings.NewReader(invalid), ""); !errors.Is(err, ErrInvalidURDF) {
+			t.Errorf("Expected %s to be invalid. Got %v", invalid, err)
+		}
+	}
+	if _, err := ParseURDF(strings.NewReader(urdf), "gripper"); !errors.Is(err, ErrInvalidURDF) {
+		t.Errorf("Expected an unknown tip to be invalid. Got %v", err)
+	}
+}
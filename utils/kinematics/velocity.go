@@ -0,0 +1,77 @@
+package kinematics
+
+import (
+	"gonum.org/v1/gonum/mat"
+	"math"
+)
+
+// Twist is the velocity of the end effector relative to the base of the arm.
+// Linear velocity is in millimeters per second and angular velocity is in
+// radians per second.
+type Twist struct {
+	Linear  [3]float64
+	Angular [3]float64
+}
+
+// VelocityOptions configure JointVelocities. Damping trades accuracy for
+// smaller joint velocities near singularities. MaxVelocity is the largest
+// velocity of each joint in radians per second, where 0 means unlimited.
+type VelocityOptions struct {
+	Damping     float64
+	MaxVelocity [6]float64
+}
+
+// DefaultVelocityOptions are good defaults for jogging and other resolved-rate
+// motion.
+var DefaultVelocityOptions = VelocityOptions{Damping: 0.05}
+
+// JointVelocities calculates the joint velocities, in radians per second,
+// that move the end effector at a twist from the current joint angles. This is
+// resolved-rate motion using damped least squares, so the joint velocities
+// stay bounded near singularities at the cost of not exactly following the
+// twist. If any joint would move faster than its maximum velocity, all joint
+// velocities are scaled down together so the end effector keeps its
+// direction.
+func JointVelocities(twist Twist, thetas StepperTheta, dhParameters DhParameters, options VelocityOptions) [6]float64 {
+	// Scale the linear rows like ConditionNumber, so that damping treats
+	// millimeters and radians alike.
+	jacobian := Jacobian(thetas, dhParameters)
+	length := armLength(dhParameters)
+	velocity := mat.NewVecDense(6, []float64{
+		twist.Linear[0] / length, twist.Linear[1] / length, twist.Linear[2] / length,
+		twist.Angular[0], twist.Angular[1], twist.Angular[2],
+	})
+	for i := 0; i < 3; i++ {
+		for j := 0; j < 6; j++ {
+			jacobian.Set(i, j, jacobian.At(i, j)/length)
+		}
+	}
+
+	// qdot = J^T (J J^T + damping^2 I)^-1 v
+	var damped mat.Dense
+	damped.Mul(jacobian, jacobian.T())
+	for i := 0; i < 6; i++ {
+		damped.Set(i, i, damped.At(i, i)+options.Damping*options.Damping)
+	}
+	var x, rates mat.VecDense
+	err := x.SolveVec(&damped, velocity)
+	if err != nil {
+		// With damping the matrix is always invertible, so this only
+		// happens with zero damping at an exact singularity.
+		return [6]float64{}
+	}
+	rates.MulVec(jacobian.T(), &x)
+
+	var jointVelocities [6]float64
+	scale := 1.0
+	for i := range jointVelocities {
+		jointVelocities[i] = rates.AtVec(i)
+		if options.MaxVelocity[i] > 0 {
+			scale = math.Min(scale, options.MaxVelocity[i]/math.Abs(jointVelocities[i]))
+		}
+	}
+	for i := range jointVelocities {
+		jointVelocities[i] = jointVelocities[i] * scale
+	}
+	return jointVelocities
+}
@@ -0,0 +1,59 @@
+package kinematics
+
+import (
+	"gonum.org/v1/gonum/mat"
+	"math"
+	"testing"
+)
+
+func TestJointVelocities(t *testing.T) {
+	thetas := StepperTheta{0.3, -1.2, 1.5, 0.4, 0.6, -0.2}
+	twist := Twist{Linear: [3]float64{10, -20, 30}, Angular: [3]float64{0.1, 0, -0.05}}
+	options := VelocityOptions{Damping: 0.001}
+	rates := JointVelocities(twist, thetas, AR3DhParameters, options)
+
+	// Far from singularities, the joint velocities should reproduce the twist.
+	var reached mat.VecDense
+	reached.MulVec(Jacobian(thetas, AR3DhParameters), mat.NewVecDense(6, rates[:]))
+	expected := []float64{10, -20, 30, 0.1, 0, -0.05}
+	for i, value := range expected {
+		if math.Abs(reached.AtVec(i)-value) > 0.01*math.Max(1, math.Abs(value)) {
+			t.Errorf("Expected twist component %d to be %f. Got %f", i, value, reached.AtVec(i))
+		}
+	}
+}
+
+func TestJointVelocities_MaxVelocity(t *testing.T) {
+	thetas := StepperTheta{0.3, -1.2, 1.5, 0.4, 0.6, -0.2}
+	twist := Twist{Linear: [3]float64{500, 0, 0}}
+	unlimited := JointVelocities(twist, thetas, AR3DhParameters, DefaultVelocityOptions)
+	options := DefaultVelocityOptions
+	for i := range options.MaxVelocity {
+		options.MaxVelocity[i] = 0.1
+	}
+	limited := JointVelocities(twist, thetas, AR3DhParameters, options)
+
+	// All joints are scaled together, so the ratio between them is kept.
+	scale := limited[0] / unlimited[0]
+	for i := range limited {
+		if math.Abs(limited[i]) > 0.1+1e-9 {
+			t.Errorf("Joint %d moves at %f, faster than its maximum velocity", i, limited[i])
+		}
+		if math.Abs(limited[i]-unlimited[i]*scale) > 1e-9 {
+			t.Errorf("Joint %d was not scaled with the other joints", i)
+		}
+	}
+}
+
+func TestJointVelocities_Singularity(t *testing.T) {
+	// At a wrist singularity, rotating about the missing axis should not
+	// produce unbounded joint velocities.
+	thetas := StepperTheta{0.3, -1.2, 1.5, 0.4, 0, -0.2}
+	twist := Twist{Angular: [3]float64{0.1, 0.1, 0.1}}
+	rates := JointVelocities(twist, thetas, AR3DhParameters, DefaultVelocityOptions)
+	for i, rate := range rates {
+		if math.IsNaN(rate) || math.Abs(rate) > 10 {
+			t.Errorf("Joint %d has an unbounded velocity of %f at a singularity", i, rate)
+		}
+	}
+}
@@ -0,0 +1,253 @@
+package kinematics
+
+import (
+	"encoding/csv"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"math"
+	"math/bits"
+	"math/rand"
+	"strconv"
+)
+
+// approachDirections are the directions the approach axis of the tool is
+// binned into, spread evenly over a sphere.
+const approachDirections = 64
+
+// WorkspaceOptions configure how a Workspace is sampled.
+//
+// Samples is the number of random joint angles sampled within Limits.
+// Resolution is the edge length of each cell of the map, in millimeters.
+// Frames are the base and tool offsets, so that the map is of the tool in the
+// world. Rand is the source of random joint angles. If Rand is nil, the global
+// math/rand source is used.
+type WorkspaceOptions struct {
+	Samples    int
+	Resolution float64
+	Limits     JointLimits
+	Frames     Frames
+	Rand       rand.Source
+}
+
+// Workspace is a reachability and dexterity map of an arm. Space is split into
+// cubic cells, and each cell records which directions the approach axis (z
+// axis) of the tool was found pointing in while inside of the cell.
+type Workspace struct {
+	Resolution float64
+	cells      map[[3]int]uint64
+	min        [3]int
+	max        [3]int
+}
+
+// Plane is a plane a Workspace can be sliced along.
+type Plane int
+
+const (
+	// PlaneXY is a horizontal slice at a height along z.
+	PlaneXY Plane = iota
+	// PlaneXZ is a vertical slice at an offset along y.
+	PlaneXZ
+	// PlaneYZ is a vertical slice at an offset along x.
+	PlaneYZ
+)
+
+// BuildWorkspace samples the joint space of an arm within its limits through
+// ForwardKinematics and builds a Workspace. More samples fill in more of the
+// edges of the workspace, where few joint angles reach. Samples and Resolution
+// must be positive.
+func BuildWorkspace(dhParameters DhParameters, options WorkspaceOptions) (Workspace, error) {
+	if options.Samples <= 0 || options.Resolution <= 0 {
+		return Workspace{}, fmt.Errorf("Workspace Samples and Resolution must be positive. Got %d and %f", options.Samples, options.Resolution)
+	}
+	randFloat := rand.Float64
+	if options.Rand != nil {
+		randFloat = rand.New(options.Rand).Float64
+	}
+	workspace := Workspace{Resolution: options.Resolution, cells: make(map[[3]int]uint64)}
+	for i := 0; i < options.Samples; i++ {
+		pose := options.Frames.ForwardKinematics(options.Limits.sample(randFloat), dhParameters)
+		workspace.add([3]float64{pose.X, pose.Y, pose.Z}, pose.RotateVector([3]float64{0, 0, 1}))
+	}
+	return workspace, nil
+}
+
+// add records that a point was reached with the tool approaching along a
+// direction.
+func (workspace *Workspace) add(point, direction [3]float64) {
+	cell := workspace.cell(point)
+	if len(workspace.cells) == 0 {
+		workspace.min, workspace.max = cell, cell
+	}
+	for i := range cell {
+		if cell[i] < workspace.min[i] {
+			workspace.min[i] = cell[i]
+		}
+		if cell[i] > workspace.max[i] {
+			workspace.max[i] = cell[i]
+		}
+	}
+	workspace.cells[cell] = workspace.cells[cell] | 1<<nearestDirection(direction)
+}
+
+// cell returns the index of the cell containing a point.
+func (workspace Workspace) cell(point [3]float64) [3]int {
+	return [3]int{
+		int(math.Floor(point[0] / workspace.Resolution)),
+		int(math.Floor(point[1] / workspace.Resolution)),
+		int(math.Floor(point[2] / workspace.Resolution)),
+	}
+}
+
+// Reachable checks if any sample reached the cell containing a point.
+func (workspace Workspace) Reachable(point [3]float64) bool {
+	return workspace.cells[workspace.cell(point)] != 0
+}
+
+// ReachableFrom checks if any sample reached the cell containing a point with
+// the approach axis of the tool within tolerance radians of a direction. For
+// example, a direction of (0, 0, -1) checks if the tool can point down at the
+// point. Directions are binned about 0.45 radians apart, so tolerances below
+// that may miss directions that were reached.
+func (workspace Workspace) ReachableFrom(point, direction [3]float64, tolerance float64) bool {
+	mask := workspace.cells[workspace.cell(point)]
+	direction = normalize3(direction)
+	for i := 0; i < approachDirections; i++ {
+		if mask&(1<<i) != 0 && math.Acos(math.Min(1, dot3(approachDirection(i), direction))) <= tolerance {
+			return true
+		}
+	}
+	return false
+}
+
+// Dexterity returns the fraction of approach directions, from 0 to 1, that the
+// tool was found pointing in within the cell containing a point. Cells that
+// cannot be reached have a dexterity of 0.
+func (workspace Workspace) Dexterity(point [3]float64) float64 {
+	return float64(bits.OnesCount64(workspace.cells[workspace.cell(point)])) / approachDirections
+}
+
+// Bounds returns the corners of the box containing every reached cell.
+func (workspace Workspace) Bounds() ([3]float64, [3]float64) {
+	var min, max [3]float64
+	for i := range min {
+		min[i] = float64(workspace.min[i]) * workspace.Resolution
+		max[i] = float64(workspace.max[i]+1) * workspace.Resolution
+	}
+	return min, max
+}
+
+// Slice returns the dexterity of every cell of a plane at an offset along the
+// remaining axis, covering the bounds of the workspace. Rows run along the
+// second axis of the plane and columns along the first, so that Slice(PlaneXY,
+// z)[y][x] is the dexterity at (x, y, z). It also returns the position of the
+// corner of the first cell along the two axes of the plane. An empty workspace
+// has an empty slice.
+func (workspace Workspace) Slice(plane Plane, offset float64) ([][]float64, [2]float64) {
+	var slice [][]float64
+	if len(workspace.cells) == 0 {
+		return slice, [2]float64{}
+	}
+	u, v, w := plane.axes()
+	cell := workspace.cell([3]float64{offset, offset, offset})
+	for j := workspace.min[v]; j <= workspace.max[v]; j++ {
+		var row []float64
+		for i := workspace.min[u]; i <= workspace.max[u]; i++ {
+			var index [3]int
+			index[u], index[v], index[w] = i, j, cell[w]
+			row = append(row, float64(bits.OnesCount64(workspace.cells[index]))/approachDirections)
+		}
+		slice = append(slice, row)
+	}
+	return slice, [2]float64{float64(workspace.min[u]) * workspace.Resolution, float64(workspace.min[v]) * workspace.Resolution}
+}
+
+// WriteCSV writes a slice of the workspace as CSV, with one row per cell. The
+// columns are the position of the center of the cell along the two axes of the
+// plane, and its dexterity.
+func (workspace Workspace) WriteCSV(w io.Writer, plane Plane, offset float64) error {
+	slice, corner := workspace.Slice(plane, offset)
+	names := [3]string{"x", "y", "z"}
+	u, v, _ := plane.axes()
+	writer := csv.NewWriter(w)
+	err := writer.Write([]string{names[u], names[v], "dexterity"})
+	if err != nil {
+		return err
+	}
+	for j, row := range slice {
+		for i, dexterity := range row {
+			err = writer.Write([]string{
+				strconv.FormatFloat(corner[0]+(float64(i)+0.5)*workspace.Resolution, 'f', -1, 64),
+				strconv.FormatFloat(corner[1]+(float64(j)+0.5)*workspace.Resolution, 'f', -1, 64),
+				strconv.FormatFloat(dexterity, 'f', -1, 64),
+			})
+			if err != nil {
+				return err
+			}
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// WritePNG writes a slice of the workspace as a grayscale PNG image, with one
+// pixel per cell. Black cells cannot be reached, and brighter cells can be
+// reached from more directions. The first axis of the plane runs to the right
+// and the second runs up, so PlaneXY is seen from above.
+func (workspace Workspace) WritePNG(w io.Writer, plane Plane, offset float64) error {
+	slice, _ := workspace.Slice(plane, offset)
+	if len(slice) == 0 {
+		return fmt.Errorf("Workspace is empty")
+	}
+	img := image.NewGray(image.Rect(0, 0, len(slice[0]), len(slice)))
+	for j, row := range slice {
+		for i, dexterity := range row {
+			img.SetGray(i, len(slice)-1-j, color.Gray{Y: uint8(math.Round(dexterity * 255))})
+		}
+	}
+	return png.Encode(w, img)
+}
+
+// axes returns the indexes of the two axes of a plane and the axis normal to
+// it.
+func (plane Plane) axes() (int, int, int) {
+	switch plane {
+	case PlaneXZ:
+		return 0, 2, 1
+	case PlaneYZ:
+		return 1, 2, 0
+	default:
+		return 0, 1, 2
+	}
+}
+
+// approachDirection returns one of approachDirections directions spread over a
+// sphere with the Fibonacci lattice.
+func approachDirection(i int) [3]float64 {
+	z := 1 - (2*float64(i)+1)/approachDirections
+	r := math.Sqrt(1 - z*z)
+	angle := float64(i) * math.Pi * (3 - math.Sqrt(5))
+	return [3]float64{r * math.Cos(angle), r * math.Sin(angle), z}
+}
+
+// nearestDirection returns the index of the approach direction nearest to a
+// unit vector.
+func nearestDirection(direction [3]float64) int {
+	nearest, best := 0, math.Inf(-1)
+	for i := 0; i < approachDirections; i++ {
+		if d := dot3(approachDirection(i), direction); d > best {
+			nearest, best = i, d
+		}
+	}
+	return nearest
+}
+
+// PoseReachable checks exactly if a tool pose in the world can be reached
+// within the joint limits, by solving for it with InverseKinematicsWithinLimits.
+// Use it to confirm a point found with a Workspace.
+func PoseReachable(pose XyzWxyz, dhParameters DhParameters, limits JointLimits, frames Frames) bool {
+	solutions, err := InverseKinematicsWithinLimits(frames.FlangePose(pose), dhParameters, limits)
+	return err == nil && len(solutions) > 0
+}
@@ -0,0 +1,112 @@
+package kinematics
+
+import (
+	"bytes"
+	"encoding/csv"
+	"image/png"
+	"math"
+	"math/rand"
+	"testing"
+)
+
+// gripper is a tool frame whose approach axis points out of the AR3 flange.
+// The z axis of the AR3 flange points back into the arm.
+var gripper = Frames{Tool: PoseFromAngleAxis(math.Pi, [3]float64{0, 1, 0})}
+
+func TestBuildWorkspace(t *testing.T) {
+	workspace, err := BuildWorkspace(AR3DhParameters, WorkspaceOptions{Samples: 50000, Resolution: 100, Limits: AR3JointLimits, Frames: gripper, Rand: rand.NewSource(1)})
+	if err != nil {
+		t.Fatalf("BuildWorkspace failed with error: %s", err)
+	}
+
+	// A point in front of the arm can be reached with the tool pointing down,
+	// and a point 2m away cannot be reached at all.
+	front := [3]float64{300, 0, 200}
+	if !workspace.Reachable(front) {
+		t.Errorf("Expected %v to be reachable", front)
+	}
+	if !workspace.ReachableFrom(front, [3]float64{0, 0, -1}, 0.5) {
+		t.Errorf("Expected %v to be reachable with the tool pointing down", front)
+	}
+	if dexterity := workspace.Dexterity(front); dexterity <= 0 || dexterity > 1 {
+		t.Errorf("Expected a dexterity between 0 and 1. Got %f", dexterity)
+	}
+	far := [3]float64{2000, 0, 0}
+	if workspace.Reachable(far) || workspace.Dexterity(far) != 0 {
+		t.Errorf("Expected %v to be unreachable", far)
+	}
+
+	// The bounds are within the length of the arm
+	min, max := workspace.Bounds()
+	for i := range min {
+		if min[i] < -1000 || max[i] > 1000 || min[i] >= max[i] {
+			t.Errorf("Unexpected bounds %v to %v", min, max)
+		}
+	}
+
+	// The same source builds the same workspace
+	again, _ := BuildWorkspace(AR3DhParameters, WorkspaceOptions{Samples: 50000, Resolution: 100, Limits: AR3JointLimits, Frames: gripper, Rand: rand.NewSource(1)})
+	if len(again.cells) != len(workspace.cells) {
+		t.Errorf("Expected the same workspace from the same source")
+	}
+
+	// Samples and Resolution must be positive
+	for _, options := range []WorkspaceOptions{{Samples: 0, Resolution: 100}, {Samples: 100, Resolution: 0}, {Samples: 100, Resolution: -1}} {
+		if _, err := BuildWorkspace(AR3DhParameters, options); err == nil {
+			t.Errorf("Expected %v to fail", options)
+		}
+	}
+}
+
+func TestWorkspace_Export(t *testing.T) {
+	workspace, err := BuildWorkspace(AR3DhParameters, WorkspaceOptions{Samples: 5000, Resolution: 100, Limits: AR3JointLimits, Rand: rand.NewSource(1)})
+	if err != nil {
+		t.Fatalf("BuildWorkspace failed with error: %s", err)
+	}
+	slice, _ := workspace.Slice(PlaneXZ, 0)
+	rows, columns := len(slice), len(slice[0])
+
+	var buffer bytes.Buffer
+	if err := workspace.WriteCSV(&buffer, PlaneXZ, 0); err != nil {
+		t.Fatalf("WriteCSV failed with error: %s", err)
+	}
+	records, err := csv.NewReader(&buffer).ReadAll()
+	if err != nil {
+		t.Fatalf("Failed to read CSV: %s", err)
+	}
+	if header := records[0]; header[0] != "x" || header[1] != "z" || header[2] != "dexterity" {
+		t.Errorf("Unexpected header %v", header)
+	}
+	if len(records) != rows*columns+1 {
+		t.Errorf("Expected %d rows. Got %d", rows*columns+1, len(records))
+	}
+
+	buffer.Reset()
+	if err := workspace.WritePNG(&buffer, PlaneXZ, 0); err != nil {
+		t.Fatalf("WritePNG failed with error: %s", err)
+	}
+	img, err := png.Decode(&buffer)
+	if err != nil {
+		t.Fatalf("Failed to decode PNG: %s", err)
+	}
+	if bounds := img.Bounds(); bounds.Dx() != columns || bounds.Dy() != rows {
+		t.Errorf("Expected a %dx%d image. Got %v", columns, rows, bounds)
+	}
+
+	if err := (Workspace{Resolution: 1}).WritePNG(&buffer, PlaneXY, 0); err == nil {
+		t.Errorf("Empty workspace should fail to write")
+	}
+}
+
+func TestPoseReachable(t *testing.T) {
+	// Gripper pointing straight down, turned so that J6 stays within its limits
+	down := PoseFromAngleAxis(math.Pi, [3]float64{1, 0, 0})
+	down.X, down.Y, down.Z = 300, 0, 200
+	if !PoseReachable(down, AR3DhParameters, AR3JointLimits, gripper) {
+		t.Errorf("Expected %v to be reachable", down)
+	}
+	down.X = 2000
+	if PoseReachable(down, AR3DhParameters, AR3JointLimits, gripper) {
+		t.Errorf("Expected %v to be unreachable", down)
+	}
+}
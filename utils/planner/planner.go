@@ -0,0 +1,405 @@
+/*
+Package planner finds collision-free joint-space paths for robotic arms.
+
+Paths are planned with RRT-Connect, which grows a tree of valid joint angles
+from both the start and the goal until the two trees meet. The path is then
+shortened by replacing parts of it with straight lines in joint space wherever
+they are valid. Which joint angles are valid is up to the caller, usually
+through CollisionCheck.
+
+Plan (start + goal joint angles	-> joint angles along a collision-free path)
+
+PlanToPose (start joint angles + goal xyzwxyz	-> joint angles along a collision-free path)
+
+PlanBetweenPoses (start + goal xyzwxyz	-> joint angles along a collision-free path)
+
+Joints move linearly between the joint angles of a path, so a path can be
+followed with the trajectory package.
+*/
+package planner
+
+import (
+	"errors"
+	"fmt"
+	"github.com/koeng101/armos/utils/kinematics"
+	"math"
+	"math/rand"
+	"time"
+)
+
+// ErrNoPath is returned when no path is found within the iteration and time
+// limits.
+var ErrNoPath = errors.New("No collision-free path found")
+
+// ErrInvalidOptions is returned when Options cannot be planned with.
+var ErrInvalidOptions = errors.New("Invalid planner options")
+
+// Options configure the planner.
+//
+// Limits are the joint limits that every joint angle of the path stays within.
+// Check returns an error if joint angles are not valid, and every joint angle
+// is valid if it is nil. StepSize is the largest distance, in radians across
+// all joints, that a tree grows at a time. CheckStep is the largest change of
+// any joint, in radians, between joint angles checked along a straight line.
+// Frames are the base and tool offsets of the arm, used to solve goal poses.
+// StepSize, CheckStep and MaxIterations must be positive.
+//
+// MaxIterations limits how many random joint angles are sampled, and Timeout
+// limits the time spent planning. A Timeout of 0 is unlimited. Shortcuts is
+// the number of random shortcuts tried once a path is found. Rand is the
+// source of random joint angles. If Rand is nil, the global math/rand source
+// is used. Setting Rand makes the planner deterministic, as long as it does
+// not time out.
+type Options struct {
+	Limits        kinematics.JointLimits
+	Check         func(kinematics.StepperTheta) error
+	StepSize      float64
+	CheckStep     float64
+	Frames        kinematics.Frames
+	MaxIterations int
+	Timeout       time.Duration
+	Shortcuts     int
+	Rand          rand.Source
+}
+
+// DefaultOptions are good defaults for the AR3. Check must still be set to
+// avoid collisions.
+var DefaultOptions = Options{
+	Limits:        kinematics.AR3JointLimits,
+	StepSize:      0.3,
+	CheckStep:     2 * math.Pi / 180,
+	MaxIterations: 10000,
+	Timeout:       5 * time.Second,
+	Shortcuts:     100,
+}
+
+// CollisionCheck returns a Check that rejects joint angles where an arm
+// collides with itself or with any of the obstacles.
+func CollisionCheck(model kinematics.CollisionModel, obstacles []kinematics.Geometry) func(kinematics.StepperTheta) error {
+	return func(thetas kinematics.StepperTheta) error {
+		err := model.CheckSelfCollision(thetas)
+		if err != nil {
+			return err
+		}
+		return model.CheckCollision(thetas, obstacles)
+	}
+}
+
+// Plan finds a collision-free path from the start to the goal joint angles.
+// The path starts with start and ends with goal.
+func Plan(start, goal kinematics.StepperTheta, options Options) ([]kinematics.StepperTheta, error) {
+	p, err := newPlanner(options)
+	if err != nil {
+		return nil, err
+	}
+	err = p.valid(start)
+	if err != nil {
+		return nil, fmt.Errorf("Start is not valid: %w", err)
+	}
+	err = p.valid(goal)
+	if err != nil {
+		return nil, fmt.Errorf("Goal is not valid: %w", err)
+	}
+	return p.plan([]joints{toJoints(start)}, []joints{toJoints(goal)})
+}
+
+// PlanToPose finds a collision-free path from the start joint angles to a
+// tool pose in the world. Every valid inverse kinematics solution of the goal
+// is tried at once, and the path ends at whichever is reached first.
+func PlanToPose(start kinematics.StepperTheta, goal kinematics.XyzWxyz, dhParameters kinematics.DhParameters, options Options) ([]kinematics.StepperTheta, error) {
+	p, err := newPlanner(options)
+	if err != nil {
+		return nil, err
+	}
+	err = p.valid(start)
+	if err != nil {
+		return nil, fmt.Errorf("Start is not valid: %w", err)
+	}
+	goals, err := p.solve(goal, dhParameters)
+	if err != nil {
+		return nil, fmt.Errorf("Goal is not valid: %w", err)
+	}
+	return p.plan([]joints{toJoints(start)}, goals)
+}
+
+// PlanBetweenPoses finds a collision-free path between two tool poses in the
+// world, like PlanToPose. Every valid inverse kinematics solution of the start
+// is tried as well, so the path may start from any of them.
+func PlanBetweenPoses(start, goal kinematics.XyzWxyz, dhParameters kinematics.DhParameters, options Options) ([]kinematics.StepperTheta, error) {
+	p, err := newPlanner(options)
+	if err != nil {
+		return nil, err
+	}
+	starts, err := p.solve(start, dhParameters)
+	if err != nil {
+		return nil, fmt.Errorf("Start is not valid: %w", err)
+	}
+	goals, err := p.solve(goal, dhParameters)
+	if err != nil {
+		return nil, fmt.Errorf("Goal is not valid: %w", err)
+	}
+	return p.plan(starts, goals)
+}
+
+/******************************************************************************
+
+                                RRT-Connect
+
+******************************************************************************/
+
+// joints are joint angles as an array, which is easier to do math on.
+type joints [6]float64
+
+func toJoints(thetas kinematics.StepperTheta) joints {
+	return joints{thetas.J1, thetas.J2, thetas.J3, thetas.J4, thetas.J5, thetas.J6}
+}
+
+func (q joints) theta() kinematics.StepperTheta {
+	return kinematics.StepperTheta{J1: q[0], J2: q[1], J3: q[2], J4: q[3], J5: q[4], J6: q[5]}
+}
+
+// distance is the Euclidean distance between joint angles.
+func distance(a, b joints) float64 {
+	sum := 0.0
+	for i := range a {
+		sum = sum + (a[i]-b[i])*(a[i]-b[i])
+	}
+	return math.Sqrt(sum)
+}
+
+// node is joint angles in a tree, with the index of its parent. Roots have a
+// parent of -1.
+type node struct {
+	q      joints
+	parent int
+}
+
+// tree is a tree of valid joint angles grown from one or more roots.
+type tree []node
+
+// path returns the joint angles from a root to a node of the tree.
+func (t tree) path(i int) []joints {
+	var path []joints
+	for ; i >= 0; i = t[i].parent {
+		path = append([]joints{t[i].q}, path...)
+	}
+	return path
+}
+
+// nearest returns the index of the node nearest to joint angles.
+func (t tree) nearest(q joints) int {
+	nearest, best := 0, math.Inf(1)
+	for i, n := range t {
+		if d := distance(n.q, q); d < best {
+			nearest, best = i, d
+		}
+	}
+	return nearest
+}
+
+// extension is the result of growing a tree towards joint angles.
+type extension int
+
+const (
+	trapped extension = iota
+	advanced
+	reached
+)
+
+// planner holds the options and random source of a single plan.
+type planner struct {
+	options   Options
+	randFloat func() float64
+	deadline  time.Time
+	min       joints
+	max       joints
+}
+
+func newPlanner(options Options) (*planner, error) {
+	if options.StepSize <= 0 || options.CheckStep <= 0 || options.MaxIterations <= 0 {
+		return nil, fmt.Errorf("%w. StepSize, CheckStep and MaxIterations must be positive. Got %f, %f and %d", ErrInvalidOptions, options.StepSize, options.CheckStep, options.MaxIterations)
+	}
+	p := &planner{options: options, randFloat: rand.Float64, min: toJoints(options.Limits.Min), max: toJoints(options.Limits.Max)}
+	if options.Rand != nil {
+		p.randFloat = rand.New(options.Rand).Float64
+	}
+	if options.Timeout > 0 {
+		p.deadline = time.Now().Add(options.Timeout)
+	}
+	return p, nil
+}
+
+// timedOut checks if the planner has run out of time.
+func (p *planner) timedOut() bool {
+	return !p.deadline.IsZero() && time.Now().After(p.deadline)
+}
+
+// valid checks that joint angles are within the limits and pass Check.
+func (p *planner) valid(thetas kinematics.StepperTheta) error {
+	if !p.options.Limits.Contains(thetas) {
+		return fmt.Errorf("Joint angles %v are outside of the joint limits", thetas)
+	}
+	if p.options.Check != nil {
+		return p.options.Check(thetas)
+	}
+	return nil
+}
+
+// validEdge checks that every joint angle along a straight line is valid. The
+// start of the line is assumed to be valid.
+func (p *planner) validEdge(from, to joints) bool {
+	step := 0.0
+	for i := range from {
+		step = math.Max(step, math.Abs(to[i]-from[i]))
+	}
+	steps := int(math.Ceil(step / p.options.CheckStep))
+	for s := 1; s <= steps; s++ {
+		var q joints
+		for i := range q {
+			q[i] = from[i] + (to[i]-from[i])*float64(s)/float64(steps)
+		}
+		if p.valid(q.theta()) != nil {
+			return false
+		}
+	}
+	return true
+}
+
+// solve returns every valid inverse kinematics solution of a tool pose.
+func (p *planner) solve(pose kinematics.XyzWxyz, dhParameters kinematics.DhParameters) ([]joints, error) {
+	solutions, err := kinematics.InverseKinematicsWithinLimits(p.options.Frames.FlangePose(pose), dhParameters, p.options.Limits)
+	if err != nil {
+		return nil, err
+	}
+	var valid []joints
+	for _, solution := range solutions {
+		err = p.valid(solution)
+		if err == nil {
+			valid = append(valid, toJoints(solution))
+		}
+	}
+	if len(valid) == 0 {
+		return nil, fmt.Errorf("No valid inverse kinematics solution: %w", err)
+	}
+	return valid, nil
+}
+
+// sample returns random joint angles within the limits.
+func (p *planner) sample() joints {
+	var q joints
+	for i := range q {
+		q[i] = p.min[i] + (p.max[i]-p.min[i])*p.randFloat()
+	}
+	return q
+}
+
+// extend grows a tree one step towards joint angles.
+func (p *planner) extend(t *tree, q joints) extension {
+	nearest := t.nearest(q)
+	from := (*t)[nearest].q
+	to := q
+	result := reached
+	if d := distance(from, q); d > p.options.StepSize {
+		for i := range to {
+			to[i] = from[i] + (q[i]-from[i])*p.options.StepSize/d
+		}
+		result = advanced
+	}
+	if !p.validEdge(from, to) {
+		return trapped
+	}
+	*t = append(*t, node{q: to, parent: nearest})
+	return result
+}
+
+// connect grows a tree towards joint angles until it reaches them, is
+// trapped, or the planner runs out of time.
+func (p *planner) connect(t *tree, q joints) extension {
+	result := advanced
+	for result == advanced && !p.timedOut() {
+		result = p.extend(t, q)
+	}
+	return result
+}
+
+// plan grows trees from the starts and goals until they meet, and then
+// shortens the path between them.
+func (p *planner) plan(starts, goals []joints) ([]kinematics.StepperTheta, error) {
+	var a, b tree
+	for _, q := range starts {
+		a = append(a, node{q: q, parent: -1})
+	}
+	for _, q := range goals {
+		b = append(b, node{q: q, parent: -1})
+	}
+
+	// Try a straight line first
+	for i, start := range starts {
+		for j, goal := range goals {
+			if p.validEdge(start, goal) {
+				return p.shortcut(append(a.path(i), b.path(j)...)), nil
+			}
+		}
+	}
+
+	// Grow each tree towards random joint angles in turn, and then try to
+	// connect the other tree to the new joint angles.
+	grow, other := &a, &b
+	for i := 0; i < p.options.MaxIterations; i++ {
+		if p.timedOut() {
+			return nil, fmt.Errorf("%w within timeout of %s", ErrNoPath, p.options.Timeout)
+		}
+		if p.extend(grow, p.sample()) != trapped {
+			q := (*grow)[len(*grow)-1].q
+			if p.connect(other, q) == reached {
+				path := append(a.path(len(a)-1), reverse(b.path(len(b)-1))...)
+				return p.shortcut(path), nil
+			}
+		}
+		grow, other = other, grow
+	}
+	return nil, fmt.Errorf("%w within %d iterations", ErrNoPath, p.options.MaxIterations)
+}
+
+// shortcut shortens a path by replacing parts of it with straight lines
+// between random pairs of joint angles along it, and then removes joint angles
+// that can be skipped. Shortcuts stop early if the planner runs out of time.
+func (p *planner) shortcut(path []joints) []kinematics.StepperTheta {
+	// Both trees end at the joint angles where they meet
+	for i := 1; i < len(path); i++ {
+		if path[i] == path[i-1] {
+			path = append(path[:i], path[i+1:]...)
+			i--
+		}
+	}
+	for s := 0; s < p.options.Shortcuts && len(path) > 2 && !p.timedOut(); s++ {
+		i := int(p.randFloat() * float64(len(path)))
+		j := int(p.randFloat() * float64(len(path)))
+		if i > j {
+			i, j = j, i
+		}
+		if j-i > 1 && p.validEdge(path[i], path[j]) {
+			path = append(path[:i+1], path[j:]...)
+		}
+	}
+	for i := 0; i+2 < len(path); {
+		if p.validEdge(path[i], path[i+2]) {
+			path = append(path[:i+1], path[i+2:]...)
+		} else {
+			i++
+		}
+	}
+	thetas := make([]kinematics.StepperTheta, len(path))
+	for i, q := range path {
+		thetas[i] = q.theta()
+	}
+	return thetas
+}
+
+// reverse reverses joint angles in place.
+func reverse(path []joints) []joints {
+	for i, j := 0, len(path)-1; i < j; i, j = i+1, j-1 {
+		path[i], path[j] = path[j], path[i]
+	}
+	return path
+}
@@ -0,0 +1,135 @@
+package planner
+
+import (
+	"errors"
+	"github.com/koeng101/armos/utils/kinematics"
+	"math"
+	"math/rand"
+	"testing"
+	"time"
+)
+
+// rack is a vial rack in front of the AR3, below its elbow.
+var rack = kinematics.Geometry{Name: "rack", Shape: kinematics.Cylinder, Pose: kinematics.XyzWxyz{X: 370}, Radius: 50, Length: 80}
+
+func TestPlan(t *testing.T) {
+	// Swing the arm across the rack with the forearm pointing down
+	model := kinematics.AR3CollisionModel
+	obstacles := []kinematics.Geometry{rack}
+	start := kinematics.StepperTheta{J1: -1.5, J2: -0.3, J3: math.Pi / 2}
+	goal := kinematics.StepperTheta{J1: 1.5, J2: -0.3, J3: math.Pi / 2}
+	if model.CheckCollisionAlong([]kinematics.StepperTheta{start, goal}, obstacles, 0.01) == nil {
+		t.Fatalf("Expected the straight move to hit the rack")
+	}
+
+	options := DefaultOptions
+	options.Check = CollisionCheck(model, obstacles)
+	options.Rand = rand.NewSource(1)
+	path, err := Plan(start, goal, options)
+	if err != nil {
+		t.Fatalf("Plan failed with error: %s", err)
+	}
+	if path[0] != start || path[len(path)-1] != goal {
+		t.Errorf("Path should run from start to goal. Got %v", path)
+	}
+	if err := model.CheckCollisionAlong(path, obstacles, 0.01); err != nil {
+		t.Errorf("Path should avoid the rack. Got %s", err)
+	}
+	if err := model.CheckSelfCollisionAlong(path, 0.01); err != nil {
+		t.Errorf("Path should avoid self-collisions. Got %s", err)
+	}
+	for _, thetas := range path {
+		if !options.Limits.Contains(thetas) {
+			t.Errorf("%v is outside of the joint limits", thetas)
+		}
+	}
+
+	// The same source plans the same path
+	options.Rand = rand.NewSource(1)
+	again, _ := Plan(start, goal, options)
+	if len(again) != len(path) || again[1] != path[1] {
+		t.Errorf("Expected the same path from the same source")
+	}
+
+	// Without obstacles the path is a straight line
+	options.Check = nil
+	path, _ = Plan(start, goal, options)
+	if len(path) != 2 {
+		t.Errorf("Expected a straight line. Got %v", path)
+	}
+}
+
+func TestPlan_Errors(t *testing.T) {
+	options := DefaultOptions
+	options.Rand = rand.NewSource(1)
+	start := kinematics.StepperTheta{J1: -1, J2: -0.3, J3: 1}
+	goal := kinematics.StepperTheta{J1: 1, J2: -0.3, J3: 1}
+
+	// A wall that J1 cannot pass times out
+	options.Check = func(thetas kinematics.StepperTheta) error {
+		if math.Abs(thetas.J1) < 0.2 {
+			return errors.New("wall")
+		}
+		return nil
+	}
+	options.Timeout = 50 * time.Millisecond
+	options.MaxIterations = math.MaxInt32
+	begin := time.Now()
+	_, err := Plan(start, goal, options)
+	if !errors.Is(err, ErrNoPath) {
+		t.Errorf("Expected ErrNoPath. Got %v", err)
+	}
+	if time.Since(begin) > 500*time.Millisecond {
+		t.Errorf("Timeout of %s was not respected", options.Timeout)
+	}
+
+	// Invalid ends are rejected before planning
+	if _, err = Plan(kinematics.StepperTheta{}, goal, options); err == nil {
+		t.Errorf("Start outside of the joint limits should fail")
+	}
+	if _, err = Plan(start, kinematics.StepperTheta{J2: -0.3, J3: 1}, options); err == nil {
+		t.Errorf("Goal inside of the wall should fail")
+	}
+
+	// Options that would never finish planning are rejected
+	for _, invalid := range []func(*Options){
+		func(o *Options) { o.StepSize = 0 },
+		func(o *Options) { o.CheckStep = -1 },
+		func(o *Options) { o.MaxIterations = 0 },
+	} {
+		options := DefaultOptions
+		invalid(&options)
+		if _, err = Plan(start, goal, options); !errors.Is(err, ErrInvalidOptions) {
+			t.Errorf("Expected ErrInvalidOptions. Got %v", err)
+		}
+		if _, err = PlanBetweenPoses(kinematics.XyzWxyz{}, kinematics.XyzWxyz{}, kinematics.AR3DhParameters, options); !errors.Is(err, ErrInvalidOptions) {
+			t.Errorf("Expected ErrInvalidOptions. Got %v", err)
+		}
+	}
+}
+
+func TestPlanToPose(t *testing.T) {
+	model := kinematics.AR3CollisionModel
+	options := DefaultOptions
+	options.Check = CollisionCheck(model, []kinematics.Geometry{rack})
+	options.Rand = rand.NewSource(1)
+	start := kinematics.StepperTheta{J1: -1.5, J2: -0.3, J3: math.Pi / 2}
+	goal := kinematics.ForwardKinematics(kinematics.StepperTheta{J1: 1.5, J2: -0.3, J3: math.Pi / 2}, kinematics.AR3DhParameters)
+
+	path, err := PlanToPose(start, goal, kinematics.AR3DhParameters, options)
+	if err != nil {
+		t.Fatalf("PlanToPose failed with error: %s", err)
+	}
+	reached := kinematics.ForwardKinematics(path[len(path)-1], kinematics.AR3DhParameters)
+	if reached.Distance(goal) > 1e-6 || reached.Angle(goal) > 1e-6 {
+		t.Errorf("Expected to end at %v. Got %v", goal, reached)
+	}
+
+	path, err = PlanBetweenPoses(kinematics.ForwardKinematics(start, kinematics.AR3DhParameters), goal, kinematics.AR3DhParameters, options)
+	if err != nil {
+		t.Fatalf("PlanBetweenPoses failed with error: %s", err)
+	}
+	if err := model.CheckCollisionAlong(path, []kinematics.Geometry{rack}, 0.01); err != nil {
+		t.Errorf("Path should avoid the rack. Got %s", err)
+	}
+}
@@ -0,0 +1,18 @@
+package trajectory_test
+
+import (
+	"fmt"
+	"github.com/koeng101/armos/utils/trajectory"
+)
+
+func ExamplePlan() {
+	// Move two joints to a waypoint and back. The first joint has twice as far
+	// to go, so the second joint slows down to arrive at the same time.
+	waypoints := [][]float64{{0, 0}, {2, 1}, {0, 0}}
+	limits := trajectory.Limits{Velocity: []float64{1, 1}, Acceleration: []float64{2, 2}, Jerk: []float64{10, 10}}
+	plan, _ := trajectory.Plan(waypoints, limits, trajectory.SCurve)
+
+	state := plan.Sample(plan.Times()[0] / 2)
+	fmt.Printf("%.2fs, halfway at %.2f %.2f\n", plan.Duration(), state.Position[0], state.Position[1])
+	// Output: 5.40s, halfway at 1.00 0.50
+}
@@ -0,0 +1,289 @@
+/*
+Package trajectory generates time-parameterized joint trajectories.
+
+A trajectory moves through joint-space waypoints, stopping at each one. Every
+joint moves along a straight line between waypoints and follows the same
+velocity profile, so all joints start and stop together. The profile is either
+trapezoidal (limited velocity and acceleration) or an S-curve (also limited
+jerk), and is as fast as the slowest joint allows.
+
+Plan (waypoints + limits	-> trajectory)
+
+Trajectory.Sample (time	-> position, velocity and acceleration of each joint)
+
+Trajectory.Duration (-> total time of the trajectory)
+
+Units are up to the caller, as long as they are consistent: joint positions in
+radians with limits in radians per second (per second, per second) work, as do
+stepper positions with limits in steps per second.
+*/
+package trajectory
+
+import (
+	"errors"
+	"fmt"
+	"math"
+)
+
+// Profile is the shape of the velocity profile between two waypoints.
+type Profile int
+
+const (
+	// Trapezoidal profiles accelerate at the acceleration limit, cruise at the
+	// velocity limit, and then decelerate at the acceleration limit.
+	// Acceleration changes instantly, so jerk is not limited.
+	Trapezoidal Profile = iota
+	// SCurve profiles also ramp acceleration up and down at the jerk limit,
+	// which is smoother on the arm.
+	SCurve
+)
+
+// ErrInvalidLimits is returned when a joint that moves does not have positive
+// limits.
+var ErrInvalidLimits = errors.New("Limits must be positive")
+
+// Limits are the maximum velocity, acceleration and jerk of each joint. Jerk is
+// only needed for SCurve profiles.
+type Limits struct {
+	Velocity     []float64
+	Acceleration []float64
+	Jerk         []float64
+}
+
+// State is the position, velocity and acceleration of each joint at a point in
+// time.
+type State struct {
+	Position     []float64
+	Velocity     []float64
+	Acceleration []float64
+}
+
+// Trajectory is a time-parameterized path through joint-space waypoints. Time
+// starts at 0 at the first waypoint.
+type Trajectory struct {
+	segments []segment
+	end      []float64
+}
+
+// segment is the move between two waypoints.
+type segment struct {
+	start float64
+	from  []float64
+	delta []float64
+	shape shape
+}
+
+// shape is a velocity profile from 0 to 1, made of phases of constant jerk.
+type shape struct {
+	phases   []phase
+	duration float64
+}
+
+// phase is part of a shape with constant jerk. Acceleration, velocity and
+// position are at the start of the phase.
+type phase struct {
+	duration     float64
+	jerk         float64
+	acceleration float64
+	velocity     float64
+	position     float64
+}
+
+// Plan plans a trajectory through waypoints, stopping at each one. Every
+// waypoint must have one position per joint, as must each of the limits.
+func Plan(waypoints [][]float64, limits Limits, profile Profile) (Trajectory, error) {
+	if len(waypoints) == 0 {
+		return Trajectory{}, errors.New("Trajectory needs at least one waypoint")
+	}
+	joints := len(waypoints[0])
+	if len(limits.Velocity) != joints || len(limits.Acceleration) != joints || (profile == SCurve && len(limits.Jerk) != joints) {
+		return Trajectory{}, fmt.Errorf("Limits must have one value for each of the %d joints", joints)
+	}
+
+	var trajectory Trajectory
+	start := 0.0
+	for i := 1; i < len(waypoints); i++ {
+		if len(waypoints[i]) != joints {
+			return Trajectory{}, fmt.Errorf("Waypoint %d has %d joints. Expected %d", i, len(waypoints[i]), joints)
+		}
+		delta := make([]float64, joints)
+		moving := false
+		for j := range delta {
+			delta[j] = waypoints[i][j] - waypoints[i-1][j]
+			moving = moving || delta[j] != 0
+		}
+		if !moving {
+			continue
+		}
+
+		// Scale the limits of each joint onto a move from 0 to 1, and use the
+		// tightest one so that every joint stays within its limits.
+		velocity, acceleration, jerk := math.Inf(1), math.Inf(1), math.Inf(1)
+		for j, d := range delta {
+			if d == 0 {
+				continue
+			}
+			if limits.Velocity[j] <= 0 || limits.Acceleration[j] <= 0 || (profile == SCurve && limits.Jerk[j] <= 0) {
+				return Trajectory{}, fmt.Errorf("%w. Joint %d moves but has a limit of 0 or less", ErrInvalidLimits, j)
+			}
+			velocity = math.Min(velocity, limits.Velocity[j]/math.Abs(d))
+			acceleration = math.Min(acceleration, limits.Acceleration[j]/math.Abs(d))
+			if profile == SCurve {
+				jerk = math.Min(jerk, limits.Jerk[j]/math.Abs(d))
+			}
+		}
+		var s shape
+		switch profile {
+		case Trapezoidal:
+			s = trapezoidalShape(velocity, acceleration)
+		case SCurve:
+			s = sCurveShape(velocity, acceleration, jerk)
+		default:
+			return Trajectory{}, fmt.Errorf("Unknown profile %d", profile)
+		}
+		trajectory.segments = append(trajectory.segments, segment{start: start, from: waypoints[i-1], delta: delta, shape: s})
+		start = start + s.duration
+	}
+	trajectory.end = waypoints[len(waypoints)-1]
+	return trajectory, nil
+}
+
+// Duration returns the time it takes to move through every waypoint.
+func (trajectory Trajectory) Duration() float64 {
+	if len(trajectory.segments) == 0 {
+		return 0
+	}
+	last := trajectory.segments[len(trajectory.segments)-1]
+	return last.start + last.shape.duration
+}
+
+// Times returns the time at which each move between waypoints ends. Moves
+// between identical waypoints take no time and are left out.
+func (trajectory Trajectory) Times() []float64 {
+	times := make([]float64, len(trajectory.segments))
+	for i, s := range trajectory.segments {
+		times[i] = s.start + s.shape.duration
+	}
+	return times
+}
+
+// Sample returns the state of every joint at a time. Times before the start are
+// at the first waypoint, and times after the end are at the last waypoint.
+func (trajectory Trajectory) Sample(time float64) State {
+	joints := len(trajectory.end)
+	state := State{Position: make([]float64, joints), Velocity: make([]float64, joints), Acceleration: make([]float64, joints)}
+	if len(trajectory.segments) == 0 || time >= trajectory.Duration() {
+		copy(state.Position, trajectory.end)
+		return state
+	}
+	if time <= 0 {
+		copy(state.Position, trajectory.segments[0].from)
+		return state
+	}
+	current := trajectory.segments[0]
+	for _, s := range trajectory.segments {
+		if time < s.start {
+			break
+		}
+		current = s
+	}
+	position, velocity, acceleration := current.shape.sample(time - current.start)
+	for j := range state.Position {
+		state.Position[j] = current.from[j] + current.delta[j]*position
+		state.Velocity[j] = current.delta[j] * velocity
+		state.Acceleration[j] = current.delta[j] * acceleration
+	}
+	return state
+}
+
+// trapezoidalShape is a trapezoidal profile from 0 to 1. If the velocity limit
+// cannot be reached, the profile is a triangle.
+func trapezoidalShape(velocity, acceleration float64) shape {
+	accelerationTime := velocity / acceleration
+	if velocity*accelerationTime > 1 {
+		accelerationTime = math.Sqrt(1 / acceleration)
+		velocity = acceleration * accelerationTime
+	}
+	cruiseTime := (1 - velocity*accelerationTime) / velocity
+	return newShape([]phase{
+		{duration: accelerationTime, acceleration: acceleration},
+		{duration: cruiseTime},
+		{duration: accelerationTime, acceleration: -acceleration},
+	})
+}
+
+// sCurveShape is a seven phase jerk limited profile from 0 to 1. If the
+// velocity or acceleration limit cannot be reached, the phases where they
+// would be held are left out.
+func sCurveShape(velocity, acceleration, jerk float64) shape {
+	// jerkTime is how long jerk is applied for, and accelerationTime is how
+	// long it takes to reach the peak velocity.
+	jerkTime := acceleration / jerk
+	peak := acceleration
+	accelerationTime := velocity/acceleration + jerkTime
+	if velocity*jerk < acceleration*acceleration {
+		jerkTime = math.Sqrt(velocity / jerk)
+		peak = jerk * jerkTime
+		accelerationTime = 2 * jerkTime
+	}
+	if velocity*accelerationTime > 1 {
+		// The velocity limit cannot be reached, so find the highest velocity
+		// that can be, first trying with the full acceleration.
+		velocity = (-acceleration*acceleration/jerk + math.Sqrt(math.Pow(acceleration, 4)/(jerk*jerk)+4*acceleration)) / 2
+		if velocity*jerk >= acceleration*acceleration {
+			jerkTime = acceleration / jerk
+			peak = acceleration
+			accelerationTime = velocity/acceleration + jerkTime
+		} else {
+			jerkTime = math.Cbrt(1 / (2 * jerk))
+			peak = jerk * jerkTime
+			velocity = jerk * jerkTime * jerkTime
+			accelerationTime = 2 * jerkTime
+		}
+	}
+	cruiseTime := math.Max(0, (1-velocity*accelerationTime)/velocity)
+	constantTime := math.Max(0, accelerationTime-2*jerkTime)
+	return newShape([]phase{
+		{duration: jerkTime, jerk: jerk},
+		{duration: constantTime, acceleration: peak},
+		{duration: jerkTime, jerk: -jerk, acceleration: peak},
+		{duration: cruiseTime},
+		{duration: jerkTime, jerk: -jerk},
+		{duration: constantTime, acceleration: -peak},
+		{duration: jerkTime, jerk: jerk, acceleration: -peak},
+	})
+}
+
+// newShape fills in the velocity and position at the start of each phase.
+func newShape(phases []phase) shape {
+	var s shape
+	velocity, position := 0.0, 0.0
+	for _, p := range phases {
+		p.velocity, p.position = velocity, position
+		t := p.duration
+		position = position + velocity*t + p.acceleration*t*t/2 + p.jerk*t*t*t/6
+		velocity = velocity + p.acceleration*t + p.jerk*t*t/2
+		s.phases = append(s.phases, p)
+		s.duration = s.duration + t
+	}
+	return s
+}
+
+// sample returns the position, velocity and acceleration of a shape at a time.
+func (s shape) sample(time float64) (float64, float64, float64) {
+	if time >= s.duration {
+		return 1, 0, 0
+	}
+	for _, p := range s.phases {
+		if time > p.duration {
+			time = time - p.duration
+			continue
+		}
+		t := time
+		position := p.position + p.velocity*t + p.acceleration*t*t/2 + p.jerk*t*t*t/6
+		velocity := p.velocity + p.acceleration*t + p.jerk*t*t/2
+		acceleration := p.acceleration + p.jerk*t
+		return position, velocity, acceleration
+	}
+	return 1, 0, 0
+}
@@ -0,0 +1,188 @@
+package trajectory
+
+import (
+	"errors"
+	"math"
+	"testing"
+)
+
+func TestPlan_Trapezoidal(t *testing.T) {
+	// 4 radians at 1 rad/s and 1 rad/s^2: 1s to accelerate, 3s to cruise, 1s
+	// to decelerate.
+	trajectory, err := Plan([][]float64{{0}, {4}}, Limits{Velocity: []float64{1}, Acceleration: []float64{1}}, Trapezoidal)
+	if err != nil {
+		t.Fatalf("Plan failed with error: %s", err)
+	}
+	if math.Abs(trajectory.Duration()-5) > 1e-12 {
+		t.Errorf("Expected a duration of 5. Got %f", trajectory.Duration())
+	}
+	testCases := []struct {
+		time         float64
+		position     float64
+		velocity     float64
+		acceleration float64
+	}{
+		{0, 0, 0, 0},
+		{0.5, 0.125, 0.5, 1},
+		{1, 0.5, 1, 1},
+		{2.5, 2, 1, 0},
+		{4.5, 3.875, 0.5, -1},
+		{5, 4, 0, 0},
+		{6, 4, 0, 0},
+	}
+	for _, testCase := range testCases {
+		state := trajectory.Sample(testCase.time)
+		if math.Abs(state.Position[0]-testCase.position) > 1e-12 || math.Abs(state.Velocity[0]-testCase.velocity) > 1e-12 || math.Abs(state.Acceleration[0]-testCase.acceleration) > 1e-12 {
+			t.Errorf("At %f expected %f, %f, %f. Got %v", testCase.time, testCase.position, testCase.velocity, testCase.acceleration, state)
+		}
+	}
+
+	// 1 radian never reaches the velocity limit, so the profile is a triangle.
+	trajectory, _ = Plan([][]float64{{0}, {-1}}, Limits{Velocity: []float64{2}, Acceleration: []float64{1}}, Trapezoidal)
+	if math.Abs(trajectory.Duration()-2) > 1e-12 {
+		t.Errorf("Expected a duration of 2. Got %f", trajectory.Duration())
+	}
+	if state := trajectory.Sample(1); math.Abs(state.Velocity[0]+1) > 1e-12 || math.Abs(state.Position[0]+0.5) > 1e-12 {
+		t.Errorf("Expected a peak velocity of -1 halfway. Got %v", state)
+	}
+}
+
+func TestPlan_SCurve(t *testing.T) {
+	// 10 radians at 2 rad/s, 2 rad/s^2 and 4 rad/s^3: 0.5s of jerk, 0.5s of
+	// constant acceleration, 0.5s of jerk, 3.5s of cruising, and then the same
+	// to stop.
+	limits := Limits{Velocity: []float64{2}, Acceleration: []float64{2}, Jerk: []float64{4}}
+	trajectory, err := Plan([][]float64{{0}, {10}}, limits, SCurve)
+	if err != nil {
+		t.Fatalf("Plan failed with error: %s", err)
+	}
+	if math.Abs(trajectory.Duration()-6.5) > 1e-12 {
+		t.Errorf("Expected a duration of 6.5. Got %f", trajectory.Duration())
+	}
+	testCases := []struct {
+		time         float64
+		position     float64
+		velocity     float64
+		acceleration float64
+	}{
+		{0.5, 4.0 / 6 * 0.125, 0.5, 2},
+		{1.5, 1.5, 2, 0},
+		{3.25, 5, 2, 0},
+		{6.5, 10, 0, 0},
+	}
+	for _, testCase := range testCases {
+		state := trajectory.Sample(testCase.time)
+		if math.Abs(state.Position[0]-testCase.position) > 1e-9 || math.Abs(state.Velocity[0]-testCase.velocity) > 1e-9 || math.Abs(state.Acceleration[0]-testCase.acceleration) > 1e-9 {
+			t.Errorf("At %f expected %f, %f, %f. Got %v", testCase.time, testCase.position, testCase.velocity, testCase.acceleration, state)
+		}
+	}
+
+	// Shorter moves reach neither the velocity nor the acceleration limit, and
+	// longer ones reach only the acceleration limit.
+	for _, distance := range []float64{0.01, 0.5, 1.2, 3, 100} {
+		trajectory, _ = Plan([][]float64{{0}, {distance}}, limits, SCurve)
+		checkLimits(t, trajectory, limits, SCurve)
+		if end := trajectory.Sample(trajectory.Duration()).Position[0]; math.Abs(end-distance) > 1e-12 {
+			t.Errorf("Expected to end at %f. Got %f", distance, end)
+		}
+		if middle := trajectory.Sample(trajectory.Duration() / 2).Position[0]; math.Abs(middle-distance/2) > 1e-9*distance {
+			t.Errorf("Expected to be halfway at half of the duration. Got %f of %f", middle, distance)
+		}
+	}
+}
+
+func TestPlan_Synchronized(t *testing.T) {
+	limits := Limits{Velocity: []float64{1, 1, 0.1}, Acceleration: []float64{2, 2, 2}, Jerk: []float64{10, 10, 10}}
+	waypoints := [][]float64{{0, 0, 0}, {4, -1, 0}, {4, -1, 0}, {2, 0, 0.5}}
+	for _, profile := range []Profile{Trapezoidal, SCurve} {
+		trajectory, err := Plan(waypoints, limits, profile)
+		if err != nil {
+			t.Fatalf("Plan failed with error: %s", err)
+		}
+		checkLimits(t, trajectory, limits, profile)
+
+		// Repeated waypoints are skipped, and every joint reaches each
+		// waypoint at the same time.
+		times := trajectory.Times()
+		if len(times) != 2 {
+			t.Fatalf("Expected 2 moves. Got %v", times)
+		}
+		for i, time := range times {
+			state := trajectory.Sample(time)
+			expected := waypoints[2*i+1]
+			for j := range state.Position {
+				if math.Abs(state.Position[j]-expected[j]) > 1e-9 {
+					t.Errorf("Expected %v at %f. Got %v", expected, time, state.Position)
+				}
+			}
+		}
+
+		// Joints move along a straight line between waypoints
+		state := trajectory.Sample(times[0] / 3)
+		if math.Abs(state.Position[0]+4*state.Position[1]) > 1e-9 {
+			t.Errorf("Joints should move in a straight line. Got %v", state.Position)
+		}
+
+		// The second move is limited by the slow third joint
+		if times[1]-times[0] < 5 {
+			t.Errorf("Second move should take at least 5s at 0.1 rad/s. Got %f", times[1]-times[0])
+		}
+	}
+}
+
+func TestPlan_Errors(t *testing.T) {
+	limits := Limits{Velocity: []float64{1, 1}, Acceleration: []float64{1, 1}}
+	if _, err := Plan(nil, limits, Trapezoidal); err == nil {
+		t.Errorf("Plan with no waypoints should fail")
+	}
+	if _, err := Plan([][]float64{{0, 0}, {1}}, limits, Trapezoidal); err == nil {
+		t.Errorf("Plan with a short waypoint should fail")
+	}
+	if _, err := Plan([][]float64{{0, 0}, {1, 1}}, limits, SCurve); err == nil {
+		t.Errorf("SCurve without jerk limits should fail")
+	}
+	_, err := Plan([][]float64{{0, 0}, {1, 1}}, Limits{Velocity: []float64{1, 0}, Acceleration: []float64{1, 1}}, Trapezoidal)
+	if !errors.Is(err, ErrInvalidLimits) {
+		t.Errorf("Moving a joint with no velocity limit should fail with ErrInvalidLimits. Got %v", err)
+	}
+
+	// Joints that do not move do not need limits
+	trajectory, err := Plan([][]float64{{0, 0}, {1, 0}}, Limits{Velocity: []float64{1, 0}, Acceleration: []float64{1, 0}}, Trapezoidal)
+	if err != nil || trajectory.Duration() != 2 {
+		t.Errorf("Only moving joints should need limits. Got %f, %v", trajectory.Duration(), err)
+	}
+
+	// A single waypoint stays put
+	trajectory, _ = Plan([][]float64{{3, 4}}, limits, Trapezoidal)
+	if trajectory.Duration() != 0 || trajectory.Sample(1).Position[1] != 4 {
+		t.Errorf("Single waypoint should take no time")
+	}
+}
+
+// checkLimits samples a trajectory and checks that no joint goes over its
+// limits, and that position and velocity are continuous.
+func checkLimits(t *testing.T, trajectory Trajectory, limits Limits, profile Profile) {
+	const dt = 0.0005
+	previous := trajectory.Sample(0)
+	for time := dt; time <= trajectory.Duration()+dt; time = time + dt {
+		state := trajectory.Sample(time)
+		for j := range state.Position {
+			if math.Abs(state.Velocity[j]) > limits.Velocity[j]+1e-9 {
+				t.Fatalf("Joint %d over its velocity limit at %f: %f", j, time, state.Velocity[j])
+			}
+			if math.Abs(state.Acceleration[j]) > limits.Acceleration[j]+1e-9 {
+				t.Fatalf("Joint %d over its acceleration limit at %f: %f", j, time, state.Acceleration[j])
+			}
+			if profile == SCurve && math.Abs(state.Acceleration[j]-previous.Acceleration[j]) > limits.Jerk[j]*dt+1e-9 {
+				t.Fatalf("Joint %d over its jerk limit at %f", j, time)
+			}
+			if math.Abs(state.Position[j]-previous.Position[j]) > limits.Velocity[j]*dt+1e-9 {
+				t.Fatalf("Joint %d position jumps at %f", j, time)
+			}
+			if math.Abs(state.Velocity[j]-previous.Velocity[j]) > limits.Acceleration[j]*dt+1e-9 {
+				t.Fatalf("Joint %d velocity jumps at %f", j, time)
+			}
+		}
+		previous = state
+	}
+}